@@ -43,11 +43,23 @@ var (
 	ResourceUnavailable  = &Error{Name: "ResourceUnavailable", Code: 1422, Message: "resource unavailable"}     // resource exists but is not currently available (temporarily or permanently)
 	RateLimited          = &Error{Name: "RateLimited", Code: 1430, Message: "rate limited"}                     // request throttled (per-user / per-session / per-IP bucket exceeded)
 
+	// Storage
+
+	StorageObjectNotFound = &Error{Name: "StorageObjectNotFound", Code: 1500, Message: "storage object not found"}
+	InvalidStoragePath    = &Error{Name: "InvalidStoragePath", Code: 1501, Message: "invalid storage path"} // path escapes the storage root or is malformed
+	StorageReadOnly       = &Error{Name: "StorageReadOnly", Code: 1502, Message: "storage is read-only"}
+	Storage               = &Error{Name: "Storage", Code: 1510, Message: "storage error"}            // general storage backend error
+	UploadMalformed       = &Error{Name: "UploadMalformed", Code: 1520, Message: "malformed upload"} // not multipart/form-data or broken parts
+	UploadTooLarge        = &Error{Name: "UploadTooLarge", Code: 1521, Message: "upload too large"}  // a file or the whole request exceeds its limit
+	UploadTooManyFiles    = &Error{Name: "UploadTooManyFiles", Code: 1522, Message: "too many files"}
+	UploadTypeNotAllowed  = &Error{Name: "UploadTypeNotAllowed", Code: 1523, Message: "file type not allowed"} // sniffed MIME type not in the allow-list
+
 	// DB
 
 	KVDB               = &Error{Name: "KVDB", Code: 1600, Message: "kvdb error"}                              // general key-value store error
 	SQLDB              = &Error{Name: "SQLDB", Code: 1610, Message: "sql db error"}                            // general SQL/database error
 	SQLNotFoundInStore = &Error{Name: "SQLNotFoundInStore", Code: 1611, Message: "sql statement not found in store"} // SQL statement not found in RawSQLStore
+	LockStore          = &Error{Name: "LockStore", Code: 1620, Message: "lock store error"}                         // named lock store failure (acquire/release/renew)
 
 	// Relation
 
@@ -11,10 +11,13 @@ import (
 
 	"github.com/x64c/gw/clients"
 	"github.com/x64c/gw/kvdbs"
+	"github.com/x64c/gw/namedlocks"
+	"github.com/x64c/gw/queue"
 	"github.com/x64c/gw/schedjobs"
 	"github.com/x64c/gw/security"
 	"github.com/x64c/gw/sqldbs"
 	"github.com/x64c/gw/storages"
+	"github.com/x64c/gw/storages/mirrorstorage"
 	"github.com/x64c/gw/svc"
 	"github.com/x64c/gw/tg"
 	"github.com/x64c/gw/throttle"
@@ -32,12 +35,18 @@ type Core struct {
 	RootCtx                  context.Context                                  `json:"-"`          // Global Context with RootCancel
 	RootCancel               context.CancelFunc                               `json:"-"`          // CancelFunc for RootCtx
 	UDSService               *uds.Service                                     `json:"-"`          // PrepareUDSService
+	JobSchedulerConf         schedjobs.Conf                                   `json:"-"`          // PrepareJobScheduler
 	JobScheduler             *schedjobs.Scheduler                             `json:"-"`          // PrepareJobScheduler
+	QueueConf                queue.Conf                                       `json:"-"`          // PrepareQueueService
+	QueueService             *queue.Service                                   `json:"-"`          // PrepareQueueService
 	WebService               *web.Service                                     `json:"-"`          // PrepareWebService
 	ThrottleBucketStore      *throttle.BucketStore                            `json:"-"`          // PrepareThrottleBucketStore
+	ThrottleConf             throttle.Conf                                    `json:"-"`          // PrepareThrottleBackend
 	VolatileKV               *sync.Map                                        `json:"-"`          // map[string]string
 	SessionLocks             *sync.Map                                        `json:"-"`          // map[string]*sync.Mutex for AccessTokenSessions and CookieSessions
 	ActionLocks              *sync.Map                                        `json:"-"`          // map[string]struct{}
+	ActionLockConf           namedlocks.Conf                                  `json:"-"`          // PrepareActionLockStore
+	ActionLockStore          namedlocks.Store                                 `json:"-"`          // MapStore on ActionLocks by default. PrepareActionLockStore
 	JwksServiceConf          security.JwksServiceConf                         `json:"-"`          // LoadJwksServiceConf
 	BaseHttpClient           *http.Client                                     `json:"-"`          // for requests to external apis
 	RawSQLFSMap              map[string]fs.FS                                 `json:"-"`          // Set before PrepareSQLDBClients
@@ -49,8 +58,15 @@ type Core struct {
 	TypedGroupRegistry       map[string]tg.RegGrp                             `json:"-"`          // Group Registry for typed groups
 	KVDBClients              map[string]kvdbs.Client                          `json:"-"`          // PrepareKVDBClients
 	MainKVDB                 kvdbs.DB                                         `json:"-"`          // From KVDBClients or set directly
+	KVKeyRegistry            *kvdbs.KeyRegistry                               `json:"-"`          // PrepareKVKeyRegistry
 	LocalStorages            map[string]*storages.LocalStorage                `json:"-"`          // PrepareStorages
+	StorageFSMap             map[string]fs.FS                                 `json:"-"`          // Set before PrepareStorages. Exposed by "memory" storages with "fs"
+	MemoryStorages           map[string]storages.Storage                      `json:"-"`          // PrepareStorages
 	StorageClients           map[string]storages.Client                       `json:"-"`          // PrepareStorageClients
+	MirrorStorages           map[string]*mirrorstorage.MirrorStorage          `json:"-"`          // PrepareStorages. Mirrors over the other storages
+	StorageRetentions        map[string]*storages.Retention                   `json:"-"`          // PrepareStorages. "<client>/<storage>" -> Retention
+	StorageURLConf           storages.URLSignerConf                           `json:"-"`          // PrepareStorageURLSigner
+	StorageURLSigner         *storages.URLSigner                              `json:"-"`          // PrepareStorageURLSigner
 
 	// internal
 	services []svc.Service
@@ -0,0 +1,46 @@
+package framework
+
+import (
+	"encoding/json/v2"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"github.com/x64c/gw/namedlocks"
+)
+
+// PrepareActionLockStore switches ActionLockStore by config/.action-locks.json. Without the file, the memory store is used
+// Prerequisite: ActionLocks (memory), MainKVDB (kvdb)
+func (c *Core) PrepareActionLockStore() error {
+	confFilePath := filepath.Join(c.AppRoot, "config", ".action-locks.json")
+	confBytes, err := os.ReadFile(confFilePath) // ([]byte, error)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	if err == nil {
+		if err = json.Unmarshal(confBytes, &c.ActionLockConf); err != nil {
+			return err
+		}
+	}
+	switch c.ActionLockConf.Backend {
+	case namedlocks.BackendMemory, "":
+		c.ActionLockStore = namedlocks.NewMapStore(c.ActionLocks)
+	case namedlocks.BackendKVDB:
+		if c.MainKVDB == nil {
+			return fmt.Errorf("action locks: main kvdb not ready")
+		}
+		if c.ActionLockConf.LeaseTTL <= 0 {
+			return fmt.Errorf("action locks: lease_ttl is required for backend %q", c.ActionLockConf.Backend)
+		}
+		store, err := namedlocks.NewKVDBStore(c.MainKVDB, c.AppName, namedlocks.KeyActionLock)
+		if err != nil {
+			return fmt.Errorf("action locks: %w", err)
+		}
+		c.ActionLockStore = store
+	default:
+		return fmt.Errorf("action locks: unknown backend %q", c.ActionLockConf.Backend)
+	}
+	return nil
+}
@@ -7,6 +7,8 @@ import (
 	"os"
 	"path/filepath"
 	"sync"
+
+	"github.com/x64c/gw/namedlocks"
 )
 
 // BaseInit - 1st step for initialization
@@ -38,4 +40,5 @@ func (c *Core) prepareDefaultFeatures() {
 	c.SessionLocks = &sync.Map{}
 	c.BaseHttpClient = &http.Client{}
 	c.ActionLocks = &sync.Map{}
+	c.ActionLockStore = namedlocks.NewMapStore(c.ActionLocks)
 }
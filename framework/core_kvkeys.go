@@ -0,0 +1,34 @@
+package framework
+
+import (
+	"github.com/x64c/gw/kvdbs"
+	"github.com/x64c/gw/namedlocks"
+	"github.com/x64c/gw/queue"
+	"github.com/x64c/gw/schedjobs"
+	"github.com/x64c/gw/throttle"
+	"github.com/x64c/gw/web/userbearersession"
+	"github.com/x64c/gw/web/usercookiesession"
+)
+
+// PrepareKVKeyRegistry registers the framework KVDB key families and the app ones into KVKeyRegistry.
+// Fails on colliding key layouts.
+// Inspect live entries over UDS with &kvdbs.InspectCommand{Registry: c.KVKeyRegistry, DB: c.MainKVDB}
+func (c *Core) PrepareKVKeyRegistry(appFamilies ...*kvdbs.KeyFamily) error {
+	c.KVKeyRegistry = kvdbs.NewKeyRegistry(c.AppName)
+	if err := c.KVKeyRegistry.Register(usercookiesession.KeyFamilies()...); err != nil {
+		return err
+	}
+	if err := c.KVKeyRegistry.Register(userbearersession.KeyAccessToken, namedlocks.KeyActionLock); err != nil {
+		return err
+	}
+	if err := c.KVKeyRegistry.Register(schedjobs.KeyOneTimeJobs, schedjobs.KeySchedulerLeader, schedjobs.KeyJobRunLock); err != nil {
+		return err
+	}
+	if err := c.KVKeyRegistry.Register(throttle.KeyFamilies()...); err != nil {
+		return err
+	}
+	if err := c.KVKeyRegistry.Register(queue.KeyFamilies()...); err != nil {
+		return err
+	}
+	return c.KVKeyRegistry.Register(appFamilies...)
+}
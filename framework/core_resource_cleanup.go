@@ -20,6 +20,11 @@ func (c *Core) ResourceCleanUp() {
 			log.Printf("[INFO] SQL DB client %q closed", name)
 		}
 	}
+	for name, localStorage := range c.LocalStorages {
+		if err := localStorage.Close(); err != nil {
+			log.Printf("[ERROR] Failed to close local storage %q: %v", name, err)
+		}
+	}
 	//----
 	log.Println("[INFO] App Resource Cleanup Complete")
 }
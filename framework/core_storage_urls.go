@@ -0,0 +1,110 @@
+package framework
+
+import (
+	"encoding/json/v2"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"github.com/x64c/gw/errs"
+	"github.com/x64c/gw/storages"
+	"github.com/x64c/gw/web/responses"
+)
+
+const defaultStorageURLTTL = 300 // seconds
+
+// PrepareStorageURLSigner reads .storage-urls.json and prepares StorageURLSigner
+func (c *Core) PrepareStorageURLSigner() error {
+	confFilePath := filepath.Join(c.AppRoot, "config", ".storage-urls.json")
+	confBytes, err := os.ReadFile(confFilePath)
+	if err != nil {
+		return fmt.Errorf("storage-urls: %w", err)
+	}
+	if err = json.Unmarshal(confBytes, &c.StorageURLConf); err != nil {
+		return fmt.Errorf("storage-urls: %w", err)
+	}
+	if len(c.StorageURLConf.Secret) < 32 {
+		return errors.New("storage-urls: secret must be at least 32 bytes")
+	}
+	if c.StorageURLConf.BaseURL == "" {
+		return errors.New("storage-urls: base_url is required")
+	}
+	if c.StorageURLConf.DefaultTTL <= 0 {
+		c.StorageURLConf.DefaultTTL = defaultStorageURLTTL
+	}
+	c.StorageURLSigner = storages.NewURLSigner([]byte(c.StorageURLConf.Secret), c.StorageURLConf.BaseURL)
+	return nil
+}
+
+// StorageDownloadURL returns a time-limited download url of an object.
+// A storage implementing storages.Presigner gets a native presigned url, unless the url is bound to a user (obj.UserID),
+// which only the app can check. Otherwise, the url is signed by StorageURLSigner and served by SignedStorageURLHandler.
+// ttl <= 0 means the configured default_ttl.
+func (c *Core) StorageDownloadURL(obj storages.SignedObject, ttl time.Duration) (string, error) {
+	st, ok := c.Storage(obj.Client, obj.Storage)
+	if !ok {
+		return "", fmt.Errorf("storage not found: %s/%s", obj.Client, obj.Storage)
+	}
+	if ttl <= 0 {
+		ttl = time.Duration(c.StorageURLConf.DefaultTTL) * time.Second
+	}
+	if presigner, ok := st.(storages.Presigner); ok && obj.UserID == "" {
+		return presigner.PresignGet(obj.Path, ttl, storages.PresignOptions{ContentDisposition: obj.ContentDisposition})
+	}
+	if c.StorageURLSigner == nil {
+		return "", errors.New("storage url signer not prepared")
+	}
+	obj.Expires = time.Now().Add(ttl)
+	return c.StorageURLSigner.Sign(obj), nil
+}
+
+// SignedStorageURLHandler serves objects of urls signed by StorageURLSigner. Mount it at base_url.
+// Until PrepareStorageURLSigner is done, it responds 503.
+// userIDFn returns the user ID of the request. It is consulted only for urls bound to a user, and may be nil if none are.
+func (c *Core) SignedStorageURLHandler(userIDFn func(r *http.Request) (string, bool)) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			w.Header().Set("Allow", "GET, HEAD")
+			responses.WriteSimpleErrorJSON(w, http.StatusMethodNotAllowed, "method not allowed")
+			return
+		}
+		if c.StorageURLSigner == nil {
+			log.Printf("[ERROR][StorageURL] signer not prepared. call PrepareStorageURLSigner")
+			responses.WriteErrorJSON(w, http.StatusServiceUnavailable, errs.ResourceUnavailable)
+			return
+		}
+		obj, err := c.StorageURLSigner.Verify(r.URL.Query(), time.Now())
+		if err != nil {
+			responses.WriteErrorJSON(w, http.StatusForbidden, errs.ResourceAccessDenied.WithDetail(err.Error()))
+			return
+		}
+		if obj.UserID != "" {
+			if userIDFn == nil {
+				log.Printf("[ERROR][StorageURL] url bound to a user but no userIDFn")
+				responses.WriteErrorJSON(w, http.StatusForbidden, errs.ResourceAccessDenied)
+				return
+			}
+			if userID, ok := userIDFn(r); !ok || userID != obj.UserID {
+				responses.WriteErrorJSON(w, http.StatusForbidden, errs.ResourceAccessDenied)
+				return
+			}
+		}
+		st, ok := c.Storage(obj.Client, obj.Storage)
+		if !ok {
+			responses.WriteErrorJSON(w, http.StatusNotFound, errs.ResourceNotFound)
+			return
+		}
+		h := w.Header()
+		h.Set("Cache-Control", "private, max-age="+strconv.Itoa(max(int(time.Until(obj.Expires)/time.Second), 0)))
+		h.Set("X-Content-Type-Options", "nosniff")
+		if obj.ContentDisposition != "" {
+			h.Set("Content-Disposition", obj.ContentDisposition)
+		}
+		responses.ServeStorageObject(w, r, st, obj.Path)
+	})
+}
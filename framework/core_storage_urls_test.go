@@ -0,0 +1,59 @@
+package framework
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/x64c/gw/storages"
+	"github.com/x64c/gw/storages/memstorage"
+)
+
+func TestSignedStorageURLHandler(t *testing.T) {
+	c := &Core{}
+	h := c.SignedStorageURLHandler(func(r *http.Request) (string, bool) {
+		uid := r.Header.Get("X-Test-UID")
+		return uid, uid != ""
+	})
+
+	// mounted before PrepareStorageURLSigner
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/files", nil))
+	if w.Code != http.StatusServiceUnavailable {
+		t.Fatalf("unprepared signer: status %d", w.Code)
+	}
+
+	st := memstorage.New()
+	_ = st.Put(context.Background(), "a.txt", strings.NewReader("hello"))
+	c.MemoryStorages = map[string]storages.Storage{"files": st}
+	c.StorageURLSigner = storages.NewURLSigner([]byte(strings.Repeat("k", 32)), "/files")
+
+	obj := storages.SignedObject{Client: "memory", Storage: "files", Path: "a.txt", Expires: time.Now().Add(time.Minute)}
+	signed := c.StorageURLSigner.Sign(obj)
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, signed, nil))
+	if w.Code != http.StatusOK || w.Body.String() != "hello" {
+		t.Fatalf("signed url: status %d body %q", w.Code, w.Body.String())
+	}
+
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, strings.Replace(signed, "a.txt", "b.txt", 1), nil))
+	if w.Code != http.StatusForbidden {
+		t.Fatalf("tampered url: status %d", w.Code)
+	}
+
+	obj.UserID = "u1"
+	bound := c.StorageURLSigner.Sign(obj)
+	for uid, want := range map[string]int{"": http.StatusForbidden, "u2": http.StatusForbidden, "u1": http.StatusOK} {
+		r := httptest.NewRequest(http.MethodGet, bound, nil)
+		r.Header.Set("X-Test-UID", uid)
+		w = httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		if w.Code != want {
+			t.Errorf("user-bound url as %q: status %d, want %d", uid, w.Code, want)
+		}
+	}
+}
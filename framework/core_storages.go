@@ -1,13 +1,21 @@
 package framework
 
 import (
+	"context"
 	"encoding/json/jsontext"
 	"encoding/json/v2"
+	"errors"
 	"fmt"
+	"log"
 	"os"
 	"path/filepath"
+	"strings"
+	"time"
 
+	"github.com/x64c/gw/schedjobs"
 	"github.com/x64c/gw/storages"
+	"github.com/x64c/gw/storages/memstorage"
+	"github.com/x64c/gw/storages/mirrorstorage"
 )
 
 func (c *Core) PrepareStorageClients(preparers ...func(string, map[string]storages.Client) error) error {
@@ -31,21 +39,32 @@ func (c *Core) PrepareStorages() error {
 		return fmt.Errorf("storages: %w", err)
 	}
 	for clientName, storagesConfMap := range clientStoragesConfMap {
+		if clientName == "mirror" {
+			continue // after all the others, as mirrors are built on them
+		}
 		if clientName == "local" {
 			if c.LocalStorages == nil {
 				c.LocalStorages = make(map[string]*storages.LocalStorage, len(storagesConfMap))
 			}
 			for storageName, storageRawConf := range storagesConfMap {
-				var storageConf struct {
-					Root string `json:"root"`
-				}
+				var storageConf storages.LocalStorageConf
 				if err = json.Unmarshal(storageRawConf, &storageConf); err != nil {
 					return fmt.Errorf("storages[local][%s]: %w", storageName, err)
 				}
-				if storageConf.Root == "" {
-					return fmt.Errorf("storages[local][%s]: root is required", storageName)
+				localStorage, err := storages.OpenLocalStorage(storageConf)
+				if err != nil {
+					return fmt.Errorf("storages[local][%s]: %w", storageName, err)
+				}
+				c.LocalStorages[storageName] = localStorage
+				if err = c.addStorageRetention(clientName, storageName, localStorage, storageRawConf); err != nil {
+					return err
 				}
-				c.LocalStorages[storageName] = storages.NewLocalStorage(storageConf.Root)
+			}
+			continue
+		}
+		if clientName == "memory" {
+			if err = c.prepareMemoryStorages(storagesConfMap); err != nil {
+				return err
 			}
 			continue
 		}
@@ -57,7 +76,165 @@ func (c *Core) PrepareStorages() error {
 			if err = client.CreateStorage(storageName, storageRawConf); err != nil {
 				return fmt.Errorf("storages[%s][%s]: %w", clientName, storageName, err)
 			}
+			st, _ := client.Storage(storageName)
+			if err = c.addStorageRetention(clientName, storageName, st, storageRawConf); err != nil {
+				return err
+			}
+		}
+	}
+	if storagesConfMap, ok := clientStoragesConfMap["mirror"]; ok {
+		if err = c.prepareMirrorStorages(storagesConfMap); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Storage finds a storage by client name ("local", "memory", "mirror" or a StorageClients key) and storage name
+func (c *Core) Storage(clientName string, storageName string) (storages.Storage, bool) {
+	switch clientName {
+	case "local":
+		s, ok := c.LocalStorages[storageName]
+		if !ok {
+			return nil, false
+		}
+		return s, true
+	case "memory":
+		s, ok := c.MemoryStorages[storageName]
+		return s, ok
+	case "mirror":
+		s, ok := c.MirrorStorages[storageName]
+		if !ok {
+			return nil, false
+		}
+		return s, true
+	}
+	client, ok := c.StorageClients[clientName]
+	if !ok {
+		return nil, false
+	}
+	return client.Storage(storageName)
+}
+
+// prepareMemoryStorages prepares "memory" storages
+// {} -> in-memory read-write storage (memstorage.MemStorage)
+// {"fs": "name"} -> read-only storage on StorageFSMap["name"] (storages.FSStorage)
+func (c *Core) prepareMemoryStorages(storagesConfMap map[string]jsontext.Value) error {
+	if c.MemoryStorages == nil {
+		c.MemoryStorages = make(map[string]storages.Storage, len(storagesConfMap))
+	}
+	for storageName, storageRawConf := range storagesConfMap {
+		var storageConf struct {
+			FS string `json:"fs"`
+		}
+		if err := json.Unmarshal(storageRawConf, &storageConf); err != nil {
+			return fmt.Errorf("storages[memory][%s]: %w", storageName, err)
+		}
+		if storageConf.FS == "" {
+			c.MemoryStorages[storageName] = memstorage.New()
+			if err := c.addStorageRetention("memory", storageName, c.MemoryStorages[storageName], storageRawConf); err != nil {
+				return err
+			}
+			continue
+		}
+		fsys, ok := c.StorageFSMap[storageConf.FS]
+		if !ok {
+			return fmt.Errorf("storages[memory][%s]: unknown fs %q", storageName, storageConf.FS)
 		}
+		c.MemoryStorages[storageName] = storages.NewFSStorage(fsys)
 	}
 	return nil
 }
+
+// prepareMirrorStorages prepares "mirror" storages over the other storages
+// {"backends": ["local/uploads", "s3/uploads"], "quorum": 1} -> mirrorstorage.MirrorStorage. quorum 0 = all backends
+func (c *Core) prepareMirrorStorages(storagesConfMap map[string]jsontext.Value) error {
+	if c.MirrorStorages == nil {
+		c.MirrorStorages = make(map[string]*mirrorstorage.MirrorStorage, len(storagesConfMap))
+	}
+	for storageName, storageRawConf := range storagesConfMap {
+		var storageConf struct {
+			Backends []string `json:"backends"` // "<client>/<storage>"
+			Quorum   int      `json:"quorum"`
+		}
+		if err := json.Unmarshal(storageRawConf, &storageConf); err != nil {
+			return fmt.Errorf("storages[mirror][%s]: %w", storageName, err)
+		}
+		backends := make([]mirrorstorage.Backend, 0, len(storageConf.Backends))
+		for _, ref := range storageConf.Backends {
+			clientName, backendName, _ := strings.Cut(ref, "/")
+			if clientName == "mirror" {
+				return fmt.Errorf("storages[mirror][%s]: nested mirror %q", storageName, ref)
+			}
+			st, ok := c.Storage(clientName, backendName)
+			if !ok {
+				return fmt.Errorf("storages[mirror][%s]: unknown backend %q", storageName, ref)
+			}
+			backends = append(backends, mirrorstorage.Backend{Name: ref, Storage: st})
+		}
+		mirror, err := mirrorstorage.New(backends, storageConf.Quorum, nil)
+		if err != nil {
+			return fmt.Errorf("storages[mirror][%s]: %w", storageName, err)
+		}
+		c.MirrorStorages[storageName] = mirror
+	}
+	return nil
+}
+
+// PrepareStorageMirrorRepairJobs registers a repair cron job of each MirrorStorages on JobScheduler.
+// Call after PrepareStorages and PrepareJobScheduler.
+func (c *Core) PrepareStorageMirrorRepairJobs() error {
+	for name, mirror := range c.MirrorStorages {
+		if err := c.JobScheduler.AddCronJob(mirror.RepairCronJob(c.JobScheduler.Ctx, "storage-mirror-repair:"+name)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// addStorageRetention registers the "retention" rules of a storage conf into StorageRetentions
+func (c *Core) addStorageRetention(clientName string, storageName string, st storages.Storage, storageRawConf jsontext.Value) error {
+	var storageConf struct {
+		Retention []storages.RetentionRule `json:"retention"`
+	}
+	if err := json.Unmarshal(storageRawConf, &storageConf); err != nil {
+		return fmt.Errorf("storages[%s][%s]: %w", clientName, storageName, err)
+	}
+	if len(storageConf.Retention) == 0 {
+		return nil
+	}
+	if _, ok := st.(storages.Lister); !ok {
+		return fmt.Errorf("storages[%s][%s]: retention: %w", clientName, storageName, storages.ErrNotLister)
+	}
+	if c.StorageRetentions == nil {
+		c.StorageRetentions = make(map[string]*storages.Retention)
+	}
+	c.StorageRetentions[clientName+"/"+storageName] = &storages.Retention{Storage: st, Rules: storageConf.Retention}
+	return nil
+}
+
+// PrepareStorageRetentionJob registers a cron job enforcing StorageRetentions every hour on JobScheduler.
+// Call after PrepareStorages and PrepareJobScheduler.
+// Dry-run over UDS with &storages.RetentionCommand{Retentions: c.StorageRetentions}
+func (c *Core) PrepareStorageRetentionJob() error {
+	if len(c.StorageRetentions) == 0 {
+		return nil
+	}
+	job := schedjobs.NewEveryMinEmptyCronJob("storage-retention")
+	job.Minutes = schedjobs.BitsFromMinutes([]int{0})
+	job.Overlap = schedjobs.OverlapSkip
+	job.TaskCtx = func(ctx context.Context) error {
+		var errList []error
+		for ref, retention := range c.StorageRetentions {
+			deleted, err := retention.Apply(ctx, time.Now())
+			if len(deleted) > 0 {
+				log.Printf("[INFO][StorageRetention] %s: %d objects deleted", ref, len(deleted))
+			}
+			if err != nil {
+				errList = append(errList, fmt.Errorf("%s: %w", ref, err))
+			}
+		}
+		return errors.Join(errList...)
+	}
+	return c.JobScheduler.AddCronJob(job)
+}
@@ -1,8 +1,69 @@
 package framework
 
-import "github.com/x64c/gw/schedjobs"
+import (
+	"encoding/json/v2"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
 
-func (c *Core) PrepareJobScheduler() {
+	"github.com/x64c/gw/schedjobs"
+)
+
+const (
+	defaultSchedulerLeaderTTL  = 30  // seconds
+	defaultSchedulerRunLockTTL = 300 // seconds
+)
+
+// PrepareJobScheduler prepares JobScheduler, coordinated across instances by config/.jobs.json if present
+//
+//	{ "coordination": "leader" | "run_lock" | "none", "lease_ttl": 30, "history_size": 20 }
+//
+// Prerequisite: MainKVDB (leader, run_lock)
+// Manage jobs over UDS with &schedjobs.ListCommand{Scheduler: c.JobScheduler} and the other schedjobs commands
+func (c *Core) PrepareJobScheduler() error {
 	c.JobScheduler = schedjobs.NewScheduler(c.RootCtx)
+	confFilePath := filepath.Join(c.AppRoot, "config", ".jobs.json")
+	confBytes, err := os.ReadFile(confFilePath) // ([]byte, error)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	if err == nil {
+		if err = json.Unmarshal(confBytes, &c.JobSchedulerConf); err != nil {
+			return err
+		}
+	}
+	if c.JobSchedulerConf.HistorySize > 0 {
+		c.JobScheduler.SetHistorySize(c.JobSchedulerConf.HistorySize)
+	}
+	switch c.JobSchedulerConf.Coordination {
+	case schedjobs.CoordinationNone, "":
+	case schedjobs.CoordinationLeader, schedjobs.CoordinationRunLock:
+		if c.MainKVDB == nil {
+			return fmt.Errorf("job scheduler: main kvdb not ready")
+		}
+		ttl := c.JobSchedulerConf.LeaseTTL
+		var coordinator schedjobs.Coordinator
+		if c.JobSchedulerConf.Coordination == schedjobs.CoordinationLeader {
+			if ttl <= 0 {
+				ttl = defaultSchedulerLeaderTTL
+			}
+			coordinator, err = schedjobs.NewLeaderCoordinator(c.MainKVDB, c.AppName, time.Duration(ttl)*time.Second)
+		} else {
+			if ttl <= 0 {
+				ttl = defaultSchedulerRunLockTTL
+			}
+			coordinator, err = schedjobs.NewRunLockCoordinator(c.MainKVDB, c.AppName, time.Duration(ttl)*time.Second)
+		}
+		if err != nil {
+			return fmt.Errorf("job scheduler: %w", err)
+		}
+		c.JobScheduler.UseCoordinator(coordinator)
+	default:
+		return fmt.Errorf("job scheduler: unknown coordination %q", c.JobSchedulerConf.Coordination)
+	}
 	c.AddService(c.JobScheduler)
+	return nil
 }
@@ -0,0 +1,41 @@
+package framework
+
+import (
+	"encoding/json/v2"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"github.com/x64c/gw/queue"
+)
+
+// PrepareQueueService prepares QueueService from config/.queue.json if present.
+// Without it, only the queues set up with Handle exist, with default confs.
+//
+//	{ "poll_interval": 1, "queues": { "emails": { "concurrency": 4, "visibility_timeout": 30, "max_attempts": 5, "retry_delay": 10 } } }
+//
+// Prerequisite: MainKVDB. JobScheduler (optional) for delayed messages on time.
+// Set handlers with QueueService.Handle or queue.Handle before StartServices.
+func (c *Core) PrepareQueueService() error {
+	if c.MainKVDB == nil {
+		return fmt.Errorf("queue service: main kvdb not ready")
+	}
+	confFilePath := filepath.Join(c.AppRoot, "config", ".queue.json")
+	confBytes, err := os.ReadFile(confFilePath) // ([]byte, error)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	if err == nil {
+		if err = json.Unmarshal(confBytes, &c.QueueConf); err != nil {
+			return err
+		}
+	}
+	c.QueueService = queue.NewService(c.RootCtx, c.MainKVDB, c.AppName, c.QueueConf)
+	if c.JobScheduler != nil {
+		c.QueueService.UseScheduler(c.JobScheduler)
+	}
+	c.AddService(c.QueueService)
+	return nil
+}
@@ -0,0 +1,46 @@
+package framework
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/x64c/gw/kvdbs/kvdbtest"
+)
+
+func TestPrepareQueueService(t *testing.T) {
+	c := &Core{AppRoot: t.TempDir(), RootCtx: context.Background()}
+	if err := c.PrepareQueueService(); err == nil {
+		t.Fatal("prepared without the main kvdb")
+	}
+	c.MainKVDB = kvdbtest.New()
+	// no config/.queue.json: handled queues only, with default confs
+	if err := c.PrepareQueueService(); err != nil {
+		t.Fatalf("missing conf file: %v", err)
+	}
+	if c.QueueService == nil || len(c.QueueConf.Queues) != 0 {
+		t.Fatalf("service %v conf %+v", c.QueueService, c.QueueConf)
+	}
+
+	confDir := filepath.Join(c.AppRoot, "config")
+	if err := os.MkdirAll(confDir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(confDir, ".queue.json"), []byte(`{"queues": {"emails": {"concurrency": 4}}}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.PrepareQueueService(); err != nil {
+		t.Fatal(err)
+	}
+	if c.QueueConf.Queues["emails"].Concurrency != 4 {
+		t.Fatalf("conf %+v", c.QueueConf)
+	}
+
+	if err := os.WriteFile(filepath.Join(confDir, ".queue.json"), []byte(`{`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.PrepareQueueService(); err == nil {
+		t.Fatal("malformed conf file accepted")
+	}
+}
@@ -1,6 +1,12 @@
 package framework
 
 import (
+	"encoding/json/v2"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
 	"time"
 
 	"github.com/x64c/gw/throttle"
@@ -10,3 +16,41 @@ func (c *Core) PrepareThrottleBucketStore(cleanupCycle time.Duration, cleanupOld
 	c.ThrottleBucketStore = throttle.NewBucketStore(c.RootCtx, cleanupCycle, cleanupOlderThan)
 	c.AddService(c.ThrottleBucketStore)
 }
+
+// PrepareThrottleBackend switches where ThrottleBucketStore keeps buckets by config/.throttle.json
+// Without it, buckets stay in memory.
+//
+//	{ "backend": "memory" | "kvdb" }
+//
+// Prerequisite: ThrottleBucketStore, MainKVDB (kvdb)
+func (c *Core) PrepareThrottleBackend() error {
+	if c.ThrottleBucketStore == nil {
+		return fmt.Errorf("throttle: bucket store not ready")
+	}
+	confFilePath := filepath.Join(c.AppRoot, "config", ".throttle.json")
+	confBytes, err := os.ReadFile(confFilePath) // ([]byte, error)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	if err == nil {
+		if err = json.Unmarshal(confBytes, &c.ThrottleConf); err != nil {
+			return err
+		}
+	}
+	switch c.ThrottleConf.Backend {
+	case throttle.BackendMemory, "":
+		c.ThrottleBucketStore.UseBackend(throttle.MemoryBucketBackend{})
+	case throttle.BackendKVDB:
+		if c.MainKVDB == nil {
+			return fmt.Errorf("throttle: main kvdb not ready")
+		}
+		backend, err := throttle.NewKVDBBucketBackend(c.MainKVDB, c.AppName)
+		if err != nil {
+			return fmt.Errorf("throttle: %w", err)
+		}
+		c.ThrottleBucketStore.UseBackend(backend)
+	default:
+		return fmt.Errorf("throttle: unknown backend %q", c.ThrottleConf.Backend)
+	}
+	return nil
+}
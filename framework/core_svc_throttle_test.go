@@ -0,0 +1,43 @@
+package framework
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/x64c/gw/kvdbs/kvdbtest"
+)
+
+func TestPrepareThrottleBackend(t *testing.T) {
+	c := &Core{AppRoot: t.TempDir(), RootCtx: context.Background()}
+	if err := c.PrepareThrottleBackend(); err == nil {
+		t.Fatal("prepared without the bucket store")
+	}
+	c.PrepareThrottleBucketStore(time.Minute, time.Minute)
+	// no config/.throttle.json: buckets in memory
+	if err := c.PrepareThrottleBackend(); err != nil {
+		t.Fatalf("missing conf file: %v", err)
+	}
+
+	confDir := filepath.Join(c.AppRoot, "config")
+	if err := os.MkdirAll(confDir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	for conf, ok := range map[string]bool{
+		`{"backend": "memory"}`: true,
+		`{"backend": "kvdb"}`:   false, // kvdbtest runs no scripts
+		`{"backend": "disk"}`:   false,
+		`{`:                     false,
+	} {
+		if err := os.WriteFile(filepath.Join(confDir, ".throttle.json"), []byte(conf), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		c.ThrottleConf.Backend = ""
+		c.MainKVDB = kvdbtest.New()
+		if err := c.PrepareThrottleBackend(); (err == nil) != ok {
+			t.Errorf("%s: %v", conf, err)
+		}
+	}
+}
@@ -0,0 +1,259 @@
+// Package cache provides a generic typed read-through cache over kvdbs.DB.
+// Values are JSON-encoded (encoding/json/v2) into single-value keys.
+// Concurrent misses of the same key are coalesced into one load (singleflight),
+// TTLs are jittered to avoid synchronized expiration, "not found" results can be cached (negative caching),
+// and keys can be grouped under tags kept in KVDB hashes to invalidate a whole group at once.
+// An optional in-process LRU can be layered in front of the KVDB for hot keys.
+package cache
+
+import (
+	"context"
+	"encoding/json/v2"
+	"errors"
+	"fmt"
+	"log"
+	"math/rand/v2"
+	"time"
+
+	"github.com/x64c/gw/kvdbs"
+)
+
+// ErrNotFound is returned by a LoadFunc when the source has no value for the key.
+// It is cached as a negative entry when Conf.NegativeTTL > 0, and returned by GetOrLoad as-is.
+var ErrNotFound = errors.New("cache: not found")
+
+// negativeMarker is stored in place of a value for negative entries. Never a valid JSON encoding.
+const negativeMarker = "\x00"
+
+// LoadFunc loads the value for a key from the source of truth on a cache miss
+type LoadFunc[K comparable, V any] func(ctx context.Context, key K) (V, error)
+
+type Cache[K comparable, V any] struct {
+	conf   Conf
+	db     kvdbs.DB
+	keyFn  func(K) string
+	load   LoadFunc[K, V]
+	local  *lru[V] // nil if Conf.LocalSize == 0
+	flight flightGroup[V]
+}
+
+// New creates a Cache.
+// keyFn converts a typed key into the key suffix. If nil, fmt.Sprint is used.
+// load may be nil if the cache is only used with Get/Set.
+func New[K comparable, V any](db kvdbs.DB, conf Conf, keyFn func(K) string, load LoadFunc[K, V]) *Cache[K, V] {
+	if keyFn == nil {
+		keyFn = func(k K) string { return fmt.Sprint(k) }
+	}
+	c := &Cache[K, V]{
+		conf:  conf,
+		db:    db,
+		keyFn: keyFn,
+		load:  load,
+	}
+	if conf.LocalSize > 0 {
+		c.local = newLRU[V](conf.LocalSize, conf.LocalTTL)
+	}
+	return c
+}
+
+// Key returns the full KVDB key for a typed key
+func (c *Cache[K, V]) Key(k K) string {
+	return c.conf.Prefix + ":" + c.keyFn(k)
+}
+
+func (c *Cache[K, V]) tagKey(tag string) string {
+	return c.conf.Prefix + ":tags:" + tag
+}
+
+func (c *Cache[K, V]) loadTimeout() time.Duration {
+	if c.conf.LoadTimeout <= 0 {
+		return defaultLoadTimeout
+	}
+	return c.conf.LoadTimeout
+}
+
+func (c *Cache[K, V]) ttl() time.Duration {
+	if c.conf.Jitter <= 0 {
+		return c.conf.TTL
+	}
+	return c.conf.TTL + rand.N(c.conf.Jitter)
+}
+
+// lookup checks the local LRU then the KVDB.
+// Returns val, negative, found, err
+func (c *Cache[K, V]) lookup(ctx context.Context, key string) (V, bool, bool, error) {
+	var zero V
+	now := time.Now()
+	if c.local != nil {
+		if val, negative, ok := c.local.get(key, now); ok {
+			return val, negative, true, nil
+		}
+	}
+	raw, ok, err := c.db.Get(ctx, key)
+	if err != nil || !ok {
+		return zero, false, false, err
+	}
+	if raw == negativeMarker {
+		if c.local != nil {
+			c.local.set(key, zero, true, now)
+		}
+		return zero, true, true, nil
+	}
+	var val V
+	if err = json.Unmarshal([]byte(raw), &val); err != nil {
+		return zero, false, false, fmt.Errorf("cache: decode %q: %w", key, err)
+	}
+	if c.local != nil {
+		c.local.set(key, val, false, now)
+	}
+	return val, false, true, nil
+}
+
+// Get reads a cached value without loading on a miss.
+// A negative entry is reported as not found.
+func (c *Cache[K, V]) Get(ctx context.Context, k K) (V, bool, error) { // val, found, err
+	val, negative, ok, err := c.lookup(ctx, c.Key(k))
+	if err != nil || !ok || negative {
+		var zero V
+		return zero, false, err
+	}
+	return val, true, nil
+}
+
+// GetOrLoad reads a cached value, loading and storing it on a miss.
+// Concurrent misses of the same key in this process share a single load.
+// KVDB failures are logged and degrade to a direct load.
+// tags are attached to the key only when a loaded value is stored.
+func (c *Cache[K, V]) GetOrLoad(ctx context.Context, k K, tags ...string) (V, error) {
+	var zero V
+	if c.load == nil {
+		return zero, errors.New("cache: no load func")
+	}
+	key := c.Key(k)
+	val, negative, ok, err := c.lookup(ctx, key)
+	if err != nil {
+		log.Printf("[WARN][Cache] lookup %q: %v", key, err)
+	} else if ok {
+		if negative {
+			return zero, ErrNotFound
+		}
+		return val, nil
+	}
+	return c.flight.do(ctx, key, func() (V, error) {
+		// the load is shared by every waiter, so it must not end with the caller that started it
+		ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), c.loadTimeout())
+		defer cancel()
+		// re-check: another instance or a finished flight may have filled it meanwhile
+		if val, negative, ok, err := c.lookup(ctx, key); err == nil && ok {
+			if negative {
+				return zero, ErrNotFound
+			}
+			return val, nil
+		}
+		val, err := c.load(ctx, k)
+		if errors.Is(err, ErrNotFound) {
+			if c.conf.NegativeTTL > 0 {
+				if setErr := c.setNegative(ctx, key); setErr != nil {
+					log.Printf("[WARN][Cache] store negative %q: %v", key, setErr)
+				}
+			}
+			return zero, ErrNotFound
+		}
+		if err != nil {
+			return zero, err
+		}
+		if setErr := c.set(ctx, key, val, tags); setErr != nil {
+			log.Printf("[WARN][Cache] store %q: %v", key, setErr)
+		}
+		return val, nil
+	})
+}
+
+// Set stores a value and attaches it to the given tags
+func (c *Cache[K, V]) Set(ctx context.Context, k K, val V, tags ...string) error {
+	return c.set(ctx, c.Key(k), val, tags)
+}
+
+func (c *Cache[K, V]) set(ctx context.Context, key string, val V, tags []string) error {
+	encoded, err := json.Marshal(val)
+	if err != nil {
+		return fmt.Errorf("cache: encode %q: %w", key, err)
+	}
+	ttl := c.ttl()
+	if err = c.db.Set(ctx, key, string(encoded), ttl); err != nil {
+		return err
+	}
+	if c.local != nil {
+		c.local.set(key, val, false, time.Now())
+	}
+	for _, tag := range tags {
+		if err = c.addTagMember(ctx, c.tagKey(tag), key); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// addTagMember adds the key to the tag index.
+// The index TTL is only ever extended, to the longest TTL a member can get, so the index outlives every member.
+func (c *Cache[K, V]) addTagMember(ctx context.Context, tagKey string, key string) error {
+	current, state, err := c.db.TTL(ctx, tagKey)
+	if err != nil {
+		return err
+	}
+	if state == kvdbs.TTLPersistent {
+		return c.db.SetField(ctx, tagKey, key, "1")
+	}
+	return c.db.SetFieldWithTTL(ctx, tagKey, key, "1", max(current, c.conf.TTL+c.conf.Jitter))
+}
+
+func (c *Cache[K, V]) setNegative(ctx context.Context, key string) error {
+	if err := c.db.Set(ctx, key, negativeMarker, c.conf.NegativeTTL); err != nil {
+		return err
+	}
+	if c.local != nil {
+		var zero V
+		c.local.set(key, zero, true, time.Now())
+	}
+	return nil
+}
+
+// Delete removes cached values (including negative entries)
+func (c *Cache[K, V]) Delete(ctx context.Context, ks ...K) error {
+	if len(ks) == 0 {
+		return nil
+	}
+	keys := make([]string, 0, len(ks))
+	for _, k := range ks {
+		keys = append(keys, c.Key(k))
+	}
+	if c.local != nil {
+		c.local.remove(keys...)
+	}
+	_, err := c.db.Delete(ctx, keys...)
+	return err
+}
+
+// InvalidateTags removes every cached value attached to any of the tags, and the tag indexes themselves.
+// The local LRU of other instances is not reached. It expires by Conf.LocalTTL.
+func (c *Cache[K, V]) InvalidateTags(ctx context.Context, tags ...string) error {
+	for _, tag := range tags {
+		tagKey := c.tagKey(tag)
+		members, err := c.db.GetAllFields(ctx, tagKey)
+		if err != nil {
+			return err
+		}
+		keys := make([]string, 0, len(members)+1)
+		for key := range members {
+			keys = append(keys, key)
+		}
+		if c.local != nil {
+			c.local.remove(keys...)
+		}
+		keys = append(keys, tagKey)
+		if _, err = c.db.Delete(ctx, keys...); err != nil {
+			return err
+		}
+	}
+	return nil
+}
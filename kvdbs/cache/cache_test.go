@@ -0,0 +1,125 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/x64c/gw/kvdbs"
+	"github.com/x64c/gw/kvdbs/kvdbtest"
+)
+
+func TestJitteredTTL(t *testing.T) {
+	db := kvdbtest.New()
+	c := New[string, int](db, Conf{Prefix: "c", TTL: time.Minute, Jitter: 10 * time.Second}, nil, nil)
+	ctx := context.Background()
+	seen := make(map[time.Duration]bool)
+	for range 50 {
+		if err := c.Set(ctx, "k", 1); err != nil {
+			t.Fatal(err)
+		}
+		ttl, state, _ := db.TTL(ctx, c.Key("k"))
+		if state != kvdbs.TTLExpiring || ttl > 70*time.Second || ttl < 59*time.Second {
+			t.Fatalf("ttl %v out of [TTL, TTL+Jitter)", ttl)
+		}
+		seen[ttl.Round(time.Millisecond)] = true
+	}
+	if len(seen) < 2 {
+		t.Fatal("ttl not jittered")
+	}
+}
+
+func TestInvalidateTags(t *testing.T) {
+	db := kvdbtest.New()
+	c := New[string, string](db, Conf{Prefix: "c", TTL: time.Minute}, nil, nil)
+	ctx := context.Background()
+	_ = c.Set(ctx, "a", "A", "users")
+	_ = c.Set(ctx, "b", "B", "users", "admins")
+	_ = c.Set(ctx, "c", "C", "admins")
+	if err := c.InvalidateTags(ctx, "users"); err != nil {
+		t.Fatal(err)
+	}
+	for k, want := range map[string]bool{"a": false, "b": false, "c": true} {
+		if _, found, _ := c.Get(ctx, k); found != want {
+			t.Errorf("%s found = %v, want %v", k, found, want)
+		}
+	}
+}
+
+func TestTagIndexTTLNeverShrinks(t *testing.T) {
+	db := kvdbtest.New()
+	ctx := context.Background()
+	long := New[string, string](db, Conf{Prefix: "c", TTL: time.Hour}, nil, nil)
+	short := New[string, string](db, Conf{Prefix: "c", TTL: time.Minute}, nil, nil)
+	_ = long.Set(ctx, "old", "v", "t")
+	_ = short.Set(ctx, "new", "v", "t")
+	ttl, _, _ := db.TTL(ctx, long.tagKey("t"))
+	if ttl < 59*time.Minute {
+		t.Fatalf("tag index ttl shrunk to %v below its longest member", ttl)
+	}
+}
+
+func TestGetOrLoadSingleflight(t *testing.T) {
+	var loads atomic.Int32
+	release := make(chan struct{})
+	load := func(ctx context.Context, k string) (string, error) {
+		loads.Add(1)
+		<-release
+		return "v:" + k, nil
+	}
+	c := New[string, string](kvdbtest.New(), Conf{Prefix: "c", TTL: time.Minute}, nil, load)
+
+	// the first caller gives up, the others still get the shared load
+	firstCtx, cancelFirst := context.WithCancel(context.Background())
+	firstErr := make(chan error, 1)
+	go func() {
+		_, err := c.GetOrLoad(firstCtx, "k")
+		firstErr <- err
+	}()
+	for loads.Load() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	var wg sync.WaitGroup
+	results := make([]string, 5)
+	errList := make([]error, 5)
+	for i := range results {
+		wg.Go(func() {
+			results[i], errList[i] = c.GetOrLoad(context.Background(), "k")
+		})
+	}
+	cancelFirst()
+	if err := <-firstErr; !errors.Is(err, context.Canceled) {
+		t.Fatalf("cancelled caller: %v", err)
+	}
+	time.Sleep(10 * time.Millisecond) // let the waiters join the flight
+	close(release)
+	wg.Wait()
+	for i := range results {
+		if errList[i] != nil || results[i] != "v:k" {
+			t.Errorf("waiter %d: %q, %v", i, results[i], errList[i])
+		}
+	}
+	if n := loads.Load(); n != 1 {
+		t.Fatalf("%d loads, want 1", n)
+	}
+}
+
+func TestNegativeCaching(t *testing.T) {
+	var loads atomic.Int32
+	load := func(ctx context.Context, k string) (string, error) {
+		loads.Add(1)
+		return "", ErrNotFound
+	}
+	c := New[string, string](kvdbtest.New(), Conf{Prefix: "c", TTL: time.Minute, NegativeTTL: time.Minute}, nil, load)
+	for range 3 {
+		if _, err := c.GetOrLoad(context.Background(), "k"); !errors.Is(err, ErrNotFound) {
+			t.Fatal(err)
+		}
+	}
+	if n := loads.Load(); n != 1 {
+		t.Fatalf("%d loads, want 1", n)
+	}
+}
@@ -0,0 +1,15 @@
+package cache
+
+import "time"
+
+const defaultLoadTimeout = 30 * time.Second
+
+type Conf struct {
+	Prefix      string        // key prefix. e.g. AppName + ":cache:users"
+	TTL         time.Duration // base TTL of a cached value in the KVDB
+	Jitter      time.Duration // random [0, Jitter) added to TTL to spread out expirations
+	NegativeTTL time.Duration // TTL of a "not found" marker. 0 = negative caching disabled
+	LocalSize   int           // max# of entries in the in-process LRU. 0 = no local layer
+	LocalTTL    time.Duration // TTL of a local LRU entry. Keep it short; invalidations do not reach other instances
+	LoadTimeout time.Duration // deadline of a shared load, which does not end with its callers' contexts. default 30s
+}
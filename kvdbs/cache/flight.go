@@ -0,0 +1,56 @@
+package cache
+
+import (
+	"context"
+	"fmt"
+	"sync"
+)
+
+// flightCall is an in-flight or completed load shared by concurrent callers
+type flightCall[V any] struct {
+	done chan struct{}
+	val  V
+	err  error
+}
+
+// flightGroup coalesces concurrent loads of the same key into a single call (singleflight)
+type flightGroup[V any] struct {
+	mu    sync.Mutex
+	calls map[string]*flightCall[V]
+}
+
+// do runs fn once per key at a time. Callers arriving while fn is running wait and share its result.
+// fn runs apart from the callers: a caller whose ctx is done stops waiting, and the others still get the result.
+func (g *flightGroup[V]) do(ctx context.Context, key string, fn func() (V, error)) (V, error) {
+	g.mu.Lock()
+	if g.calls == nil {
+		g.calls = make(map[string]*flightCall[V])
+	}
+	c, ok := g.calls[key]
+	if !ok {
+		c = &flightCall[V]{done: make(chan struct{})}
+		g.calls[key] = c
+		go func() {
+			defer func() {
+				// no caller stack to unwind into. report it to the waiters instead of crashing
+				if r := recover(); r != nil {
+					c.err = fmt.Errorf("cache: load panicked: %v", r)
+				}
+				g.mu.Lock()
+				delete(g.calls, key)
+				g.mu.Unlock()
+				close(c.done)
+			}()
+			c.val, c.err = fn()
+		}()
+	}
+	g.mu.Unlock()
+
+	select {
+	case <-c.done:
+		return c.val, c.err
+	case <-ctx.Done():
+		var zero V
+		return zero, ctx.Err()
+	}
+}
@@ -0,0 +1,80 @@
+package cache
+
+import (
+	"container/list"
+	"sync"
+	"time"
+)
+
+type lruEntry[V any] struct {
+	key      string
+	val      V
+	negative bool // cached "not found"
+	expireAt time.Time
+}
+
+// lru is a size-bounded in-process cache placed in front of the KVDB for hot keys
+type lru[V any] struct {
+	mu    sync.Mutex
+	size  int
+	ttl   time.Duration
+	ll    *list.List               // front = most recently used
+	items map[string]*list.Element // key -> element holding *lruEntry[V]
+}
+
+func newLRU[V any](size int, ttl time.Duration) *lru[V] {
+	return &lru[V]{
+		size:  size,
+		ttl:   ttl,
+		ll:    list.New(),
+		items: make(map[string]*list.Element, size),
+	}
+}
+
+// get returns val, negative, found
+func (c *lru[V]) get(key string, now time.Time) (V, bool, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	var zero V
+	elem, ok := c.items[key]
+	if !ok {
+		return zero, false, false
+	}
+	entry := elem.Value.(*lruEntry[V])
+	if now.After(entry.expireAt) {
+		c.ll.Remove(elem)
+		delete(c.items, key)
+		return zero, false, false
+	}
+	c.ll.MoveToFront(elem)
+	return entry.val, entry.negative, true
+}
+
+func (c *lru[V]) set(key string, val V, negative bool, now time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	expireAt := now.Add(c.ttl)
+	if elem, ok := c.items[key]; ok {
+		entry := elem.Value.(*lruEntry[V])
+		entry.val, entry.negative, entry.expireAt = val, negative, expireAt
+		c.ll.MoveToFront(elem)
+		return
+	}
+	c.items[key] = c.ll.PushFront(&lruEntry[V]{key: key, val: val, negative: negative, expireAt: expireAt})
+	for c.ll.Len() > c.size {
+		oldest := c.ll.Back()
+		c.ll.Remove(oldest)
+		delete(c.items, oldest.Value.(*lruEntry[V]).key)
+	}
+}
+
+func (c *lru[V]) remove(keys ...string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, key := range keys {
+		if elem, ok := c.items[key]; ok {
+			c.ll.Remove(elem)
+			delete(c.items, key)
+		}
+	}
+}
@@ -0,0 +1,18 @@
+package kvdbs
+
+import (
+	"context"
+	"time"
+)
+
+// ConditionalDB is an optional capability of DB for conditional single-value ops.
+// Used for leases and distributed locks where check-and-act must be atomic on the backend.
+// Type-assert a DB to check availability.
+type ConditionalDB interface {
+	// SetIfAbsent sets the value only if the key does not exist (e.g. Redis SET NX PX)
+	SetIfAbsent(ctx context.Context, key string, value string, expiration time.Duration) (bool, error) // set, err
+	// DeleteIfEquals deletes the key only if its current value equals value
+	DeleteIfEquals(ctx context.Context, key string, value string) (bool, error) // deleted, err
+	// ExpireIfEquals updates the key's expiration only if its current value equals value
+	ExpireIfEquals(ctx context.Context, key string, value string, expiration time.Duration) (bool, error) // updated, err
+}
@@ -0,0 +1,113 @@
+package kvdbs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"slices"
+)
+
+// InspectCommand is a uds.CommandHandler pretty-printing live entries of registered key families.
+// Without args, lists the registered families.
+type InspectCommand struct {
+	Registry *KeyRegistry
+	DB       DB
+}
+
+func (c *InspectCommand) Command() string {
+	return "kv-inspect"
+}
+
+func (c *InspectCommand) GroupName() string {
+	return "KVDB"
+}
+
+func (c *InspectCommand) Desc() string {
+	return "inspect a KVDB entry by key family"
+}
+
+func (c *InspectCommand) Usage() string {
+	return "kv-inspect [<family> [<id>]]"
+}
+
+func (c *InspectCommand) HandleCommand(args []string, w io.Writer) error {
+	if len(args) == 0 {
+		for _, f := range c.Registry.Families() {
+			_, _ = fmt.Fprintf(w, "%-36s %-7s %-8s %s\n", f.Name, f.ValueType, f.TTLPolicy, f.Pattern)
+		}
+		return nil
+	}
+	f, ok := c.Registry.Family(args[0])
+	if !ok {
+		return fmt.Errorf("unknown key family %q", args[0])
+	}
+	var id string
+	if f.HasID() {
+		if len(args) < 2 {
+			return errors.New("usage: " + c.Usage())
+		}
+		id = args[1]
+	}
+	key, err := c.Registry.Key(f.Name, id)
+	if err != nil {
+		return err
+	}
+	ctx := context.Background()
+
+	ttl, ttlState, err := c.DB.TTL(ctx, key)
+	if err != nil {
+		return err
+	}
+	_, _ = fmt.Fprintf(w, "key:  %s\n", key)
+	switch ttlState {
+	case TTLKeyNotFound:
+		_, _ = fmt.Fprintln(w, "(not found)")
+		return nil
+	case TTLPersistent:
+		_, _ = fmt.Fprintln(w, "ttl:  persistent")
+	default:
+		_, _ = fmt.Fprintf(w, "ttl:  %v\n", ttl)
+	}
+	_, _ = fmt.Fprintf(w, "type: %s\n", f.ValueType)
+
+	switch f.ValueType {
+	case ValueString:
+		val, _, err := c.DB.Get(ctx, key)
+		if err != nil {
+			return err
+		}
+		_, _ = fmt.Fprintf(w, "  %q\n", val)
+	case ValueList:
+		vals, err := c.DB.Range(ctx, key, 0, -1)
+		if err != nil {
+			return err
+		}
+		for i, val := range vals {
+			_, _ = fmt.Fprintf(w, "  [%d] %q\n", i, val)
+		}
+	case ValueHash:
+		fields, err := c.DB.GetAllFields(ctx, key)
+		if err != nil {
+			return err
+		}
+		names := slices.Clone(f.Fields) // declared fields first, in declaration order
+		for _, name := range slices.Sorted(maps.Keys(fields)) {
+			if !slices.Contains(names, name) {
+				names = append(names, name)
+			}
+		}
+		for _, name := range names {
+			val, found := fields[name]
+			if !found {
+				_, _ = fmt.Fprintf(w, "  %-24s (missing)\n", name)
+				continue
+			}
+			_, _ = fmt.Fprintf(w, "  %-24s %q\n", name, val)
+		}
+	default:
+		return fmt.Errorf("unsupported value type %q", f.ValueType)
+	}
+	return nil
+}
@@ -0,0 +1,50 @@
+package kvdbs
+
+import "strings"
+
+type ValueType string
+
+const (
+	ValueString ValueType = "string"
+	ValueList   ValueType = "list"
+	ValueHash   ValueType = "hash"
+)
+
+type TTLPolicy string
+
+const (
+	TTLNone    TTLPolicy = "none"    // persistent
+	TTLFixed   TTLPolicy = "fixed"   // set once on creation
+	TTLSliding TTLPolicy = "sliding" // extended on access
+	TTLLease   TTLPolicy = "lease"   // owner-renewed lease
+)
+
+// IDPlaceholder is replaced by the id in KeyFamily.Pattern
+const IDPlaceholder = "{id}"
+
+// KeyFamily declares a family of KVDB keys sharing a layout and a value type.
+// Declare families as package-level vars next to the feature using them, and build keys only through Key.
+// Register them in a KeyRegistry to detect collisions and to inspect live entries.
+type KeyFamily struct {
+	Name      string    // unique family name. e.g. "ucookie_session"
+	Pattern   string    // key layout after "<AppName>:". "{id}" is replaced by the id. e.g. "ucookie_session:{id}:access_tokens"
+	ValueType ValueType // value type stored at the key
+	Fields    []string  // known hash fields (ValueHash only). nil = dynamic fields
+	TTLPolicy TTLPolicy
+	Desc      string
+}
+
+// idEscaper keeps an id a single key segment. "%" is escaped too, so distinct ids never map to the same key
+var idEscaper = strings.NewReplacer("%", "%25", ":", "%3A")
+
+// Key builds the full key for an id: "<AppName>:<Pattern with {id} replaced>"
+// ":" in the id is escaped to "%3A" (and "%" to "%25"), so composite ids such as "group:bucket" or an IPv6 address
+// stay a single key segment. KeyRegistry collision checks rely on it.
+func (f *KeyFamily) Key(appName string, id string) string {
+	return appName + ":" + strings.Replace(f.Pattern, IDPlaceholder, idEscaper.Replace(id), 1)
+}
+
+// HasID reports whether the pattern takes an id
+func (f *KeyFamily) HasID() bool {
+	return strings.Contains(f.Pattern, IDPlaceholder)
+}
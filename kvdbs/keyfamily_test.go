@@ -0,0 +1,41 @@
+package kvdbs
+
+import "testing"
+
+func TestKeyEscapesID(t *testing.T) {
+	f := &KeyFamily{Name: "throttle_bucket", Pattern: "throttle:{id}"}
+	tests := map[string]string{
+		"session":           "app:throttle:session",
+		"login:2001:db8::1": "app:throttle:login%3A2001%3Adb8%3A%3A1",
+		"group:bucket":      "app:throttle:group%3Abucket",
+		"group%3Abucket":    "app:throttle:group%253Abucket",
+		"job@1700000000":    "app:throttle:job@1700000000",
+	}
+	for id, want := range tests {
+		if got := f.Key("app", id); got != want {
+			t.Errorf("Key(%q) = %q, want %q", id, got, want)
+		}
+	}
+}
+
+func TestRegisterCollisions(t *testing.T) {
+	r := NewKeyRegistry("app")
+	if err := r.Register(
+		&KeyFamily{Name: "session", Pattern: "session:{id}"},
+		&KeyFamily{Name: "session_tokens", Pattern: "session:{id}:tokens"},
+	); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Register(&KeyFamily{Name: "session_meta", Pattern: "session:meta"}); err == nil {
+		t.Error("session:meta should collide with session:{id}")
+	}
+	if err := r.Register(&KeyFamily{Name: "partial", Pattern: "x:pre{id}"}); err == nil {
+		t.Error("partial-segment id should be rejected")
+	}
+	// an id with ":" cannot reach the tokens family through the session family
+	session, _ := r.Family("session")
+	tokens, _ := r.Family("session_tokens")
+	if session.Key("app", "s1:tokens") == tokens.Key("app", "s1") {
+		t.Error("composite id produced another family's key")
+	}
+}
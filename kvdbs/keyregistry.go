@@ -0,0 +1,90 @@
+package kvdbs
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// KeyRegistry is the set of KeyFamily declarations of an app.
+// Registration fails on duplicate names or patterns that can produce the same key.
+type KeyRegistry struct {
+	AppName  string
+	families map[string]*KeyFamily
+	order    []string // registration order
+}
+
+func NewKeyRegistry(appName string) *KeyRegistry {
+	return &KeyRegistry{
+		AppName:  appName,
+		families: make(map[string]*KeyFamily),
+	}
+}
+
+func (r *KeyRegistry) Register(families ...*KeyFamily) error {
+	for _, f := range families {
+		if f.Name == "" || f.Pattern == "" {
+			return fmt.Errorf("kvdbs: key family name and pattern are required")
+		}
+		if strings.Count(f.Pattern, IDPlaceholder) > 1 {
+			return fmt.Errorf("kvdbs: key family %q: at most one %s allowed", f.Name, IDPlaceholder)
+		}
+		if f.HasID() && !slices.Contains(strings.Split(f.Pattern, ":"), IDPlaceholder) {
+			return fmt.Errorf("kvdbs: key family %q: %s must be a whole segment", f.Name, IDPlaceholder)
+		}
+		if _, exists := r.families[f.Name]; exists {
+			return fmt.Errorf("kvdbs: key family %q already registered", f.Name)
+		}
+		for _, name := range r.order {
+			if other := r.families[name]; patternsCollide(f.Pattern, other.Pattern) {
+				return fmt.Errorf("kvdbs: key family %q collides with %q (%q vs %q)", f.Name, other.Name, f.Pattern, other.Pattern)
+			}
+		}
+		r.families[f.Name] = f
+		r.order = append(r.order, f.Name)
+	}
+	return nil
+}
+
+func (r *KeyRegistry) Family(name string) (*KeyFamily, bool) {
+	f, ok := r.families[name]
+	return f, ok
+}
+
+// Families returns all families in registration order
+func (r *KeyRegistry) Families() []*KeyFamily {
+	families := make([]*KeyFamily, len(r.order))
+	for i, name := range r.order {
+		families[i] = r.families[name]
+	}
+	return families
+}
+
+// Key builds the key of a registered family
+func (r *KeyRegistry) Key(familyName string, id string) (string, error) {
+	f, ok := r.families[familyName]
+	if !ok {
+		return "", fmt.Errorf("kvdbs: unknown key family %q", familyName)
+	}
+	if f.HasID() && id == "" {
+		return "", fmt.Errorf("kvdbs: key family %q: invalid id %q", familyName, id)
+	}
+	return f.Key(r.AppName, id), nil
+}
+
+// patternsCollide reports whether two patterns share a layout:
+// the same segment count and every segment pair equal or taking an id.
+// ids are single segments, as KeyFamily.Key escapes ":" in them.
+func patternsCollide(a, b string) bool {
+	aSegs := strings.Split(a, ":")
+	bSegs := strings.Split(b, ":")
+	if len(aSegs) != len(bSegs) {
+		return false
+	}
+	for i := range aSegs {
+		if aSegs[i] != bSegs[i] && aSegs[i] != IDPlaceholder && bSegs[i] != IDPlaceholder {
+			return false
+		}
+	}
+	return true
+}
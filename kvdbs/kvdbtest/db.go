@@ -0,0 +1,399 @@
+// Package kvdbtest provides an in-memory kvdbs.DB for tests.
+// It implements kvdbs.DB and kvdbs.ConditionalDB with expirations, in a single process.
+package kvdbtest
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/x64c/gw/kvdbs"
+)
+
+type entry struct {
+	str      string
+	list     []string
+	hash     map[string]string
+	typ      kvdbs.ValueType
+	expireAt time.Time // zero = persistent
+}
+
+// DB is an in-memory kvdbs.DB. The zero value is not ready. Use New
+type DB struct {
+	mu      sync.Mutex
+	entries map[string]*entry
+	now     func() time.Time
+}
+
+func New() *DB {
+	return &DB{entries: make(map[string]*entry), now: time.Now}
+}
+
+// SetClock replaces the clock used for expirations
+func (d *DB) SetClock(now func() time.Time) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.now = now
+}
+
+// live returns the unexpired entry at the key. Must hold mu
+func (d *DB) live(key string) (*entry, bool) {
+	e, ok := d.entries[key]
+	if !ok {
+		return nil, false
+	}
+	if !e.expireAt.IsZero() && !d.now().Before(e.expireAt) {
+		delete(d.entries, key)
+		return nil, false
+	}
+	return e, true
+}
+
+// typed returns the entry of the value type at the key, creating it if missing. Must hold mu
+func (d *DB) typed(key string, typ kvdbs.ValueType) (*entry, error) {
+	e, ok := d.live(key)
+	if !ok {
+		e = &entry{typ: typ}
+		if typ == kvdbs.ValueHash {
+			e.hash = make(map[string]string)
+		}
+		d.entries[key] = e
+		return e, nil
+	}
+	if e.typ != typ {
+		return nil, fmt.Errorf("kvdbtest: %q holds a %s", key, e.typ)
+	}
+	return e, nil
+}
+
+func (d *DB) expireAt(expiration time.Duration) time.Time {
+	if expiration <= 0 {
+		return time.Time{}
+	}
+	return d.now().Add(expiration)
+}
+
+//---- Key Ops ----
+
+func (d *DB) Exists(_ context.Context, key string) (bool, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	_, ok := d.live(key)
+	return ok, nil
+}
+
+func (d *DB) TTL(_ context.Context, key string) (time.Duration, kvdbs.TTLState, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	e, ok := d.live(key)
+	if !ok {
+		return 0, kvdbs.TTLKeyNotFound, nil
+	}
+	if e.expireAt.IsZero() {
+		return 0, kvdbs.TTLPersistent, nil
+	}
+	return e.expireAt.Sub(d.now()), kvdbs.TTLExpiring, nil
+}
+
+func (d *DB) Delete(_ context.Context, keys ...string) (int64, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	var n int64
+	for _, key := range keys {
+		if _, ok := d.live(key); ok {
+			delete(d.entries, key)
+			n++
+		}
+	}
+	return n, nil
+}
+
+func (d *DB) Expire(_ context.Context, key string, expiration time.Duration) (bool, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	e, ok := d.live(key)
+	if !ok {
+		return false, nil
+	}
+	e.expireAt = d.expireAt(expiration)
+	return true, nil
+}
+
+func (d *DB) Type(_ context.Context, key string) (string, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	e, ok := d.live(key)
+	if !ok {
+		return "none", nil
+	}
+	return string(e.typ), nil
+}
+
+// ScanKeys returns all the keys at once
+func (d *DB) ScanKeys(_ context.Context, _ any, _ int) ([]string, any, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	keys := make([]string, 0, len(d.entries))
+	for key := range d.entries {
+		if _, ok := d.live(key); ok {
+			keys = append(keys, key)
+		}
+	}
+	slices.Sort(keys)
+	return keys, nil, nil
+}
+
+//---- Single-value Ops ----
+
+func (d *DB) Set(_ context.Context, key string, value any, expiration time.Duration) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.entries[key] = &entry{typ: kvdbs.ValueString, str: fmt.Sprint(value), expireAt: d.expireAt(expiration)}
+	return nil
+}
+
+func (d *DB) Get(_ context.Context, key string) (string, bool, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	e, ok := d.live(key)
+	if !ok {
+		return "", false, nil
+	}
+	if e.typ != kvdbs.ValueString {
+		return "", false, fmt.Errorf("kvdbtest: %q holds a %s", key, e.typ)
+	}
+	return e.str, true, nil
+}
+
+//---- List Ops ----
+
+func (d *DB) Push(_ context.Context, key string, value string) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	e, err := d.typed(key, kvdbs.ValueList)
+	if err != nil {
+		return err
+	}
+	e.list = append(e.list, value)
+	return nil
+}
+
+func (d *DB) Pop(_ context.Context, key string) (string, bool, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	e, ok := d.live(key)
+	if !ok || len(e.list) == 0 {
+		return "", false, nil
+	}
+	val := e.list[0]
+	e.list = e.list[1:]
+	if len(e.list) == 0 {
+		delete(d.entries, key)
+	}
+	return val, true, nil
+}
+
+func (d *DB) Len(_ context.Context, key string) (int64, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	e, ok := d.live(key)
+	if !ok {
+		return 0, nil
+	}
+	return int64(len(e.list)), nil
+}
+
+// bounds converts inclusive, possibly negative, list indexes into a slice range
+func bounds(n int, start int64, stop int64) (int, int) {
+	if start < 0 {
+		start += int64(n)
+	}
+	if stop < 0 {
+		stop += int64(n)
+	}
+	start = max(start, 0)
+	stop = min(stop, int64(n)-1)
+	if start > stop {
+		return 0, 0
+	}
+	return int(start), int(stop) + 1
+}
+
+func (d *DB) Range(_ context.Context, key string, start int64, stop int64) ([]string, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	e, ok := d.live(key)
+	if !ok {
+		return nil, nil
+	}
+	from, to := bounds(len(e.list), start, stop)
+	return slices.Clone(e.list[from:to]), nil
+}
+
+func (d *DB) Remove(_ context.Context, key string, cnt int64, value any) (int64, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	e, ok := d.live(key)
+	if !ok {
+		return 0, nil
+	}
+	target := fmt.Sprint(value)
+	var removed int64
+	e.list = slices.DeleteFunc(e.list, func(v string) bool {
+		if v != target || (cnt > 0 && removed >= cnt) {
+			return false
+		}
+		removed++
+		return true
+	})
+	return removed, nil
+}
+
+func (d *DB) Trim(_ context.Context, key string, start int64, stop int64) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	e, ok := d.live(key)
+	if !ok {
+		return nil
+	}
+	from, to := bounds(len(e.list), start, stop)
+	e.list = slices.Clone(e.list[from:to])
+	return nil
+}
+
+//---- Hash Ops ----
+
+func (d *DB) SetField(ctx context.Context, key string, field string, value any) error {
+	return d.SetFields(ctx, key, map[string]any{field: value})
+}
+
+func (d *DB) SetFieldWithTTL(ctx context.Context, key string, field string, value any, ttl time.Duration) error {
+	return d.SetFieldsWithTTL(ctx, key, map[string]any{field: value}, ttl)
+}
+
+func (d *DB) SetFields(_ context.Context, key string, fields map[string]any) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	e, err := d.typed(key, kvdbs.ValueHash)
+	if err != nil {
+		return err
+	}
+	for field, value := range fields {
+		e.hash[field] = fmt.Sprint(value)
+	}
+	return nil
+}
+
+func (d *DB) SetFieldsWithTTL(_ context.Context, key string, fields map[string]any, ttl time.Duration) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	e, err := d.typed(key, kvdbs.ValueHash)
+	if err != nil {
+		return err
+	}
+	for field, value := range fields {
+		e.hash[field] = fmt.Sprint(value)
+	}
+	e.expireAt = d.expireAt(ttl)
+	return nil
+}
+
+func (d *DB) GetField(_ context.Context, key string, field string) (string, bool, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	e, ok := d.live(key)
+	if !ok {
+		return "", false, nil
+	}
+	val, ok := e.hash[field]
+	return val, ok, nil
+}
+
+func (d *DB) GetFields(_ context.Context, key string, fields ...string) (map[string]string, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	found := make(map[string]string, len(fields))
+	e, ok := d.live(key)
+	if !ok {
+		return found, nil
+	}
+	for _, field := range fields {
+		if val, ok := e.hash[field]; ok {
+			found[field] = val
+		}
+	}
+	return found, nil
+}
+
+func (d *DB) RemoveFields(_ context.Context, key string, fields ...string) (int64, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	e, ok := d.live(key)
+	if !ok {
+		return 0, nil
+	}
+	var n int64
+	for _, field := range fields {
+		if _, ok := e.hash[field]; ok {
+			delete(e.hash, field)
+			n++
+		}
+	}
+	if len(e.hash) == 0 {
+		delete(d.entries, key)
+	}
+	return n, nil
+}
+
+func (d *DB) GetAllFields(_ context.Context, key string) (map[string]string, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	all := make(map[string]string)
+	e, ok := d.live(key)
+	if !ok {
+		return all, nil
+	}
+	for field, val := range e.hash {
+		all[field] = val
+	}
+	return all, nil
+}
+
+//---- Conditional Ops ----
+
+func (d *DB) SetIfAbsent(_ context.Context, key string, value string, expiration time.Duration) (bool, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if _, ok := d.live(key); ok {
+		return false, nil
+	}
+	d.entries[key] = &entry{typ: kvdbs.ValueString, str: value, expireAt: d.expireAt(expiration)}
+	return true, nil
+}
+
+func (d *DB) DeleteIfEquals(_ context.Context, key string, value string) (bool, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	e, ok := d.live(key)
+	if !ok || e.typ != kvdbs.ValueString || e.str != value {
+		return false, nil
+	}
+	delete(d.entries, key)
+	return true, nil
+}
+
+func (d *DB) ExpireIfEquals(_ context.Context, key string, value string, expiration time.Duration) (bool, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	e, ok := d.live(key)
+	if !ok || e.typ != kvdbs.ValueString || e.str != value {
+		return false, nil
+	}
+	e.expireAt = d.expireAt(expiration)
+	return true, nil
+}
+
+var _ kvdbs.DB = (*DB)(nil)
+var _ kvdbs.ConditionalDB = (*DB)(nil)
@@ -0,0 +1,85 @@
+package kvdbs
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// ErrPipelineNotExecuted is returned by a Result read before its Pipeline is executed
+var ErrPipelineNotExecuted = errors.New("kvdbs: pipeline not executed")
+
+// PipelineDB is an optional capability of DB for batching operations into one round trip.
+// Type-assert a DB to check availability, and fall back to single ops otherwise.
+type PipelineDB interface {
+	// Pipeline starts a batch. Queued ops are sent together on Exec, in queued order.
+	Pipeline() Pipeline
+	// TxPipeline starts a batch executed atomically on Exec (e.g. Redis MULTI/EXEC).
+	// No other client's op interleaves with the queued ops.
+	TxPipeline() Pipeline
+}
+
+// Pipeline queues ops mirroring DB methods. Each returns a Result resolved by Exec.
+type Pipeline interface {
+	//---- Key Ops ----
+
+	Exists(key string) *Result[bool]
+	TTL(key string) *Result[TTLInfo]
+	Delete(keys ...string) *Result[int64]
+	Expire(key string, expiration time.Duration) *Result[bool]
+
+	//---- Single-value Ops ----
+
+	Set(key string, value any, expiration time.Duration) *Result[struct{}]
+	Get(key string) *Result[Found[string]]
+
+	//---- List Ops ----
+
+	Push(key string, value string) *Result[struct{}]
+	Len(key string) *Result[int64]
+	Range(key string, start int64, stop int64) *Result[[]string] // 0-basis, stop inclusive
+	Trim(key string, start int64, stop int64) *Result[struct{}]  // 0-basis, stop inclusive
+
+	//---- Hash Ops ----
+
+	SetField(key string, field string, value any) *Result[struct{}]
+	SetFields(key string, fields map[string]any) *Result[struct{}]
+	GetField(key string, field string) *Result[Found[string]]
+	GetFields(key string, fields ...string) *Result[map[string]string]
+
+	// Exec sends the queued ops and resolves their Results.
+	// Returns the first op error (or the transport error). Every Result is resolved either way.
+	Exec(ctx context.Context) error
+}
+
+// Result is the deferred outcome of a queued Pipeline op
+type Result[T any] struct {
+	val      T
+	err      error
+	resolved bool
+}
+
+// Resolve is called by Pipeline implementations on Exec
+func (r *Result[T]) Resolve(val T, err error) {
+	r.val, r.err, r.resolved = val, err, true
+}
+
+func (r *Result[T]) Val() (T, error) {
+	if !r.resolved {
+		var zero T
+		return zero, ErrPipelineNotExecuted
+	}
+	return r.val, r.err
+}
+
+// Found is a value with its existence. For ops returning (val, found, err) on DB
+type Found[T any] struct {
+	Val   T
+	Found bool
+}
+
+// TTLInfo is the (ttl, state) pair returned by DB.TTL
+type TTLInfo struct {
+	TTL   time.Duration
+	State TTLState
+}
@@ -0,0 +1,11 @@
+package kvdbs
+
+import "context"
+
+// ScriptDB is an optional capability of DB for running server-side scripts atomically (e.g. Redis EVALSHA with Lua).
+// Used for read-modify-write ops that must take one round trip. Type-assert a DB to check availability.
+type ScriptDB interface {
+	// Eval runs the Lua script atomically. Implementations may cache it by digest.
+	// Results map integers to int64, strings to string, arrays to []any and nil replies to nil.
+	Eval(ctx context.Context, script string, keys []string, args ...any) (any, error)
+}
@@ -0,0 +1,14 @@
+package namedlocks
+
+type Backend string
+
+const (
+	BackendMemory Backend = "memory" // MapStore
+	BackendKVDB   Backend = "kvdb"   // KVDBStore
+)
+
+type Conf struct {
+	Backend       Backend `json:"backend"`
+	LeaseTTL      int     `json:"lease_ttl"`      // seconds
+	RenewInterval int     `json:"renew_interval"` // seconds. 0 = no renewal while the holder runs
+}
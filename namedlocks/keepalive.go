@@ -0,0 +1,34 @@
+package namedlocks
+
+import (
+	"context"
+	"log"
+	"time"
+)
+
+// KeepAlive renews the lease every interval until the returned stop func is called or ctx is done.
+// Lost locks (renewal refused) are logged and end the renewal; the holder is not interrupted.
+func KeepAlive(ctx context.Context, store Store, lease *Lease, ttl time.Duration, interval time.Duration) (stop func()) {
+	ctx, cancel := context.WithCancel(ctx)
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				ok, err := store.Renew(ctx, lease, ttl)
+				if err != nil {
+					log.Printf("[WARN][NamedLocks] renew %v: %v", lease.Names, err)
+					continue
+				}
+				if !ok {
+					log.Printf("[WARN][NamedLocks] lost %v", lease.Names)
+					return
+				}
+			}
+		}
+	}()
+	return cancel
+}
@@ -0,0 +1,12 @@
+package namedlocks
+
+import "github.com/x64c/gw/kvdbs"
+
+// KeyActionLock - action lock name -> owner token (KVDBStore for ActionLocks)
+var KeyActionLock = &kvdbs.KeyFamily{
+	Name:      "action_lock",
+	Pattern:   "action_lock:{id}",
+	ValueType: kvdbs.ValueString,
+	TTLPolicy: kvdbs.TTLLease,
+	Desc:      "action lock owner token by lock name (\"action:target\")",
+}
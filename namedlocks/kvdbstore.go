@@ -0,0 +1,140 @@
+package namedlocks
+
+import (
+	"context"
+	"time"
+
+	"github.com/x64c/gw/kvdbs"
+)
+
+const defaultKVDBRetryInterval = 200 * time.Millisecond
+
+// KVDBStore is a Store shared by multiple app instances through a KVDB.
+// Each lock is a single-value key holding the owner token with the lease ttl.
+// The DB must implement kvdbs.ConditionalDB.
+//
+// In WAIT mode, releases within this process wake the first local waiter of the name immediately.
+// kvdbs.DB has no release notifications, so releases by other instances (or lease expirations)
+// are observed by re-checking every RetryInterval.
+type KVDBStore struct {
+	RetryInterval time.Duration // WAIT mode re-check interval for releases by other instances
+
+	db      kvdbs.ConditionalDB
+	appName string
+	family  *kvdbs.KeyFamily // lock name -> key. e.g. KeyActionLock
+	waiters waitQueues
+}
+
+func NewKVDBStore(db kvdbs.DB, appName string, family *kvdbs.KeyFamily) (*KVDBStore, error) {
+	condDB, ok := db.(kvdbs.ConditionalDB)
+	if !ok {
+		return nil, kvdbs.ErrNotSupported
+	}
+	return &KVDBStore{
+		RetryInterval: defaultKVDBRetryInterval,
+		db:            condDB,
+		appName:       appName,
+		family:        family,
+	}, nil
+}
+
+func (s *KVDBStore) key(lockName string) string {
+	return s.family.Key(s.appName, lockName)
+}
+
+func (s *KVDBStore) TryAcquire(ctx context.Context, lockNames []string, ttl time.Duration) (*Lease, bool, error) {
+	lease := newLease(nil)
+	for _, lockName := range lockNames {
+		set, err := s.db.SetIfAbsent(ctx, s.key(lockName), lease.Token, ttl)
+		if err != nil || !set {
+			// release all locks acquired up to this point
+			if releaseErr := s.Release(context.WithoutCancel(ctx), lease); releaseErr != nil && err == nil {
+				err = releaseErr
+			}
+			return nil, false, err
+		}
+		lease.Names = append(lease.Names, lockName)
+	}
+	return lease, true, nil
+}
+
+func (s *KVDBStore) Acquire(ctx context.Context, lockNames []string, ttl time.Duration) (*Lease, error) {
+	lease := newLease(nil)
+	for _, lockName := range sortedUnique(lockNames) {
+		if err := s.acquireOne(ctx, lockName, lease.Token, ttl); err != nil {
+			if releaseErr := s.Release(context.WithoutCancel(ctx), lease); releaseErr != nil {
+				return nil, releaseErr
+			}
+			return nil, err
+		}
+		lease.Names = append(lease.Names, lockName)
+	}
+	return lease, nil
+}
+
+func (s *KVDBStore) acquireOne(ctx context.Context, lockName string, token string, ttl time.Duration) error {
+	for {
+		set, err := s.db.SetIfAbsent(ctx, s.key(lockName), token, ttl)
+		if err != nil {
+			return err
+		}
+		if set {
+			return nil
+		}
+		s.waiters.mu.Lock()
+		w := s.waiters.enqueue(lockName, token)
+		s.waiters.mu.Unlock()
+
+		timer := time.NewTimer(s.RetryInterval)
+		select {
+		case <-w.ready: // released locally. try again
+		case <-timer.C:
+			s.waiters.mu.Lock()
+			s.waiters.remove(lockName, w)
+			s.waiters.mu.Unlock()
+		case <-ctx.Done():
+			timer.Stop()
+			s.waiters.mu.Lock()
+			removed := s.waiters.remove(lockName, w)
+			s.waiters.mu.Unlock()
+			if !removed { // notified concurrently. pass it on
+				s.notify(lockName)
+			}
+			return ctx.Err()
+		}
+		timer.Stop()
+	}
+}
+
+// notify wakes the first local waiter of the name
+func (s *KVDBStore) notify(lockName string) {
+	s.waiters.mu.Lock()
+	defer s.waiters.mu.Unlock()
+	if w, ok := s.waiters.dequeue(lockName); ok {
+		close(w.ready)
+	}
+}
+
+func (s *KVDBStore) Release(ctx context.Context, lease *Lease) error {
+	var firstErr error
+	for _, lockName := range lease.Names {
+		deleted, err := s.db.DeleteIfEquals(ctx, s.key(lockName), lease.Token)
+		if err != nil && firstErr == nil {
+			firstErr = err
+		}
+		if deleted {
+			s.notify(lockName)
+		}
+	}
+	return firstErr
+}
+
+func (s *KVDBStore) Renew(ctx context.Context, lease *Lease, ttl time.Duration) (bool, error) {
+	for _, lockName := range lease.Names {
+		updated, err := s.db.ExpireIfEquals(ctx, s.key(lockName), lease.Token, ttl)
+		if err != nil || !updated {
+			return false, err
+		}
+	}
+	return true, nil
+}
@@ -0,0 +1,100 @@
+package namedlocks
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// MapStore is a process-local Store on a *sync.Map (lockName -> owner token).
+// Locks die with the process, so ttl is ignored.
+// Protects nothing across multiple app instances. Use KVDBStore for that.
+//
+// In WAIT mode, a released lock is handed over directly to the first waiter of its name (FIFO).
+// Locks released with the legacy ReleaseLocks do not wake waiters.
+type MapStore struct {
+	locks   *sync.Map
+	waiters waitQueues
+}
+
+func NewMapStore(locks *sync.Map) *MapStore {
+	return &MapStore{locks: locks}
+}
+
+func (s *MapStore) TryAcquire(_ context.Context, lockNames []string, _ time.Duration) (*Lease, bool, error) {
+	lease := newLease(nil)
+	for _, lockName := range lockNames {
+		if _, lockedOut := s.locks.LoadOrStore(lockName, lease.Token); lockedOut {
+			s.releaseNames(lease.Names, lease.Token)
+			return nil, false, nil
+		}
+		lease.Names = append(lease.Names, lockName)
+	}
+	return lease, true, nil
+}
+
+func (s *MapStore) Acquire(ctx context.Context, lockNames []string, _ time.Duration) (*Lease, error) {
+	lease := newLease(nil)
+	for _, lockName := range sortedUnique(lockNames) {
+		if err := s.acquireOne(ctx, lockName, lease.Token); err != nil {
+			s.releaseNames(lease.Names, lease.Token)
+			return nil, err
+		}
+		lease.Names = append(lease.Names, lockName)
+	}
+	return lease, nil
+}
+
+func (s *MapStore) acquireOne(ctx context.Context, lockName string, token string) error {
+	s.waiters.mu.Lock()
+	if _, lockedOut := s.locks.LoadOrStore(lockName, token); !lockedOut {
+		s.waiters.mu.Unlock()
+		return nil
+	}
+	w := s.waiters.enqueue(lockName, token)
+	s.waiters.mu.Unlock()
+
+	select {
+	case <-w.ready: // handed over by the releaser
+		return nil
+	case <-ctx.Done():
+		s.waiters.mu.Lock()
+		removed := s.waiters.remove(lockName, w)
+		s.waiters.mu.Unlock()
+		if !removed { // handed over concurrently. pass it on
+			s.releaseNames([]string{lockName}, token)
+		}
+		return ctx.Err()
+	}
+}
+
+func (s *MapStore) Release(_ context.Context, lease *Lease) error {
+	s.releaseNames(lease.Names, lease.Token)
+	return nil
+}
+
+// releaseNames releases the locks owned by token, handing each over to its first waiter if any
+func (s *MapStore) releaseNames(lockNames []string, token string) {
+	s.waiters.mu.Lock()
+	defer s.waiters.mu.Unlock()
+	for _, lockName := range lockNames {
+		if owner, ok := s.locks.Load(lockName); !ok || owner != token {
+			continue
+		}
+		if w, ok := s.waiters.dequeue(lockName); ok {
+			s.locks.Store(lockName, w.token)
+			close(w.ready)
+			continue
+		}
+		s.locks.CompareAndDelete(lockName, token)
+	}
+}
+
+func (s *MapStore) Renew(_ context.Context, lease *Lease, _ time.Duration) (bool, error) {
+	for _, lockName := range lease.Names {
+		if owner, ok := s.locks.Load(lockName); !ok || owner != lease.Token {
+			return false, nil
+		}
+	}
+	return true, nil
+}
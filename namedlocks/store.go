@@ -0,0 +1,41 @@
+package namedlocks
+
+import (
+	"context"
+	"crypto/rand"
+	"time"
+)
+
+// Store is a pluggable registry of named locks.
+// A lock is owned by a Lease carrying a random owner token, so only the owner can release or renew it.
+type Store interface {
+	// TryAcquire acquires all the named locks or none (fail-fast).
+	// ttl bounds how long the locks survive if the owner never releases them. Stores may ignore it.
+	TryAcquire(ctx context.Context, lockNames []string, ttl time.Duration) (*Lease, bool, error)
+	// Release releases the locks still owned by the lease
+	Release(ctx context.Context, lease *Lease) error
+	// Renew extends the ttl of the locks. Returns false if any of them is no longer owned by the lease.
+	Renew(ctx context.Context, lease *Lease, ttl time.Duration) (bool, error)
+}
+
+// WaitingStore is an optional capability of Store for blocking acquisition (WAIT mode).
+// Type-assert a Store to check availability.
+type WaitingStore interface {
+	Store
+	// Acquire blocks until all the named locks are acquired or ctx is done.
+	// Locks are acquired in sorted order to avoid deadlocks; waiters on the same name are served in arrival order.
+	// On ctx done, locks acquired so far are released and ctx.Err() is returned.
+	Acquire(ctx context.Context, lockNames []string, ttl time.Duration) (*Lease, error)
+}
+
+type Lease struct {
+	Names []string
+	Token string // owner token
+}
+
+func newLease(lockNames []string) *Lease {
+	return &Lease{
+		Names: lockNames,
+		Token: rand.Text(),
+	}
+}
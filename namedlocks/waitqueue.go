@@ -0,0 +1,66 @@
+package namedlocks
+
+import (
+	"slices"
+	"sync"
+)
+
+// waiter is a blocked Acquire call waiting for a single lock name
+type waiter struct {
+	token string
+	ready chan struct{} // closed on notification
+}
+
+// waitQueues holds per-name FIFO queues of waiters, so locks are granted in arrival order
+type waitQueues struct {
+	mu     sync.Mutex
+	queues map[string][]*waiter // lockName -> waiters
+}
+
+// enqueue must be called with mu held
+func (q *waitQueues) enqueue(lockName string, token string) *waiter {
+	if q.queues == nil {
+		q.queues = make(map[string][]*waiter)
+	}
+	w := &waiter{token: token, ready: make(chan struct{})}
+	q.queues[lockName] = append(q.queues[lockName], w)
+	return w
+}
+
+// dequeue pops the first waiter of the name. Must be called with mu held
+func (q *waitQueues) dequeue(lockName string) (*waiter, bool) {
+	queue := q.queues[lockName]
+	if len(queue) == 0 {
+		return nil, false
+	}
+	w := queue[0]
+	if len(queue) == 1 {
+		delete(q.queues, lockName)
+	} else {
+		q.queues[lockName] = queue[1:]
+	}
+	return w, true
+}
+
+// remove drops a waiter that gave up. Returns false if it was already dequeued (notified).
+// Must be called with mu held
+func (q *waitQueues) remove(lockName string, w *waiter) bool {
+	queue := q.queues[lockName]
+	i := slices.Index(queue, w)
+	if i < 0 {
+		return false
+	}
+	queue = slices.Delete(queue, i, i+1)
+	if len(queue) == 0 {
+		delete(q.queues, lockName)
+	} else {
+		q.queues[lockName] = queue
+	}
+	return true
+}
+
+// sortedUnique returns a sorted copy of lockNames without duplicates.
+// Acquiring in a global order prevents deadlocks between waiters holding some of the locks.
+func sortedUnique(lockNames []string) []string {
+	return slices.Compact(slices.Sorted(slices.Values(lockNames)))
+}
@@ -0,0 +1,58 @@
+package queue
+
+import "time"
+
+// Conf is the queue service config (.queue.json)
+type Conf struct {
+	Queues       map[string]QueueConf `json:"queues"`        // queue name -> conf. queues with a Handler get default confs
+	PollInterval int                  `json:"poll_interval"` // seconds. idle workers and the re-delivery check. default 1
+}
+
+type QueueConf struct {
+	Concurrency       int `json:"concurrency"`        // workers on this instance. default 1
+	VisibilityTimeout int `json:"visibility_timeout"` // seconds. a message not acked within it is re-delivered. default 30
+	MaxAttempts       int `json:"max_attempts"`       // deliveries before dead-lettering. default 5
+	RetryDelay        int `json:"retry_delay"`        // seconds before re-delivering a failed message. doubled per attempt. default 10
+	MaxDeadLetters    int `json:"max_dead_letters"`   // dead letters kept. the oldest are dropped. default 1000
+}
+
+const (
+	defaultPollInterval      = 1  // seconds
+	defaultVisibilityTimeout = 30 // seconds
+	defaultMaxAttempts       = 5
+	defaultRetryDelay        = 10 // seconds
+	defaultMaxDeadLetters    = 1000
+	maxRetryDelay            = time.Hour
+)
+
+func (c QueueConf) withDefaults() QueueConf {
+	if c.Concurrency <= 0 {
+		c.Concurrency = 1
+	}
+	if c.VisibilityTimeout <= 0 {
+		c.VisibilityTimeout = defaultVisibilityTimeout
+	}
+	if c.MaxAttempts <= 0 {
+		c.MaxAttempts = defaultMaxAttempts
+	}
+	if c.RetryDelay <= 0 {
+		c.RetryDelay = defaultRetryDelay
+	}
+	if c.MaxDeadLetters <= 0 {
+		c.MaxDeadLetters = defaultMaxDeadLetters
+	}
+	return c
+}
+
+func (c QueueConf) visibility() time.Duration {
+	return time.Duration(c.VisibilityTimeout) * time.Second
+}
+
+// retryDelay returns the delay before re-delivering a message that failed its attempts-th delivery
+func (c QueueConf) retryDelay(attempts int) time.Duration {
+	delay := time.Duration(c.RetryDelay) * time.Second
+	for i := 1; i < attempts && delay < maxRetryDelay; i++ {
+		delay *= 2
+	}
+	return min(delay, maxRetryDelay)
+}
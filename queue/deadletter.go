@@ -0,0 +1,70 @@
+package queue
+
+import (
+	"context"
+	"encoding/json/v2"
+	"fmt"
+	"time"
+)
+
+func (s *Service) queue(name string) (*queue, error) {
+	q, ok := s.queues[name]
+	if !ok {
+		return nil, fmt.Errorf("%w: %q", ErrUnknownQueue, name)
+	}
+	return q, nil
+}
+
+// DeadLetters returns the dead letters of a queue, oldest first
+func (s *Service) DeadLetters(ctx context.Context, queueName string) ([]DeadLetter, error) {
+	q, err := s.queue(queueName)
+	if err != nil {
+		return nil, err
+	}
+	raws, err := s.db.Range(ctx, q.deadKey, 0, -1)
+	if err != nil {
+		return nil, err
+	}
+	letters := make([]DeadLetter, 0, len(raws))
+	for _, raw := range raws {
+		var letter DeadLetter
+		if err = json.Unmarshal([]byte(raw), &letter); err != nil {
+			return nil, err
+		}
+		letters = append(letters, letter)
+	}
+	return letters, nil
+}
+
+// RequeueDeadLetters enqueues the dead letters of a queue again with fresh attempts, under their IDs.
+// Returns the number requeued.
+func (s *Service) RequeueDeadLetters(ctx context.Context, queueName string) (int, error) {
+	q, err := s.queue(queueName)
+	if err != nil {
+		return 0, err
+	}
+	raws, err := s.db.Range(ctx, q.deadKey, 0, -1)
+	if err != nil {
+		return 0, err
+	}
+	requeued := 0
+	for _, raw := range raws {
+		var letter DeadLetter
+		if err = json.Unmarshal([]byte(raw), &letter); err != nil {
+			return requeued, err
+		}
+		now := time.Now().UnixMilli()
+		msg := message{ID: letter.ID, Payload: letter.Payload, EnqueuedAt: now, UpdatedAt: now}
+		if err = s.saveMessage(ctx, q, &msg); err != nil {
+			return requeued, err
+		}
+		if err = s.push(ctx, q, msg.ID); err != nil {
+			return requeued, err
+		}
+		if _, err = s.db.Remove(ctx, q.deadKey, 1, raw); err != nil {
+			return requeued, err
+		}
+		requeued++
+	}
+	return requeued, nil
+}
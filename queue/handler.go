@@ -0,0 +1,27 @@
+package queue
+
+import (
+	"context"
+	"encoding/json/jsontext"
+	"encoding/json/v2"
+	"errors"
+	"fmt"
+)
+
+// ErrPermanent marks a failure not worth retrying. A Handler error wrapping it dead-letters the message right away
+var ErrPermanent = errors.New("queue: permanent failure")
+
+// Handler processes the JSON payload of a message. Messages are delivered at least once: handlers must be idempotent.
+// ctx is done when the visibility timeout runs out. The message is then re-delivered anyway.
+type Handler func(ctx context.Context, payload jsontext.Value) error
+
+// HandlerFor adapts a typed handler. Payloads that fail to decode are dead-lettered
+func HandlerFor[T any](fn func(ctx context.Context, payload T) error) Handler {
+	return func(ctx context.Context, payload jsontext.Value) error {
+		var v T
+		if err := json.Unmarshal(payload, &v); err != nil {
+			return fmt.Errorf("%w: decode payload: %v", ErrPermanent, err)
+		}
+		return fn(ctx, v)
+	}
+}
@@ -0,0 +1,44 @@
+package queue
+
+import "github.com/x64c/gw/kvdbs"
+
+// KeyQueueReady - message IDs ready for delivery. id = queue name
+var KeyQueueReady = &kvdbs.KeyFamily{
+	Name:      "queue_ready",
+	Pattern:   "queue:{id}:ready",
+	ValueType: kvdbs.ValueList,
+	TTLPolicy: kvdbs.TTLNone,
+	Desc:      "message IDs ready for delivery by queue name",
+}
+
+// KeyQueueMessages - pending messages. id = queue name. message ID -> JSON
+var KeyQueueMessages = &kvdbs.KeyFamily{
+	Name:      "queue_messages",
+	Pattern:   "queue:{id}:messages",
+	ValueType: kvdbs.ValueHash,
+	TTLPolicy: kvdbs.TTLNone,
+	Desc:      "pending messages by queue name",
+}
+
+// KeyQueueInflight - delivered messages not acked yet. id = queue name. message ID -> re-delivery time (unix millis)
+var KeyQueueInflight = &kvdbs.KeyFamily{
+	Name:      "queue_inflight",
+	Pattern:   "queue:{id}:inflight",
+	ValueType: kvdbs.ValueHash,
+	TTLPolicy: kvdbs.TTLNone,
+	Desc:      "in-flight message re-delivery times by queue name",
+}
+
+// KeyQueueDead - dead letters (JSON), oldest first. id = queue name
+var KeyQueueDead = &kvdbs.KeyFamily{
+	Name:      "queue_dead",
+	Pattern:   "queue:{id}:dead",
+	ValueType: kvdbs.ValueList,
+	TTLPolicy: kvdbs.TTLNone,
+	Desc:      "dead letters by queue name",
+}
+
+// KeyFamilies returns all KVDB key families of the queue service
+func KeyFamilies() []*kvdbs.KeyFamily {
+	return []*kvdbs.KeyFamily{KeyQueueReady, KeyQueueMessages, KeyQueueInflight, KeyQueueDead}
+}
@@ -0,0 +1,24 @@
+package queue
+
+import "encoding/json/jsontext"
+
+// message is the JSON stored in KeyQueueMessages
+type message struct {
+	ID         string         `json:"id"`
+	Payload    jsontext.Value `json:"payload"`
+	Attempts   int            `json:"attempts"`
+	EnqueuedAt int64          `json:"enqueued_at"`          // unix millis
+	DeliverAt  int64          `json:"deliver_at,omitempty"` // unix millis. delayed messages only
+	UpdatedAt  int64          `json:"updated_at"`           // unix millis. last enqueue or delivery
+	LastError  string         `json:"last_error,omitempty"`
+}
+
+// DeadLetter is a message that exhausted its attempts or failed permanently
+type DeadLetter struct {
+	ID         string         `json:"id"`
+	Payload    jsontext.Value `json:"payload"`
+	Attempts   int            `json:"attempts"`
+	EnqueuedAt int64          `json:"enqueued_at"` // unix millis
+	FailedAt   int64          `json:"failed_at"`   // unix millis
+	Error      string         `json:"error"`
+}
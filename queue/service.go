@@ -0,0 +1,448 @@
+package queue
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/json/jsontext"
+	"encoding/json/v2"
+	"errors"
+	"fmt"
+	"log"
+	"runtime/debug"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/x64c/gw/kvdbs"
+	"github.com/x64c/gw/schedjobs"
+	"github.com/x64c/gw/svc"
+)
+
+// ErrUnknownQueue is returned for a queue neither configured nor handled
+var ErrUnknownQueue = errors.New("queue: unknown queue")
+
+// sweepInterval is how often lost messages are looked for (e.g. popped by an instance that crashed right after)
+const sweepInterval = time.Minute
+
+// Service is a durable work queue on KVDB lists and hashes, with named queues.
+// A message is re-delivered if not acked within its queue's visibility timeout, retried with backoff on failure,
+// and dead-lettered after MaxAttempts. Delivery is at least once.
+type Service struct {
+	Ctx       context.Context    // Service Context
+	cancel    context.CancelFunc // Service Context CancelFunc
+	state     int                // internal service state
+	done      chan error         // Shutdown Error Channel
+	db        kvdbs.DB
+	appName   string
+	poll      time.Duration
+	queues    map[string]*queue
+	scheduler *schedjobs.Scheduler // UseScheduler. nil = delayed messages are picked up by the sweep
+	wg        sync.WaitGroup
+}
+
+type queue struct {
+	name        string
+	conf        QueueConf
+	handler     Handler // nil = enqueue only on this instance
+	readyKey    string
+	messagesKey string
+	inflightKey string
+	deadKey     string
+	notify      chan struct{} // wakes an idle worker on a local enqueue
+}
+
+func (s *Service) Name() string {
+	return "QueueService"
+}
+
+func NewService(parentCtx context.Context, db kvdbs.DB, appName string, conf Conf) *Service {
+	svcCtx, svcCancel := context.WithCancel(parentCtx)
+	s := &Service{
+		Ctx:     svcCtx,
+		cancel:  svcCancel,
+		state:   svc.StateREADY,
+		done:    make(chan error, 1),
+		db:      db,
+		appName: appName,
+		poll:    time.Duration(defaultPollInterval) * time.Second,
+		queues:  make(map[string]*queue),
+	}
+	if conf.PollInterval > 0 {
+		s.poll = time.Duration(conf.PollInterval) * time.Second
+	}
+	for name, qConf := range conf.Queues {
+		s.addQueue(name, qConf)
+	}
+	return s
+}
+
+func (s *Service) addQueue(name string, conf QueueConf) *queue {
+	q := &queue{
+		name:        name,
+		conf:        conf.withDefaults(),
+		readyKey:    KeyQueueReady.Key(s.appName, name),
+		messagesKey: KeyQueueMessages.Key(s.appName, name),
+		inflightKey: KeyQueueInflight.Key(s.appName, name),
+		deadKey:     KeyQueueDead.Key(s.appName, name),
+		notify:      make(chan struct{}, 1),
+	}
+	s.queues[name] = q
+	return q
+}
+
+// UseScheduler delivers delayed messages on time through one-time jobs. Call before Start
+func (s *Service) UseScheduler(scheduler *schedjobs.Scheduler) {
+	s.scheduler = scheduler
+}
+
+// Handle sets the handler of a queue and runs its workers on this instance. Call before Start.
+// Queues missing in Conf get the default QueueConf.
+func (s *Service) Handle(queueName string, handler Handler) {
+	q, ok := s.queues[queueName]
+	if !ok {
+		q = s.addQueue(queueName, QueueConf{})
+	}
+	q.handler = handler
+}
+
+// Handle sets a typed handler of a queue. See Service.Handle
+func Handle[T any](s *Service, queueName string, fn func(ctx context.Context, payload T) error) {
+	s.Handle(queueName, HandlerFor(fn))
+}
+
+func (s *Service) Start() error {
+	if s.state == svc.StateRUNNING {
+		return fmt.Errorf("already started")
+	}
+	if s.state != svc.StateREADY {
+		return fmt.Errorf("cannot start. not ready")
+	}
+	s.state = svc.StateRUNNING
+	for _, q := range s.queues {
+		if q.handler != nil {
+			for range q.conf.Concurrency {
+				s.wg.Go(func() { s.work(q) })
+			}
+		}
+		s.wg.Go(func() { s.maintain(q) })
+	}
+	go func() {
+		<-s.Ctx.Done()
+		log.Println("[INFO][Queue] shutting down...")
+		s.wg.Wait() // in-flight messages finish, bounded by their visibility timeouts
+		s.done <- nil
+	}()
+	log.Println("[INFO][Queue] service started")
+	return nil
+}
+
+func (s *Service) Stop() {
+	if s.state != svc.StateRUNNING {
+		log.Println("[ERROR][Queue] cannot stop. not running")
+		return
+	}
+	s.cancel()
+	s.state = svc.StateSTOPPED
+	log.Println("[INFO][Queue] service stopped")
+}
+
+func (s *Service) Done() <-chan error {
+	return s.done
+}
+
+// Enqueue adds a message with a JSON-encoded payload. Returns the message ID
+func (s *Service) Enqueue(ctx context.Context, queueName string, payload any) (string, error) {
+	return s.EnqueueAt(ctx, queueName, payload, time.Time{})
+}
+
+// EnqueueAfter adds a message delivered after delay
+func (s *Service) EnqueueAfter(ctx context.Context, queueName string, payload any, delay time.Duration) (string, error) {
+	return s.EnqueueAt(ctx, queueName, payload, time.Now().Add(delay))
+}
+
+// EnqueueAt adds a message delivered at deliverAt. Zero or past deliverAt = right away.
+// Without UseScheduler, or if this instance stops before deliverAt, the message is delivered by the next sweep.
+func (s *Service) EnqueueAt(ctx context.Context, queueName string, payload any, deliverAt time.Time) (string, error) {
+	q, err := s.queue(queueName)
+	if err != nil {
+		return "", err
+	}
+	encoded, err := json.Marshal(payload)
+	if err != nil {
+		return "", err
+	}
+	now := time.Now()
+	msg := message{
+		ID:         rand.Text(),
+		Payload:    encoded,
+		EnqueuedAt: now.UnixMilli(),
+		UpdatedAt:  now.UnixMilli(),
+	}
+	delayed := deliverAt.After(now)
+	if delayed {
+		msg.DeliverAt = deliverAt.UnixMilli()
+	}
+	if err = s.saveMessage(ctx, q, &msg); err != nil {
+		return "", err
+	}
+	if !delayed {
+		return msg.ID, s.push(ctx, q, msg.ID)
+	}
+	if s.scheduler != nil {
+		id := msg.ID
+		err = s.scheduler.AddOneTimeJob(&schedjobs.OneTimeJob{
+			ID:       "queue:" + queueName + ":" + id,
+			ExecTime: deliverAt,
+			TaskCtx: func(ctx context.Context) error {
+				return s.push(ctx, q, id)
+			},
+		})
+		if err != nil { // deliverAt passed meanwhile
+			return msg.ID, s.push(ctx, q, msg.ID)
+		}
+	}
+	return msg.ID, nil
+}
+
+func (s *Service) saveMessage(ctx context.Context, q *queue, msg *message) error {
+	encoded, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	return s.db.SetField(ctx, q.messagesKey, msg.ID, string(encoded))
+}
+
+// push makes a message ready for delivery
+func (s *Service) push(ctx context.Context, q *queue, id string) error {
+	if err := s.db.Push(ctx, q.readyKey, id); err != nil {
+		return err
+	}
+	select {
+	case q.notify <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+// work runs a worker of the queue until the service stops
+func (s *Service) work(q *queue) {
+	for {
+		if s.Ctx.Err() != nil {
+			return
+		}
+		id, found, err := s.db.Pop(s.Ctx, q.readyKey)
+		if err != nil && s.Ctx.Err() == nil {
+			log.Printf("[ERROR][Queue] %s: pop: %v", q.name, err)
+		}
+		if err != nil || !found {
+			select {
+			case <-s.Ctx.Done():
+				return
+			case <-q.notify:
+			case <-time.After(s.poll):
+			}
+			continue
+		}
+		// the message is not lost if we stop now: it is in-flight or swept
+		s.deliver(context.WithoutCancel(s.Ctx), q, id)
+	}
+}
+
+// deliver processes a popped message
+func (s *Service) deliver(ctx context.Context, q *queue, id string) {
+	now := time.Now()
+	if until, ok := s.inflightUntil(ctx, q, id); ok && until.After(now) {
+		return // a duplicate of a message being processed elsewhere
+	}
+	if err := s.setInflight(ctx, q, id, now.Add(q.conf.visibility())); err != nil {
+		log.Printf("[ERROR][Queue] %s: message %s: %v", q.name, id, err)
+		_ = s.push(ctx, q, id)
+		return
+	}
+	raw, found, err := s.db.GetField(ctx, q.messagesKey, id)
+	if err != nil {
+		log.Printf("[ERROR][Queue] %s: message %s: %v", q.name, id, err)
+		return // re-delivered after the visibility timeout
+	}
+	if !found { // acked meanwhile
+		_, _ = s.db.RemoveFields(ctx, q.inflightKey, id)
+		return
+	}
+	var msg message
+	if err = json.Unmarshal([]byte(raw), &msg); err != nil {
+		log.Printf("[ERROR][Queue] %s: message %s: malformed: %v", q.name, id, err)
+		s.ack(ctx, q, id)
+		return
+	}
+	msg.Attempts++
+	msg.UpdatedAt = now.UnixMilli()
+	if err = s.saveMessage(ctx, q, &msg); err != nil {
+		log.Printf("[ERROR][Queue] %s: message %s: %v", q.name, id, err)
+	}
+
+	err = s.handle(ctx, q, msg.Payload)
+	switch {
+	case err == nil:
+		s.ack(ctx, q, id)
+	case errors.Is(err, ErrPermanent) || msg.Attempts >= q.conf.MaxAttempts:
+		log.Printf("[ERROR][Queue] %s: message %s dead-lettered after %d attempts: %v", q.name, id, msg.Attempts, err)
+		s.deadLetter(ctx, q, &msg, err)
+	default:
+		delay := q.conf.retryDelay(msg.Attempts)
+		log.Printf("[WARN][Queue] %s: message %s attempt %d failed: %v. retrying in %s", q.name, id, msg.Attempts, err, delay)
+		msg.LastError = err.Error()
+		if err = s.saveMessage(ctx, q, &msg); err != nil {
+			log.Printf("[ERROR][Queue] %s: message %s: %v", q.name, id, err)
+		}
+		if err = s.setInflight(ctx, q, id, time.Now().Add(delay)); err != nil {
+			log.Printf("[ERROR][Queue] %s: message %s: %v", q.name, id, err)
+		}
+	}
+}
+
+// handle runs the handler within the visibility timeout, recovering panics
+func (s *Service) handle(ctx context.Context, q *queue, payload jsontext.Value) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			log.Printf("[PANIC][Queue] %s: handler panicked: %v\n%s", q.name, r, debug.Stack())
+			err = fmt.Errorf("handler panicked: %v", r)
+		}
+	}()
+	ctx, cancel := context.WithTimeout(ctx, q.conf.visibility())
+	defer cancel()
+	return q.handler(ctx, payload)
+}
+
+func (s *Service) ack(ctx context.Context, q *queue, id string) {
+	if _, err := s.db.RemoveFields(ctx, q.messagesKey, id); err != nil {
+		log.Printf("[ERROR][Queue] %s: ack %s: %v", q.name, id, err)
+		return
+	}
+	_, _ = s.db.RemoveFields(ctx, q.inflightKey, id)
+}
+
+func (s *Service) inflightUntil(ctx context.Context, q *queue, id string) (time.Time, bool) {
+	raw, found, err := s.db.GetField(ctx, q.inflightKey, id)
+	if err != nil || !found {
+		return time.Time{}, false
+	}
+	ms, err := strconv.ParseInt(raw, 10, 64)
+	if err != nil {
+		return time.Time{}, false
+	}
+	return time.UnixMilli(ms), true
+}
+
+// setInflight hides a message until t, when it is re-delivered unless acked
+func (s *Service) setInflight(ctx context.Context, q *queue, id string, t time.Time) error {
+	return s.db.SetField(ctx, q.inflightKey, id, strconv.FormatInt(t.UnixMilli(), 10))
+}
+
+func (s *Service) deadLetter(ctx context.Context, q *queue, msg *message, cause error) {
+	encoded, err := json.Marshal(DeadLetter{
+		ID:         msg.ID,
+		Payload:    msg.Payload,
+		Attempts:   msg.Attempts,
+		EnqueuedAt: msg.EnqueuedAt,
+		FailedAt:   time.Now().UnixMilli(),
+		Error:      cause.Error(),
+	})
+	if err == nil {
+		err = s.db.Push(ctx, q.deadKey, string(encoded))
+	}
+	if err != nil {
+		log.Printf("[ERROR][Queue] %s: dead-letter %s: %v", q.name, msg.ID, err)
+		return // retried after the visibility timeout
+	}
+	if err = s.db.Trim(ctx, q.deadKey, -int64(q.conf.MaxDeadLetters), -1); err != nil {
+		log.Printf("[ERROR][Queue] %s: trim dead letters: %v", q.name, err)
+	}
+	s.ack(ctx, q, msg.ID)
+}
+
+// maintain re-delivers expired in-flight messages every poll interval and sweeps lost ones, until the service stops
+func (s *Service) maintain(q *queue) {
+	ticker := time.NewTicker(s.poll)
+	defer ticker.Stop()
+	lastSweep := time.Now()
+	for {
+		select {
+		case <-s.Ctx.Done():
+			return
+		case now := <-ticker.C:
+			if err := s.redeliver(s.Ctx, q, now); err != nil && s.Ctx.Err() == nil {
+				log.Printf("[ERROR][Queue] %s: re-delivery: %v", q.name, err)
+			}
+			if now.Sub(lastSweep) >= sweepInterval {
+				lastSweep = now
+				if err := s.sweep(s.Ctx, q, now); err != nil && s.Ctx.Err() == nil {
+					log.Printf("[ERROR][Queue] %s: sweep: %v", q.name, err)
+				}
+			}
+		}
+	}
+}
+
+// redeliver makes in-flight messages past their visibility timeout (or retry delay) ready again
+func (s *Service) redeliver(ctx context.Context, q *queue, now time.Time) error {
+	inflight, err := s.db.GetAllFields(ctx, q.inflightKey)
+	if err != nil {
+		return err
+	}
+	for id, raw := range inflight {
+		ms, err := strconv.ParseInt(raw, 10, 64)
+		if err == nil && time.UnixMilli(ms).After(now) {
+			continue
+		}
+		// only the instance removing the entry re-delivers it
+		removed, err := s.db.RemoveFields(ctx, q.inflightKey, id)
+		if err != nil {
+			return err
+		}
+		if removed == 1 {
+			if err = s.push(ctx, q, id); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// sweep makes ready the messages found neither ready nor in-flight, well past their last update or delivery time.
+// Covers delayed messages whose one-time job was lost, and messages popped by an instance that died right after.
+func (s *Service) sweep(ctx context.Context, q *queue, now time.Time) error {
+	messages, err := s.db.GetAllFields(ctx, q.messagesKey)
+	if err != nil || len(messages) == 0 {
+		return err
+	}
+	inflight, err := s.db.GetAllFields(ctx, q.inflightKey)
+	if err != nil {
+		return err
+	}
+	ready, err := s.db.Range(ctx, q.readyKey, 0, -1)
+	if err != nil {
+		return err
+	}
+	isReady := make(map[string]bool, len(ready))
+	for _, id := range ready {
+		isReady[id] = true
+	}
+	grace := q.conf.visibility().Milliseconds()
+	for id, raw := range messages {
+		if _, ok := inflight[id]; ok || isReady[id] {
+			continue
+		}
+		var msg message
+		if err = json.Unmarshal([]byte(raw), &msg); err != nil {
+			continue
+		}
+		if now.UnixMilli() < max(msg.UpdatedAt, msg.DeliverAt)+grace {
+			continue
+		}
+		log.Printf("[WARN][Queue] %s: lost message %s made ready", q.name, id)
+		if err = s.push(ctx, q, id); err != nil {
+			return err
+		}
+	}
+	return nil
+}
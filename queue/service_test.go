@@ -0,0 +1,247 @@
+package queue
+
+import (
+	"context"
+	"encoding/json/jsontext"
+	"encoding/json/v2"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/x64c/gw/kvdbs/kvdbtest"
+)
+
+// testHandler records payloads and fails while fail returns an error
+type testHandler struct {
+	payloads []string
+	fail     func(attempt int) error
+}
+
+func (h *testHandler) handle(_ context.Context, payload jsontext.Value) error {
+	h.payloads = append(h.payloads, string(payload))
+	if h.fail != nil {
+		return h.fail(len(h.payloads))
+	}
+	return nil
+}
+
+func newTestService(t *testing.T, conf QueueConf) (*Service, *queue, *testHandler) {
+	s := NewService(context.Background(), kvdbtest.New(), "app", Conf{Queues: map[string]QueueConf{"q": conf}})
+	h := &testHandler{}
+	s.Handle("q", h.handle)
+	return s, s.queues["q"], h
+}
+
+// step delivers the next ready message as a worker would. Reports whether there was one
+func step(t *testing.T, s *Service, q *queue) bool {
+	t.Helper()
+	id, found, err := s.db.Pop(context.Background(), q.readyKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if found {
+		s.deliver(context.Background(), q, id)
+	}
+	return found
+}
+
+func readyLen(t *testing.T, s *Service, q *queue) int64 {
+	t.Helper()
+	n, err := s.db.Len(context.Background(), q.readyKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return n
+}
+
+func storedMessage(t *testing.T, s *Service, q *queue, id string) (message, bool) {
+	t.Helper()
+	var msg message
+	raw, found, err := s.db.GetField(context.Background(), q.messagesKey, id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if found {
+		if err = json.Unmarshal([]byte(raw), &msg); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return msg, found
+}
+
+func TestDeliverAndAck(t *testing.T) {
+	ctx := context.Background()
+	s, q, h := newTestService(t, QueueConf{})
+	id, err := s.Enqueue(ctx, "q", map[string]int{"n": 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !step(t, s, q) || len(h.payloads) != 1 || h.payloads[0] != `{"n":1}` {
+		t.Fatalf("payloads %v", h.payloads)
+	}
+	if _, found := storedMessage(t, s, q, id); found {
+		t.Fatal("acked message kept")
+	}
+	if _, ok := s.inflightUntil(ctx, q, id); ok {
+		t.Fatal("acked message left in flight")
+	}
+	if _, err = s.Enqueue(ctx, "missing", 1); !errors.Is(err, ErrUnknownQueue) {
+		t.Fatalf("unknown queue: %v", err)
+	}
+}
+
+func TestRedeliveryAfterVisibilityTimeout(t *testing.T) {
+	ctx := context.Background()
+	s, q, h := newTestService(t, QueueConf{VisibilityTimeout: 30})
+	id, err := s.Enqueue(ctx, "q", "job")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// a worker pops the message, marks it in flight and dies
+	popped, _, _ := s.db.Pop(ctx, q.readyKey)
+	if err = s.setInflight(ctx, q, popped, time.Now().Add(q.conf.visibility())); err != nil {
+		t.Fatal(err)
+	}
+	// a duplicate in the ready list is skipped while the message is in flight
+	_ = s.push(ctx, q, id)
+	if step(t, s, q); len(h.payloads) != 0 {
+		t.Fatal("in-flight message delivered twice")
+	}
+
+	if err = s.redeliver(ctx, q, time.Now()); err != nil || readyLen(t, s, q) != 0 {
+		t.Fatalf("re-delivered before the visibility timeout: %v", err)
+	}
+	if err = s.redeliver(ctx, q, time.Now().Add(31*time.Second)); err != nil || readyLen(t, s, q) != 1 {
+		t.Fatalf("not re-delivered after the visibility timeout: %v", err)
+	}
+	if !step(t, s, q) || len(h.payloads) != 1 {
+		t.Fatalf("payloads %v", h.payloads)
+	}
+	if _, found := storedMessage(t, s, q, id); found {
+		t.Fatal("re-delivered message not acked")
+	}
+}
+
+func TestRetryBackoff(t *testing.T) {
+	ctx := context.Background()
+	s, q, h := newTestService(t, QueueConf{RetryDelay: 10, MaxAttempts: 5})
+	h.fail = func(int) error { return errors.New("temporary") }
+	id, err := s.Enqueue(ctx, "q", "job")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for attempt, want := range []time.Duration{10 * time.Second, 20 * time.Second, 40 * time.Second} {
+		start := time.Now()
+		if !step(t, s, q) {
+			t.Fatalf("attempt %d: nothing delivered", attempt+1)
+		}
+		// stored to the millisecond
+		until, ok := s.inflightUntil(ctx, q, id)
+		if delay := until.Sub(start); !ok || delay < want-time.Millisecond || delay > want+time.Second {
+			t.Fatalf("attempt %d: retry in %s, want %s", attempt+1, delay, want)
+		}
+		msg, _ := storedMessage(t, s, q, id)
+		if msg.Attempts != attempt+1 || msg.LastError != "temporary" {
+			t.Fatalf("attempt %d: message %+v", attempt+1, msg)
+		}
+		if err = s.redeliver(ctx, q, until); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	conf := QueueConf{RetryDelay: 600}
+	for attempts, want := range map[int]time.Duration{1: 10 * time.Minute, 3: 40 * time.Minute, 4: time.Hour, 50: time.Hour} {
+		if got := conf.retryDelay(attempts); got != want {
+			t.Errorf("retryDelay(%d) = %s, want %s", attempts, got, want)
+		}
+	}
+}
+
+func TestDeadLetterAndRequeue(t *testing.T) {
+	ctx := context.Background()
+	s, q, h := newTestService(t, QueueConf{MaxAttempts: 2})
+	h.fail = func(int) error { return errors.New("broken") }
+	id, err := s.Enqueue(ctx, "q", "job")
+	if err != nil {
+		t.Fatal(err)
+	}
+	step(t, s, q)
+	until, _ := s.inflightUntil(ctx, q, id)
+	if err = s.redeliver(ctx, q, until); err != nil {
+		t.Fatal(err)
+	}
+	step(t, s, q)
+
+	letters, err := s.DeadLetters(ctx, "q")
+	if err != nil || len(letters) != 1 {
+		t.Fatalf("dead letters %v, %v", letters, err)
+	}
+	if l := letters[0]; l.ID != id || l.Attempts != 2 || l.Error != "broken" || string(l.Payload) != `"job"` {
+		t.Fatalf("dead letter %+v", l)
+	}
+	if _, found := storedMessage(t, s, q, id); found {
+		t.Fatal("dead-lettered message kept")
+	}
+	if _, ok := s.inflightUntil(ctx, q, id); ok {
+		t.Fatal("dead-lettered message left in flight")
+	}
+
+	h.fail = nil
+	if n, err := s.RequeueDeadLetters(ctx, "q"); err != nil || n != 1 {
+		t.Fatalf("RequeueDeadLetters = %d, %v", n, err)
+	}
+	if letters, _ = s.DeadLetters(ctx, "q"); len(letters) != 0 {
+		t.Fatalf("dead letters left %v", letters)
+	}
+	msg, found := storedMessage(t, s, q, id)
+	if !found || msg.Attempts != 0 {
+		t.Fatalf("requeued message %+v, %v", msg, found)
+	}
+	if !step(t, s, q) || len(h.payloads) != 3 {
+		t.Fatalf("payloads %v", h.payloads)
+	}
+	if _, found = storedMessage(t, s, q, id); found {
+		t.Fatal("requeued message not acked")
+	}
+}
+
+func TestDeadLetterPermanentAndTrim(t *testing.T) {
+	ctx := context.Background()
+	s, q, h := newTestService(t, QueueConf{MaxDeadLetters: 2})
+	h.fail = func(attempt int) error { return fmt.Errorf("%w: message %d", ErrPermanent, attempt) }
+	for i := range 3 {
+		if _, err := s.Enqueue(ctx, "q", i); err != nil {
+			t.Fatal(err)
+		}
+		step(t, s, q) // dead-lettered on the first attempt
+	}
+	letters, err := s.DeadLetters(ctx, "q")
+	if err != nil || len(letters) != 2 {
+		t.Fatalf("dead letters %v, %v", letters, err)
+	}
+	if string(letters[0].Payload) != "1" || string(letters[1].Payload) != "2" || letters[1].Attempts != 1 {
+		t.Fatalf("kept %+v", letters)
+	}
+}
+
+func TestDelayedMessageSwept(t *testing.T) {
+	ctx := context.Background()
+	s, q, _ := newTestService(t, QueueConf{VisibilityTimeout: 30})
+	id, err := s.EnqueueAfter(ctx, "q", "later", time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if readyLen(t, s, q) != 0 {
+		t.Fatal("delayed message ready right away")
+	}
+	if err = s.sweep(ctx, q, time.Now().Add(time.Hour)); err != nil || readyLen(t, s, q) != 0 {
+		t.Fatalf("swept within the grace period: %v", err)
+	}
+	if err = s.sweep(ctx, q, time.Now().Add(time.Hour+31*time.Second)); err != nil || readyLen(t, s, q) != 1 {
+		t.Fatalf("lost delayed message not swept: %v", err)
+	}
+	if ready, _ := s.db.Range(ctx, q.readyKey, 0, -1); ready[0] != id {
+		t.Fatalf("ready %v", ready)
+	}
+}
@@ -0,0 +1,238 @@
+package schedjobs
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"time"
+)
+
+// uds.CommandHandlers managing the jobs of a live Scheduler (group "Jobs").
+// Register them all with uds.NewCommandStore(&schedjobs.ListCommand{Scheduler: s}, &schedjobs.HistoryCommand{Scheduler: s}, ...)
+
+func formatTime(t time.Time) string {
+	if t.IsZero() {
+		return "-"
+	}
+	return t.Format(time.DateTime)
+}
+
+// jobIDArg returns the job ID argument of a command
+func jobIDArg(args []string, usage string) (string, error) {
+	if len(args) < 1 {
+		return "", errors.New("usage: " + usage)
+	}
+	return args[0], nil
+}
+
+// ListCommand lists the scheduled jobs with their next run and run stats
+type ListCommand struct {
+	Scheduler *Scheduler
+}
+
+func (c *ListCommand) Command() string {
+	return "jobs-list"
+}
+
+func (c *ListCommand) GroupName() string {
+	return "Jobs"
+}
+
+func (c *ListCommand) Desc() string {
+	return "list scheduled jobs"
+}
+
+func (c *ListCommand) Usage() string {
+	return "jobs-list"
+}
+
+func (c *ListCommand) HandleCommand(args []string, w io.Writer) error {
+	_, _ = fmt.Fprintf(w, "%-32s %-8s %-6s %-19s %-19s %6s %6s\n", "ID", "KIND", "STATE", "NEXT RUN", "LAST RUN", "RUNS", "FAILED")
+	for _, job := range c.Scheduler.Jobs() {
+		state := "active"
+		if job.Paused {
+			state = "paused"
+		}
+		var lastRun time.Time
+		if job.Stats.LastRun != nil {
+			lastRun = job.Stats.LastRun.Start
+		}
+		_, _ = fmt.Fprintf(w, "%-32s %-8s %-6s %-19s %-19s %6d %6d\n",
+			job.ID, job.Kind, state, formatTime(job.NextRun), formatTime(lastRun), job.Stats.Runs, job.Stats.Failures)
+	}
+	return nil
+}
+
+// HistoryCommand prints the recent runs of a job
+type HistoryCommand struct {
+	Scheduler *Scheduler
+}
+
+func (c *HistoryCommand) Command() string {
+	return "jobs-history"
+}
+
+func (c *HistoryCommand) GroupName() string {
+	return "Jobs"
+}
+
+func (c *HistoryCommand) Desc() string {
+	return "show the recent runs of a job"
+}
+
+func (c *HistoryCommand) Usage() string {
+	return "jobs-history <id>"
+}
+
+func (c *HistoryCommand) HandleCommand(args []string, w io.Writer) error {
+	jobID, err := jobIDArg(args, c.Usage())
+	if err != nil {
+		return err
+	}
+	records := c.Scheduler.History(jobID)
+	if len(records) == 0 {
+		_, _ = fmt.Fprintln(w, "(no runs recorded)")
+		return nil
+	}
+	for _, rec := range records {
+		result := "ok"
+		if rec.Err != nil {
+			result = "error: " + rec.Err.Error()
+		}
+		_, _ = fmt.Fprintf(w, "%s  %10s  next %-19s  %s\n",
+			formatTime(rec.Start), rec.Duration.Round(time.Millisecond), formatTime(rec.NextRun), result)
+	}
+	return nil
+}
+
+// RunNowCommand runs a job right away (Scheduler.RunNow)
+type RunNowCommand struct {
+	Scheduler *Scheduler
+}
+
+func (c *RunNowCommand) Command() string {
+	return "jobs-run-now"
+}
+
+func (c *RunNowCommand) GroupName() string {
+	return "Jobs"
+}
+
+func (c *RunNowCommand) Desc() string {
+	return "run a job now, outside its schedule"
+}
+
+func (c *RunNowCommand) Usage() string {
+	return "jobs-run-now <id>"
+}
+
+func (c *RunNowCommand) HandleCommand(args []string, w io.Writer) error {
+	jobID, err := jobIDArg(args, c.Usage())
+	if err != nil {
+		return err
+	}
+	if err = c.Scheduler.RunNow(jobID); err != nil {
+		return err
+	}
+	_, _ = fmt.Fprintf(w, "job %s started. see jobs-history %s\n", jobID, jobID)
+	return nil
+}
+
+// PauseCommand pauses a job (Scheduler.Pause)
+type PauseCommand struct {
+	Scheduler *Scheduler
+}
+
+func (c *PauseCommand) Command() string {
+	return "jobs-pause"
+}
+
+func (c *PauseCommand) GroupName() string {
+	return "Jobs"
+}
+
+func (c *PauseCommand) Desc() string {
+	return "pause a job until jobs-resume"
+}
+
+func (c *PauseCommand) Usage() string {
+	return "jobs-pause <id>"
+}
+
+func (c *PauseCommand) HandleCommand(args []string, w io.Writer) error {
+	jobID, err := jobIDArg(args, c.Usage())
+	if err != nil {
+		return err
+	}
+	if err = c.Scheduler.Pause(jobID); err != nil {
+		return err
+	}
+	_, _ = fmt.Fprintf(w, "job %s paused\n", jobID)
+	return nil
+}
+
+// ResumeCommand resumes a paused job (Scheduler.Resume)
+type ResumeCommand struct {
+	Scheduler *Scheduler
+}
+
+func (c *ResumeCommand) Command() string {
+	return "jobs-resume"
+}
+
+func (c *ResumeCommand) GroupName() string {
+	return "Jobs"
+}
+
+func (c *ResumeCommand) Desc() string {
+	return "resume a paused job"
+}
+
+func (c *ResumeCommand) Usage() string {
+	return "jobs-resume <id>"
+}
+
+func (c *ResumeCommand) HandleCommand(args []string, w io.Writer) error {
+	jobID, err := jobIDArg(args, c.Usage())
+	if err != nil {
+		return err
+	}
+	if err = c.Scheduler.Resume(jobID); err != nil {
+		return err
+	}
+	_, _ = fmt.Fprintf(w, "job %s resumed\n", jobID)
+	return nil
+}
+
+// DeleteCommand deletes a job of any kind (Scheduler.Delete)
+type DeleteCommand struct {
+	Scheduler *Scheduler
+}
+
+func (c *DeleteCommand) Command() string {
+	return "jobs-delete"
+}
+
+func (c *DeleteCommand) GroupName() string {
+	return "Jobs"
+}
+
+func (c *DeleteCommand) Desc() string {
+	return "delete a job"
+}
+
+func (c *DeleteCommand) Usage() string {
+	return "jobs-delete <id>"
+}
+
+func (c *DeleteCommand) HandleCommand(args []string, w io.Writer) error {
+	jobID, err := jobIDArg(args, c.Usage())
+	if err != nil {
+		return err
+	}
+	if err = c.Scheduler.Delete(jobID); err != nil {
+		return err
+	}
+	_, _ = fmt.Fprintf(w, "job %s deleted\n", jobID)
+	return nil
+}
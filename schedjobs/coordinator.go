@@ -0,0 +1,177 @@
+package schedjobs
+
+import (
+	"context"
+	"crypto/rand"
+	"log"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"github.com/x64c/gw/kvdbs"
+)
+
+// Coordinator makes a Scheduler cluster-safe: among the instances running the same jobs,
+// only the one it allows executes a job occurrence.
+type Coordinator interface {
+	// Start runs background work (e.g. an election) until ctx is done. Called by Scheduler.Start
+	Start(ctx context.Context)
+	// ShouldRun reports whether this instance runs the occurrence of the cron or interval job scheduled at the time (to the second)
+	ShouldRun(ctx context.Context, jobID string, scheduled time.Time) bool
+	// ShouldRunOnce reports whether this instance runs the one-time job due at the time (to the second).
+	// A one-time job may be scheduled on this instance only, so it must be refused only if another instance runs it.
+	ShouldRunOnce(ctx context.Context, jobID string, scheduled time.Time) bool
+}
+
+type Coordination string
+
+const (
+	CoordinationNone    Coordination = "none"     // every instance runs every job
+	CoordinationLeader  Coordination = "leader"   // LeaderCoordinator
+	CoordinationRunLock Coordination = "run_lock" // RunLockCoordinator
+)
+
+// Conf is the scheduler config (.jobs.json).
+// Instances sharing a JobStore need a coordination other than none, or they all run every stored job.
+type Conf struct {
+	Coordination Coordination `json:"coordination"`
+	LeaseTTL     int          `json:"lease_ttl"`    // seconds. leader lease, or per-run lock TTL. default 30 (leader), 300 (run_lock)
+	HistorySize  int          `json:"history_size"` // run records kept per job. default 20
+}
+
+// KeySchedulerLeader - instance token of the scheduler leader (LeaderCoordinator)
+var KeySchedulerLeader = &kvdbs.KeyFamily{
+	Name:      "schedjobs_leader",
+	Pattern:   "schedjobs:leader",
+	ValueType: kvdbs.ValueString,
+	TTLPolicy: kvdbs.TTLLease,
+	Desc:      "scheduler leader instance token",
+}
+
+// KeyJobRunLock - per-occurrence run lock (RunLockCoordinator, and one-time jobs under LeaderCoordinator). id = "<job ID>@<scheduled unix second>"
+var KeyJobRunLock = &kvdbs.KeyFamily{
+	Name:      "schedjobs_run_lock",
+	Pattern:   "schedjobs:run_lock:{id}",
+	ValueType: kvdbs.ValueString,
+	TTLPolicy: kvdbs.TTLFixed,
+	Desc:      "job occurrence run lock by \"<job ID>@<scheduled unix second>\"",
+}
+
+// LeaderCoordinator elects one scheduler instance as the leader through a KVDB lease. Only the leader runs cron
+// and interval jobs. One-time jobs run on the instance locking them first (as with RunLockCoordinator), leader or not.
+// The leader renews the lease every third of its TTL. If it dies, another instance takes over within the TTL.
+type LeaderCoordinator struct {
+	db       kvdbs.ConditionalDB
+	appName  string
+	key      string
+	token    string
+	ttl      time.Duration
+	isLeader atomic.Bool
+}
+
+func NewLeaderCoordinator(db kvdbs.DB, appName string, ttl time.Duration) (*LeaderCoordinator, error) {
+	condDB, ok := db.(kvdbs.ConditionalDB)
+	if !ok {
+		return nil, kvdbs.ErrNotSupported
+	}
+	return &LeaderCoordinator{
+		db:      condDB,
+		appName: appName,
+		key:     KeySchedulerLeader.Key(appName, ""),
+		token:   rand.Text(),
+		ttl:     ttl,
+	}, nil
+}
+
+func (c *LeaderCoordinator) Start(ctx context.Context) {
+	c.campaign(ctx)
+	go func() {
+		ticker := time.NewTicker(c.ttl / 3)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				if c.isLeader.Load() {
+					// hand over right away instead of letting the lease run out
+					_, _ = c.db.DeleteIfEquals(context.WithoutCancel(ctx), c.key, c.token)
+					c.isLeader.Store(false)
+				}
+				return
+			case <-ticker.C:
+				c.campaign(ctx)
+			}
+		}
+	}()
+}
+
+// campaign renews the lease if leading, or tries to take it otherwise
+func (c *LeaderCoordinator) campaign(ctx context.Context) {
+	wasLeader := c.isLeader.Load()
+	var ok bool
+	var err error
+	if wasLeader {
+		ok, err = c.db.ExpireIfEquals(ctx, c.key, c.token, c.ttl)
+	}
+	if !ok && err == nil {
+		ok, err = c.db.SetIfAbsent(ctx, c.key, c.token, c.ttl)
+	}
+	if err != nil {
+		// cannot tell. step down so two leaders never overlap beyond the TTL
+		log.Printf("[WARN][JobScheduler] leader election: %v", err)
+		ok = false
+	}
+	c.isLeader.Store(ok)
+	if ok != wasLeader {
+		log.Printf("[INFO][JobScheduler] leader: %v", ok)
+	}
+}
+
+func (c *LeaderCoordinator) IsLeader() bool {
+	return c.isLeader.Load()
+}
+
+func (c *LeaderCoordinator) ShouldRun(_ context.Context, _ string, _ time.Time) bool {
+	return c.isLeader.Load()
+}
+
+func (c *LeaderCoordinator) ShouldRunOnce(ctx context.Context, jobID string, scheduled time.Time) bool {
+	return lockRun(ctx, c.db, c.appName, c.token, c.ttl, jobID, scheduled)
+}
+
+// RunLockCoordinator lets the first instance locking a job occurrence run it. No election, no background work.
+// The lock expires after its TTL, which must outlast the clock skew between instances.
+type RunLockCoordinator struct {
+	db      kvdbs.ConditionalDB
+	appName string
+	ttl     time.Duration
+	token   string
+}
+
+func NewRunLockCoordinator(db kvdbs.DB, appName string, ttl time.Duration) (*RunLockCoordinator, error) {
+	condDB, ok := db.(kvdbs.ConditionalDB)
+	if !ok {
+		return nil, kvdbs.ErrNotSupported
+	}
+	return &RunLockCoordinator{db: condDB, appName: appName, ttl: ttl, token: rand.Text()}, nil
+}
+
+func (c *RunLockCoordinator) Start(_ context.Context) {}
+
+func (c *RunLockCoordinator) ShouldRun(ctx context.Context, jobID string, scheduled time.Time) bool {
+	return lockRun(ctx, c.db, c.appName, c.token, c.ttl, jobID, scheduled)
+}
+
+func (c *RunLockCoordinator) ShouldRunOnce(ctx context.Context, jobID string, scheduled time.Time) bool {
+	return lockRun(ctx, c.db, c.appName, c.token, c.ttl, jobID, scheduled)
+}
+
+// lockRun takes the run lock of a job occurrence. false if another instance holds it, or on a KVDB error
+func lockRun(ctx context.Context, db kvdbs.ConditionalDB, appName string, token string, ttl time.Duration, jobID string, scheduled time.Time) bool {
+	id := jobID + "@" + strconv.FormatInt(scheduled.Unix(), 10)
+	ok, err := db.SetIfAbsent(ctx, KeyJobRunLock.Key(appName, id), token, ttl)
+	if err != nil {
+		log.Printf("[WARN][JobScheduler] run lock %s: %v", id, err)
+		return false
+	}
+	return ok
+}
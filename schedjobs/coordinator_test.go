@@ -0,0 +1,135 @@
+package schedjobs
+
+import (
+	"context"
+	"encoding/json/jsontext"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/x64c/gw/kvdbs/kvdbtest"
+)
+
+// newTestLeaders starts two LeaderCoordinators on one KVDB. The first one started leads
+func newTestLeaders(t *testing.T) (*kvdbtest.DB, *LeaderCoordinator, *LeaderCoordinator) {
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	db := kvdbtest.New()
+	leader, err := NewLeaderCoordinator(db, "app", time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	follower, err := NewLeaderCoordinator(db, "app", time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	leader.Start(ctx)
+	follower.Start(ctx)
+	if !leader.IsLeader() || follower.IsLeader() {
+		t.Fatalf("leader %v, follower %v", leader.IsLeader(), follower.IsLeader())
+	}
+	return db, leader, follower
+}
+
+func TestLeaderCoordinator(t *testing.T) {
+	ctx := context.Background()
+	_, leader, follower := newTestLeaders(t)
+	at := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	if !leader.ShouldRun(ctx, "cron", at) || follower.ShouldRun(ctx, "cron", at) {
+		t.Fatal("recurring jobs not gated by leadership")
+	}
+	// one-time jobs go to the first instance locking them, leader or not
+	if !follower.ShouldRunOnce(ctx, "once", at) {
+		t.Fatal("one-time job refused on a follower")
+	}
+	if leader.ShouldRunOnce(ctx, "once", at) {
+		t.Fatal("one-time job occurrence run twice")
+	}
+	if !leader.ShouldRunOnce(ctx, "once", at.Add(time.Second)) {
+		t.Fatal("another occurrence refused")
+	}
+}
+
+func TestRunLockCoordinator(t *testing.T) {
+	ctx := context.Background()
+	db := kvdbtest.New()
+	a, _ := NewRunLockCoordinator(db, "app", time.Minute)
+	b, _ := NewRunLockCoordinator(db, "app", time.Minute)
+	at := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	if !a.ShouldRun(ctx, "job", at) || b.ShouldRun(ctx, "job", at) {
+		t.Fatal("occurrence not locked to one instance")
+	}
+	if !b.ShouldRun(ctx, "job", at.Add(time.Minute)) {
+		t.Fatal("next occurrence refused")
+	}
+}
+
+func TestFollowerRunsLocalOneTimeJob(t *testing.T) {
+	_, _, follower := newTestLeaders(t)
+	s := NewScheduler(context.Background())
+	s.UseCoordinator(follower)
+	if err := s.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer s.Stop()
+	ran := make(chan struct{})
+	err := s.AddOneTimeJob(&OneTimeJob{
+		ID:       "local",
+		ExecTime: time.Now().Add(20 * time.Millisecond),
+		Task: func() error {
+			close(ran)
+			return nil
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-ran:
+	case <-time.After(2 * time.Second):
+		t.Fatal("one-time job dropped on a follower")
+	}
+}
+
+func TestSharedJobStoreRunsOnce(t *testing.T) {
+	db := kvdbtest.New()
+	store := NewKVDBJobStore(db, "app")
+	var runs atomic.Int32
+	task := func(context.Context, jsontext.Value) error {
+		runs.Add(1)
+		return nil
+	}
+	// stored before the instances start: every instance rehydrates it
+	err := store.Save(context.Background(), StoredJob{ID: "stored", ExecTime: time.Now().Add(100 * time.Millisecond), TaskName: "count"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var schedulers []*Scheduler
+	for range 2 {
+		coordinator, err := NewLeaderCoordinator(db, "app", time.Minute)
+		if err != nil {
+			t.Fatal(err)
+		}
+		s := NewScheduler(context.Background())
+		s.UseCoordinator(coordinator)
+		s.UseJobStore(store, CatchUpConf{})
+		s.RegisterTask("count", task)
+		if err = s.Start(); err != nil {
+			t.Fatal(err)
+		}
+		defer s.Stop()
+		schedulers = append(schedulers, s)
+	}
+	// added on the follower
+	if err = schedulers[1].AddPersistentOneTimeJob("added", time.Now().Add(100*time.Millisecond), "count", nil); err != nil {
+		t.Fatal(err)
+	}
+	deadline := time.Now().Add(2 * time.Second)
+	for runs.Load() < 2 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	time.Sleep(100 * time.Millisecond) // a duplicate run would land by now
+	if got := runs.Load(); got != 2 {
+		t.Fatalf("2 jobs ran %d times", got)
+	}
+}
@@ -1,15 +1,34 @@
 package schedjobs
 
+import (
+	"context"
+	"time"
+)
+
 type CronJob struct {
 	ID          string
 	Minutes     uint64 // 60 bits
 	Hours       uint32 // 24 bits
 	DaysOfMonth uint32 // 31 bits
 	Weekdays    uint8  // 7 bits
-	Task        func() error
+	Months      uint16 // 12 bits. 0 = every month (jobs built before the month field)
+	// DayOrWeekday follows the standard cron rule: if both DaysOfMonth and Weekdays are restricted,
+	// a day matches when either matches. Set by SetSchedule. false = both must match
+	DayOrWeekday bool
+	Location     *time.Location // time zone the fields are in. nil = time.Local
+	Jitter       time.Duration  // random delay in [0, Jitter) added to each occurrence
+	Task         func() error
+	TaskCtx      func(ctx context.Context) error // context-aware task. Used instead of Task if set
+	// Execution controls
+	MaxRunDuration time.Duration // deadline of TaskCtx's ctx per attempt. 0 = none. Task (without ctx) cannot be interrupted
+	Retries        int           // extra attempts after a failure
+	RetryBackoff   time.Duration // delay before the first retry, doubled each time. default 1s
+	Overlap        OverlapPolicy // when the previous run is still going. default OverlapAllow
 	// Job-specific callbacks
 	OnAdded    func()
-	OnFinished func(error)
+	OnFinished func(error) // err is a *PanicError if the task panicked
+
+	overlap overlapState
 }
 
 // NewEveryMinEmptyCronJob provides a cronjob matching every minute without a task as a template
@@ -21,6 +40,7 @@ func NewEveryMinEmptyCronJob(jobID string) *CronJob {
 		Hours:       AllHours,
 		DaysOfMonth: AllDaysOfMonth,
 		Weekdays:    AllWeekdays,
+		Months:      AllMonths,
 	}
 }
 
@@ -29,6 +49,7 @@ const (
 	AllHours       uint32 = 0xFFFFFF          // 24 bits set
 	AllWeekdays    uint8  = 0b01111111        // sun:0b00000001, mon:0b00000010, ..., fri:0b00100000, sat:0b01000000
 	AllDaysOfMonth uint32 = 0x7FFFFFFF        // 31 bits set
+	AllMonths      uint16 = 0xFFF             // 12 bits set. jan:bit 0, ..., dec:bit 11
 )
 
 func BitsFromMinutes(list []int) uint64 {
@@ -70,3 +91,67 @@ func BitsFromDaysOfMonth(list []int) uint32 {
 	}
 	return bits
 }
+
+func BitsFromMonths(list []int) uint16 {
+	var bits uint16
+	for _, v := range list {
+		if v >= 1 && v <= 12 { // month 1 = bit 0
+			bits |= 1 << (v - 1)
+		}
+	}
+	return bits
+}
+
+// in returns t in the job's time zone
+func (job *CronJob) in(t time.Time) time.Time {
+	if job.Location != nil {
+		return t.In(job.Location)
+	}
+	return t.Local()
+}
+
+func (job *CronJob) monthMatches(t time.Time) bool {
+	return job.Months == 0 || job.Months&(1<<(t.Month()-1)) != 0
+}
+
+func (job *CronJob) dayMatches(t time.Time) bool {
+	dom := job.DaysOfMonth&(1<<(t.Day()-1)) != 0 // t.Day() = 1..31 -> bit 0 = day 1
+	dow := job.Weekdays&(1<<t.Weekday()) != 0
+	if job.DayOrWeekday && job.DaysOfMonth != AllDaysOfMonth && job.Weekdays != AllWeekdays {
+		return dom || dow
+	}
+	return dom && dow
+}
+
+// maxNextRunSearch bounds NextRun for schedules that never match (e.g. Feb 30)
+const maxNextRunSearch = 5 * 366 * 24 * time.Hour
+
+// NextRun returns the first matching minute strictly after after, in the job's time zone.
+// Zero time if nothing matches within 5 years.
+// Wall-clock minutes skipped by a DST change are never matched, and repeated ones match twice, as with Matches.
+func (job *CronJob) NextRun(after time.Time) time.Time {
+	t := job.in(after)
+	t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute()+1, 0, 0, t.Location())
+	limit := t.Add(maxNextRunSearch)
+	for t.Before(limit) {
+		var next time.Time
+		switch {
+		case !job.monthMatches(t):
+			next = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, t.Location())
+		case !job.dayMatches(t):
+			next = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, t.Location())
+		case job.Hours&(1<<t.Hour()) == 0:
+			next = t.Add(time.Duration(60-t.Minute()) * time.Minute)
+		case job.Minutes&(1<<t.Minute()) == 0:
+			next = t.Add(time.Minute)
+		default:
+			return t
+		}
+		// time.Date may resolve a midnight in a DST gap to the hour before. keep moving forward
+		if !next.After(t) {
+			next = t.Add(time.Hour)
+		}
+		t = next
+	}
+	return time.Time{}
+}
@@ -8,9 +8,10 @@ import (
 )
 
 func (job *CronJob) Matches(now time.Time) bool {
+	now = job.in(now)
 	log.Printf("[DEBUG] Checking match for %s at %v", job.ID, now)
-	log.Printf("[DEBUG] Cron spec: Minutes=%v Hours=%v DaysOfMonth=%v Weekdays=%v",
-		job.Minutes, job.Hours, job.DaysOfMonth, job.Weekdays,
+	log.Printf("[DEBUG] Cron spec: Minutes=%v Hours=%v DaysOfMonth=%v Weekdays=%v Months=%v DayOrWeekday=%v",
+		job.Minutes, job.Hours, job.DaysOfMonth, job.Weekdays, job.Months, job.DayOrWeekday,
 	)
 	if (job.Minutes & (1 << now.Minute())) == 0 {
 		log.Println("[DEBUG] Minute mismatch")
@@ -20,12 +21,12 @@ func (job *CronJob) Matches(now time.Time) bool {
 		log.Println("[DEBUG] Hour mismatch")
 		return false
 	}
-	if (job.DaysOfMonth & (1 << (now.Day() - 1))) == 0 { // now.Day() = 1..31 -> bit 0 = day 1
-		log.Println("[DEBUG] Days of month mismatch")
+	if !job.dayMatches(now) {
+		log.Println("[DEBUG] Days of month / weekday mismatch")
 		return false
 	}
-	if (job.Weekdays & (1 << now.Weekday())) == 0 {
-		log.Println("[DEBUG] weekday mismatch")
+	if !job.monthMatches(now) {
+		log.Println("[DEBUG] Month mismatch")
 		return false
 	}
 	log.Println("[DEBUG] All fields match")
@@ -5,16 +5,17 @@ package schedjobs
 import "time"
 
 func (job *CronJob) Matches(now time.Time) bool {
+	now = job.in(now)
 	if (job.Minutes & (1 << now.Minute())) == 0 {
 		return false
 	}
 	if (job.Hours & (1 << now.Hour())) == 0 {
 		return false
 	}
-	if (job.DaysOfMonth & (1 << (now.Day() - 1))) == 0 { // now.Day() = 1..31 -> bit 0 = day 1
+	if !job.dayMatches(now) {
 		return false
 	}
-	if (job.Weekdays & (1 << now.Weekday())) == 0 {
+	if !job.monthMatches(now) {
 		return false
 	}
 	return true
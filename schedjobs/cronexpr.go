@@ -0,0 +1,162 @@
+package schedjobs
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Standard 5-field cron expressions: minute hour day-of-month month day-of-week
+//
+//	field         values   names
+//	minute        0-59
+//	hour          0-23
+//	day of month  1-31
+//	month         1-12     JAN-DEC
+//	day of week   0-7      SUN-SAT (0 and 7 are Sunday)
+//
+// Each field is "*" or a comma-separated list of values, ranges "a-b" and steps "*/n", "a-b/n", "a/n".
+// Macros: @yearly (@annually), @monthly, @weekly, @daily (@midnight), @hourly
+
+var cronMacros = map[string]string{
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+	"@monthly":  "0 0 1 * *",
+	"@weekly":   "0 0 * * 0",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@hourly":   "0 * * * *",
+}
+
+var (
+	monthNames   = []string{"JAN", "FEB", "MAR", "APR", "MAY", "JUN", "JUL", "AUG", "SEP", "OCT", "NOV", "DEC"}
+	weekdayNames = []string{"SUN", "MON", "TUE", "WED", "THU", "FRI", "SAT"}
+)
+
+type cronField struct {
+	name  string
+	min   int
+	max   int
+	names []string // names[i] = min+i
+}
+
+var cronFields = [5]cronField{
+	{name: "minute", min: 0, max: 59},
+	{name: "hour", min: 0, max: 23},
+	{name: "day of month", min: 1, max: 31},
+	{name: "month", min: 1, max: 12, names: monthNames},
+	{name: "day of week", min: 0, max: 7, names: weekdayNames},
+}
+
+// NewCronJob creates a cron job without a task scheduled by a cron expression in loc (nil = time.Local)
+// e.g. NewCronJob("daily-report", "0 9 * * MON-FRI", seoul)
+func NewCronJob(jobID string, expr string, loc *time.Location) (*CronJob, error) {
+	job := &CronJob{ID: jobID, Location: loc}
+	if err := job.SetSchedule(expr); err != nil {
+		return nil, err
+	}
+	return job, nil
+}
+
+// SetSchedule replaces the time condition of the job with a cron expression
+func (job *CronJob) SetSchedule(expr string) error {
+	fields, err := parseCronExpr(expr)
+	if err != nil {
+		return err
+	}
+	job.Minutes = fields[0]
+	job.Hours = uint32(fields[1])
+	job.DaysOfMonth = uint32(fields[2] >> 1) // day 1 = bit 0
+	job.Months = uint16(fields[3] >> 1)      // month 1 = bit 0
+	weekdays := fields[4]
+	if weekdays&(1<<7) != 0 { // 7 = Sunday
+		weekdays |= 1
+	}
+	job.Weekdays = uint8(weekdays) & AllWeekdays
+	job.DayOrWeekday = true
+	return nil
+}
+
+// parseCronExpr returns the bits of each field, bit n = value n
+func parseCronExpr(expr string) ([5]uint64, error) {
+	var bits [5]uint64
+	expr = strings.TrimSpace(expr)
+	if strings.HasPrefix(expr, "@") {
+		expanded, ok := cronMacros[strings.ToLower(expr)]
+		if !ok {
+			return bits, fmt.Errorf("cron: unknown macro %q", expr)
+		}
+		expr = expanded
+	}
+	parts := strings.Fields(expr)
+	if len(parts) != len(cronFields) {
+		return bits, fmt.Errorf("cron: expected %d fields, got %d in %q", len(cronFields), len(parts), expr)
+	}
+	for i, part := range parts {
+		b, err := cronFields[i].parse(part)
+		if err != nil {
+			return bits, err
+		}
+		bits[i] = b
+	}
+	return bits, nil
+}
+
+func (f cronField) parse(s string) (uint64, error) {
+	var bits uint64
+	for item := range strings.SplitSeq(s, ",") {
+		rangePart, stepPart, hasStep := strings.Cut(item, "/")
+		step := 1
+		if hasStep {
+			n, err := strconv.Atoi(stepPart)
+			if err != nil || n <= 0 {
+				return 0, fmt.Errorf("cron: %s: invalid step %q", f.name, item)
+			}
+			step = n
+		}
+		var lo, hi int
+		switch {
+		case rangePart == "*":
+			lo, hi = f.min, f.max
+		case strings.Contains(rangePart, "-"):
+			loPart, hiPart, _ := strings.Cut(rangePart, "-")
+			var err error
+			if lo, err = f.value(loPart); err != nil {
+				return 0, err
+			}
+			if hi, err = f.value(hiPart); err != nil {
+				return 0, err
+			}
+			if lo > hi {
+				return 0, fmt.Errorf("cron: %s: invalid range %q", f.name, item)
+			}
+		default:
+			v, err := f.value(rangePart)
+			if err != nil {
+				return 0, err
+			}
+			lo, hi = v, v
+			if hasStep { // "a/n" = from a to max
+				hi = f.max
+			}
+		}
+		for v := lo; v <= hi; v += step {
+			bits |= 1 << v
+		}
+	}
+	return bits, nil
+}
+
+func (f cronField) value(s string) (int, error) {
+	for i, name := range f.names {
+		if strings.EqualFold(s, name) {
+			return f.min + i, nil
+		}
+	}
+	v, err := strconv.Atoi(s)
+	if err != nil || v < f.min || v > f.max {
+		return 0, fmt.Errorf("cron: %s: invalid value %q", f.name, s)
+	}
+	return v, nil
+}
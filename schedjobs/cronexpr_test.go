@@ -0,0 +1,96 @@
+package schedjobs
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseCronExpr(t *testing.T) {
+	tests := []struct {
+		expr string
+		want [5]uint64
+	}{
+		{"*/15 * * * *", [5]uint64{1<<0 | 1<<15 | 1<<30 | 1<<45, 1<<24 - 1, (1<<31 - 1) << 1, (1<<12 - 1) << 1, 1<<8 - 1}},
+		{"5/20 0 1 1 0", [5]uint64{1<<5 | 1<<25 | 1<<45, 1, 1 << 1, 1 << 1, 1}},
+		{"0 9-17/4 1,15 JAN-MAR/2 MON-FRI", [5]uint64{1, 1<<9 | 1<<13 | 1<<17, 1<<1 | 1<<15, 1<<1 | 1<<3, 0b111110}},
+		{"0 0 * * 7", [5]uint64{1, 1, (1<<31 - 1) << 1, (1<<12 - 1) << 1, 1 << 7}},
+		{"@weekly", [5]uint64{1, 1, (1<<31 - 1) << 1, (1<<12 - 1) << 1, 1}},
+		{"  @Daily ", [5]uint64{1, 1, (1<<31 - 1) << 1, (1<<12 - 1) << 1, 1<<8 - 1}},
+		{"0 0 * dec sun", [5]uint64{1, 1, (1<<31 - 1) << 1, 1 << 12, 1}},
+	}
+	for _, tt := range tests {
+		got, err := parseCronExpr(tt.expr)
+		if err != nil || got != tt.want {
+			t.Errorf("%q: got %b, %v\nwant %b", tt.expr, got, err, tt.want)
+		}
+	}
+	for _, expr := range []string{
+		"", "* * * *", "* * * * * *", "@often",
+		"60 * * * *", "* 24 * * *", "* * 0 * *", "* * * 13 *", "* * * * 8",
+		"*/0 * * * *", "*/x * * * *", "5-1 * * * *", "-1 * * * *", "a * * * *", "1-2-3 * * * *", "1,,2 * * * *",
+	} {
+		if _, err := parseCronExpr(expr); err == nil {
+			t.Errorf("%q: no error", expr)
+		}
+	}
+}
+
+func TestCronSetScheduleSunday(t *testing.T) {
+	for _, expr := range []string{"0 0 * * 0", "0 0 * * 7", "0 0 * * SUN"} {
+		job, err := NewCronJob("job", expr, time.UTC)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if job.Weekdays != 1 {
+			t.Errorf("%q: weekdays %b", expr, job.Weekdays)
+		}
+	}
+}
+
+func TestCronNextRun(t *testing.T) {
+	date := func(y int, m time.Month, d, h, min int) time.Time {
+		return time.Date(y, m, d, h, min, 0, 0, time.UTC)
+	}
+	tests := []struct {
+		expr  string
+		after time.Time
+		want  time.Time
+	}{
+		{"*/15 * * * *", date(2026, 1, 1, 10, 7).Add(30 * time.Second), date(2026, 1, 1, 10, 15)},
+		{"*/15 * * * *", date(2026, 1, 1, 10, 15), date(2026, 1, 1, 10, 30)}, // strictly after
+		{"0 9 * * MON-FRI", date(2026, 1, 2, 9, 0), date(2026, 1, 5, 9, 0)},  // Friday -> Monday
+		{"0 0 13 * FRI", date(2026, 1, 1, 0, 0), date(2026, 1, 2, 0, 0)},     // day of month or weekday
+		{"30 12 29 2 *", date(2026, 3, 1, 0, 0), date(2028, 2, 29, 12, 30)},
+		{"@yearly", date(2026, 6, 1, 0, 0), date(2027, 1, 1, 0, 0)},
+		{"0 0 30 2 *", date(2026, 1, 1, 0, 0), time.Time{}}, // never
+	}
+	for _, tt := range tests {
+		job, err := NewCronJob("job", tt.expr, time.UTC)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got := job.NextRun(tt.after)
+		if !got.Equal(tt.want) {
+			t.Errorf("%q after %v: got %v, want %v", tt.expr, tt.after, got, tt.want)
+		}
+		if !got.IsZero() && !job.Matches(got) {
+			t.Errorf("%q: NextRun %v does not match", tt.expr, got)
+		}
+	}
+}
+
+func TestCronNextRunDSTGap(t *testing.T) {
+	ny, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skip(err)
+	}
+	// 2026-03-08 02:00-03:00 does not exist in New York
+	job, err := NewCronJob("job", "30 2 * * *", ny)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := job.NextRun(time.Date(2026, 3, 7, 3, 0, 0, 0, ny))
+	if want := time.Date(2026, 3, 9, 2, 30, 0, 0, ny); !got.Equal(want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
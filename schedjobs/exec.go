@@ -0,0 +1,142 @@
+package schedjobs
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"runtime/debug"
+	"sync"
+	"time"
+)
+
+type OverlapPolicy int
+
+const (
+	OverlapAllow OverlapPolicy = iota // start another run alongside the running one
+	OverlapSkip                       // drop the occurrence
+	OverlapQueue                      // run once more right after the running one. occurrences beyond one are dropped
+)
+
+const (
+	defaultRetryBackoff = time.Second
+	maxRetryBackoff     = 10 * time.Minute
+)
+
+// PanicError is the error of a task that panicked
+type PanicError struct {
+	Value any
+	Stack []byte
+}
+
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("task panicked: %v", e.Value)
+}
+
+// overlapState tracks the runs of a recurring job for its OverlapPolicy
+type overlapState struct {
+	mu      sync.Mutex
+	running int
+	queued  bool
+}
+
+// begin reports whether an occurrence starts now. A queued one is started later by finish
+func (o *overlapState) begin(policy OverlapPolicy, jobID string) bool {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if o.running > 0 {
+		switch policy {
+		case OverlapSkip:
+			log.Printf("[WARN][JobScheduler] job %s still running. occurrence skipped", jobID)
+			return false
+		case OverlapQueue:
+			o.queued = true
+			return false
+		}
+	}
+	o.running++
+	return true
+}
+
+// finish ends a run and reports whether a queued occurrence should run now (in its place)
+func (o *overlapState) finish() bool {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if o.queued {
+		o.queued = false
+		return true
+	}
+	o.running--
+	return false
+}
+
+// execute runs a task with its execution controls: a deadline per attempt, retries with exponential backoff
+// and panic recovery. Retries stop when the scheduler stops.
+func (s *Scheduler) execute(jobID string, task func() error, taskCtx func(context.Context) error, maxRunDuration time.Duration, retries int, backoff time.Duration) error {
+	if backoff <= 0 {
+		backoff = defaultRetryBackoff
+	}
+	for attempt := 0; ; attempt++ {
+		err := s.attempt(jobID, task, taskCtx, maxRunDuration)
+		if err == nil || attempt >= retries {
+			return err
+		}
+		log.Printf("[WARN][JobScheduler] job %s attempt %d failed: %v. retrying in %s", jobID, attempt+1, err, backoff)
+		select {
+		case <-s.Ctx.Done():
+			return err
+		case <-time.After(backoff):
+		}
+		backoff = min(backoff*2, maxRetryBackoff)
+	}
+}
+
+func (s *Scheduler) attempt(jobID string, task func() error, taskCtx func(context.Context) error, maxRunDuration time.Duration) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			stack := debug.Stack()
+			log.Printf("[PANIC][JobScheduler] job %s panicked: %v\n%s", jobID, r, stack)
+			err = &PanicError{Value: r, Stack: stack}
+		}
+	}()
+	if taskCtx == nil {
+		return task()
+	}
+	ctx := s.Ctx
+	if maxRunDuration > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, maxRunDuration)
+		defer cancel()
+	}
+	return taskCtx(ctx)
+}
+
+// callFinished runs a finish callback, recovering its panic so that a faulty callback cannot crash the process
+func callFinished(jobID string, callback func()) {
+	defer func() {
+		if r := recover(); r != nil {
+			log.Printf("[PANIC][JobScheduler] finish callback of job %s panicked: %v\n%s", jobID, r, debug.Stack())
+		}
+	}()
+	callback()
+}
+
+func (s *Scheduler) executeOneTimeJob(job *OneTimeJob) error {
+	start := time.Now()
+	err := s.execute(job.ID, job.Task, job.TaskCtx, job.MaxRunDuration, job.Retries, job.RetryBackoff)
+	s.recordRun(job.ID, true, start, err)
+	return err
+}
+
+func (s *Scheduler) executeCronJob(job *CronJob) error {
+	start := time.Now()
+	err := s.execute(job.ID, job.Task, job.TaskCtx, job.MaxRunDuration, job.Retries, job.RetryBackoff)
+	s.recordRun(job.ID, false, start, err)
+	return err
+}
+
+func (s *Scheduler) executeIntervalJob(job *IntervalJob) error {
+	start := time.Now()
+	err := s.execute(job.ID, job.Task, job.TaskCtx, job.MaxRunDuration, job.Retries, job.RetryBackoff)
+	s.recordRun(job.ID, false, start, err)
+	return err
+}
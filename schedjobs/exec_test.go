@@ -0,0 +1,52 @@
+package schedjobs
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestFinishCallbackPanicRecovered(t *testing.T) {
+	s := NewScheduler(context.Background())
+	if err := s.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer s.Stop()
+	finished := make(chan error, 1)
+	s.OnOneTimeJobFinished = func(_ *OneTimeJob, err error) {
+		finished <- err
+		panic("scheduler callback")
+	}
+	attempts := 0
+	err := s.AddOneTimeJob(&OneTimeJob{
+		ID:           "job",
+		ExecTime:     time.Now().Add(time.Hour),
+		Retries:      2,
+		RetryBackoff: time.Millisecond,
+		Task: func() error {
+			attempts++
+			panic("task")
+		},
+		OnFinished: func(error) { panic("job callback") },
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = s.RunNow("job"); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case err = <-finished:
+	case <-time.After(2 * time.Second):
+		t.Fatal("scheduler callback not called after the job callback panicked")
+	}
+	var panicErr *PanicError
+	if !errors.As(err, &panicErr) || panicErr.Value != "task" {
+		t.Fatalf("err = %v", err)
+	}
+	s.wg.Wait() // the process survives the scheduler callback panic
+	if attempts != 3 {
+		t.Fatalf("%d attempts, want 3", attempts)
+	}
+}
@@ -0,0 +1,152 @@
+package schedjobs
+
+import (
+	"sort"
+	"time"
+)
+
+const (
+	DefaultHistorySize = 20  // run records kept per job
+	maxOneTimeHistory  = 200 // one-time jobs whose history is kept after they ran
+)
+
+// RunRecord is a run of a job, including its retries
+type RunRecord struct {
+	Start    time.Time
+	Duration time.Duration
+	Err      error     // nil = succeeded
+	NextRun  time.Time // next scheduled occurrence when the run finished. zero = none
+}
+
+// JobStats summarizes the runs of a job since the scheduler started
+type JobStats struct {
+	Runs     int
+	Failures int
+	LastRun  *RunRecord // nil = never ran
+}
+
+// JobInfo describes a scheduled job
+type JobInfo struct {
+	ID      string
+	Kind    string    // "cron", "interval" or "one-time"
+	NextRun time.Time // zero = none (e.g. a paused one-time job that is due)
+	Paused  bool
+	Stats   JobStats
+}
+
+// runHistory is the bounded run history of a job
+type runHistory struct {
+	records  []RunRecord // oldest first
+	runs     int
+	failures int
+}
+
+// SetHistorySize sets the number of run records kept per job. Call before Start
+func (s *Scheduler) SetHistorySize(n int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.historySize = n
+}
+
+// recordRun appends a finished run to the job's history
+func (s *Scheduler) recordRun(jobID string, oneTime bool, start time.Time, err error) {
+	rec := RunRecord{Start: start, Duration: time.Since(start), Err: err}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	rec.NextRun = s.nextRunLocked(jobID)
+	if s.histories == nil {
+		s.histories = make(map[string]*runHistory)
+	}
+	h, ok := s.histories[jobID]
+	if !ok {
+		h = &runHistory{}
+		s.histories[jobID] = h
+		if oneTime {
+			// one-time jobs come and go. keep the latest ones only
+			s.oneTimeHistoryOrder = append(s.oneTimeHistoryOrder, jobID)
+			if len(s.oneTimeHistoryOrder) > maxOneTimeHistory {
+				delete(s.histories, s.oneTimeHistoryOrder[0])
+				s.oneTimeHistoryOrder = s.oneTimeHistoryOrder[1:]
+			}
+		}
+	}
+	size := s.historySize
+	if size <= 0 {
+		size = DefaultHistorySize
+	}
+	if len(h.records) >= size {
+		h.records = append(h.records[:0], h.records[len(h.records)-size+1:]...)
+	}
+	h.records = append(h.records, rec)
+	h.runs++
+	if err != nil {
+		h.failures++
+	}
+}
+
+// nextRunLocked returns the next occurrence of the job. s.mu must be held
+func (s *Scheduler) nextRunLocked(jobID string) time.Time {
+	var next time.Time
+	for _, t := range s.timers {
+		if t.jobID() == jobID && (next.IsZero() || t.at.Before(next)) {
+			next = t.at
+		}
+	}
+	return next
+}
+
+// History returns the recorded runs of a job, oldest first
+func (s *Scheduler) History(jobID string) []RunRecord {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	h, ok := s.histories[jobID]
+	if !ok {
+		return nil
+	}
+	return append([]RunRecord(nil), h.records...)
+}
+
+// statsLocked returns the run stats of a job. s.mu must be held
+func (s *Scheduler) statsLocked(jobID string) JobStats {
+	h, ok := s.histories[jobID]
+	if !ok {
+		return JobStats{}
+	}
+	stats := JobStats{Runs: h.runs, Failures: h.failures}
+	if n := len(h.records); n > 0 {
+		last := h.records[n-1]
+		stats.LastRun = &last
+	}
+	return stats
+}
+
+// Jobs returns all scheduled jobs sorted by ID
+func (s *Scheduler) Jobs() []JobInfo {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var jobs []JobInfo
+	add := func(id, kind string) {
+		jobs = append(jobs, JobInfo{
+			ID:      id,
+			Kind:    kind,
+			NextRun: s.nextRunLocked(id),
+			Paused:  s.paused[id],
+			Stats:   s.statsLocked(id),
+		})
+	}
+	for id := range s.cronJobs {
+		add(id, "cron")
+	}
+	for id := range s.intervalJobs {
+		add(id, "interval")
+	}
+	seen := make(map[string]bool)
+	for _, t := range append(s.timers[:len(s.timers):len(s.timers)], s.held...) {
+		if t.oneTime != nil && !seen[t.oneTime.ID] {
+			seen[t.oneTime.ID] = true
+			add(t.oneTime.ID, "one-time")
+		}
+	}
+	sort.Slice(jobs, func(i, j int) bool { return jobs[i].ID < jobs[j].ID })
+	return jobs
+}
@@ -0,0 +1,31 @@
+package schedjobs
+
+import (
+	"context"
+	"time"
+)
+
+// IntervalJob runs every Interval. Occurrences are aligned to multiples of Interval on the wall clock
+// (e.g. :00, :10, :20 for 10s), so every instance of a cluster schedules the same occurrences.
+type IntervalJob struct {
+	ID       string
+	Interval time.Duration
+	Jitter   time.Duration // random delay in [0, Jitter) added to each occurrence. keep it below Interval
+	Task     func() error
+	TaskCtx  func(ctx context.Context) error // context-aware task. Used instead of Task if set
+	// Execution controls
+	MaxRunDuration time.Duration // deadline of TaskCtx's ctx per attempt. 0 = none. Task (without ctx) cannot be interrupted
+	Retries        int           // extra attempts after a failure
+	RetryBackoff   time.Duration // delay before the first retry, doubled each time. default 1s
+	Overlap        OverlapPolicy // when the previous run is still going. default OverlapAllow
+	// Job-specific callbacks
+	OnAdded    func()
+	OnFinished func(error) // err is a *PanicError if the task panicked
+
+	overlap overlapState
+}
+
+// NextRun returns the first occurrence strictly after after
+func (job *IntervalJob) NextRun(after time.Time) time.Time {
+	return after.Truncate(job.Interval).Add(job.Interval)
+}
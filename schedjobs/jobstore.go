@@ -0,0 +1,31 @@
+package schedjobs
+
+import (
+	"context"
+	"encoding/json/jsontext"
+	"time"
+)
+
+// StoredJob is a persisted one-time job. The task is referenced by its registered name
+type StoredJob struct {
+	ID       string
+	ExecTime time.Time
+	TaskName string         // name in the Scheduler task registry (RegisterTask)
+	Payload  jsontext.Value // argument of the task
+}
+
+// JobStore persists one-time jobs so they survive restarts
+type JobStore interface {
+	Save(ctx context.Context, job StoredJob) error // insert or replace by ID
+	Delete(ctx context.Context, id string) error
+	LoadAll(ctx context.Context) ([]StoredJob, error)
+}
+
+// PersistentTask is a task runnable from a StoredJob
+type PersistentTask func(ctx context.Context, payload jsontext.Value) error
+
+// CatchUpConf decides what happens at Start to stored jobs whose time passed while the app was down
+type CatchUpConf struct {
+	Skip     bool          // drop missed jobs. Otherwise they run right after Start
+	MaxDelay time.Duration // if not Skip, drop jobs missed longer ago than this. 0 = no limit
+}
@@ -0,0 +1,73 @@
+package schedjobs
+
+import (
+	"context"
+	"encoding/json/jsontext"
+	"encoding/json/v2"
+	"time"
+
+	"github.com/x64c/gw/kvdbs"
+)
+
+// KeyOneTimeJobs - hash of persisted one-time jobs (KVDBJobStore). job ID -> JSON
+var KeyOneTimeJobs = &kvdbs.KeyFamily{
+	Name:      "schedjobs_onetime",
+	Pattern:   "schedjobs:onetime",
+	ValueType: kvdbs.ValueHash,
+	TTLPolicy: kvdbs.TTLNone,
+	Desc:      "persisted one-time jobs by job ID",
+}
+
+type storedJobJSON struct {
+	ExecTime int64          `json:"exec_time"` // unix millis
+	TaskName string         `json:"task_name"`
+	Payload  jsontext.Value `json:"payload,omitempty"`
+}
+
+// KVDBJobStore implements JobStore on a single KVDB hash
+type KVDBJobStore struct {
+	db  kvdbs.DB
+	key string
+}
+
+func NewKVDBJobStore(db kvdbs.DB, appName string) *KVDBJobStore {
+	return &KVDBJobStore{db: db, key: KeyOneTimeJobs.Key(appName, "")}
+}
+
+func (s *KVDBJobStore) Save(ctx context.Context, job StoredJob) error {
+	encoded, err := json.Marshal(storedJobJSON{
+		ExecTime: job.ExecTime.UnixMilli(),
+		TaskName: job.TaskName,
+		Payload:  job.Payload,
+	})
+	if err != nil {
+		return err
+	}
+	return s.db.SetField(ctx, s.key, job.ID, string(encoded))
+}
+
+func (s *KVDBJobStore) Delete(ctx context.Context, id string) error {
+	_, err := s.db.RemoveFields(ctx, s.key, id)
+	return err
+}
+
+func (s *KVDBJobStore) LoadAll(ctx context.Context) ([]StoredJob, error) {
+	fields, err := s.db.GetAllFields(ctx, s.key)
+	if err != nil {
+		return nil, err
+	}
+	jobs := make([]StoredJob, 0, len(fields))
+	for id, encoded := range fields {
+		var j storedJobJSON
+		if err = json.Unmarshal([]byte(encoded), &j); err != nil {
+			return nil, err
+		}
+		jobs = append(jobs, StoredJob{
+			ID:       id,
+			ExecTime: time.UnixMilli(j.ExecTime),
+			TaskName: j.TaskName,
+			Payload:  j.Payload,
+		})
+	}
+	return jobs, nil
+}
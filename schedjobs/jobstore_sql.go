@@ -0,0 +1,85 @@
+package schedjobs
+
+import (
+	"context"
+	"encoding/json/jsontext"
+	"time"
+
+	"github.com/x64c/gw/sqldbs"
+)
+
+// SQLJobStore implements JobStore on a SQL table
+//
+//	CREATE TABLE sched_onetime_jobs (
+//	    id        VARCHAR(255) PRIMARY KEY,
+//	    exec_time BIGINT       NOT NULL, -- unix millis
+//	    task_name VARCHAR(255) NOT NULL,
+//	    payload   TEXT         NOT NULL  -- JSON
+//	);
+type SQLJobStore struct {
+	db    sqldbs.DB
+	table string
+}
+
+var sqlJobStoreColumns = []string{"id", "exec_time", "task_name", "payload"}
+
+// NewSQLJobStore uses the table. "" = "sched_onetime_jobs"
+func NewSQLJobStore(db sqldbs.DB, table string) *SQLJobStore {
+	if table == "" {
+		table = "sched_onetime_jobs"
+	}
+	return &SQLJobStore{db: db, table: table}
+}
+
+// Save replaces the row in a transaction (delete + insert), portable across dialects
+func (s *SQLJobStore) Save(ctx context.Context, job StoredJob) (err error) {
+	tx, err := s.db.BeginTx(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback(context.WithoutCancel(ctx))
+		}
+	}()
+	if _, err = tx.DeleteRow(ctx, s.table, "id", job.ID); err != nil {
+		return err
+	}
+	payload := job.Payload
+	if len(payload) == 0 {
+		payload = jsontext.Value("null")
+	}
+	values := []any{job.ID, job.ExecTime.UnixMilli(), job.TaskName, string(payload)}
+	if _, err = tx.InsertRow(ctx, s.table, sqlJobStoreColumns, values); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+func (s *SQLJobStore) Delete(ctx context.Context, id string) error {
+	_, err := s.db.DeleteRow(ctx, s.table, "id", id)
+	return err
+}
+
+func (s *SQLJobStore) LoadAll(ctx context.Context) ([]StoredJob, error) {
+	rows, err := s.db.SelectRowsRaw(ctx, "SELECT id, exec_time, task_name, payload FROM "+s.db.Client().QuoteIdentifier(s.table))
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = rows.Close() }()
+	var jobs []StoredJob
+	for rows.Next() {
+		var (
+			job      StoredJob
+			execTime int64
+			payload  string
+		)
+		if err = rows.Scan(&job.ID, &execTime, &job.TaskName, &payload); err != nil {
+			return nil, err
+		}
+		job.ExecTime = time.UnixMilli(execTime)
+		job.Payload = jsontext.Value(payload)
+		jobs = append(jobs, job)
+	}
+	return jobs, rows.Err()
+}
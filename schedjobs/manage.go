@@ -0,0 +1,124 @@
+package schedjobs
+
+import (
+	"errors"
+	"slices"
+	"time"
+)
+
+// ErrJobNotFound is returned by the job management methods for an unknown job ID
+var ErrJobNotFound = errors.New("schedjobs: job not found")
+
+// hasJobLocked reports whether a job with the ID is scheduled. s.mu must be held
+func (s *Scheduler) hasJobLocked(jobID string) bool {
+	if _, ok := s.cronJobs[jobID]; ok {
+		return true
+	}
+	if _, ok := s.intervalJobs[jobID]; ok {
+		return true
+	}
+	isJob := func(t *timer) bool { return t.oneTime != nil && t.oneTime.ID == jobID }
+	return slices.ContainsFunc(s.timers, isJob) || slices.ContainsFunc(s.held, isJob)
+}
+
+// Pause stops running the job's occurrences until Resume.
+// Occurrences of paused cron and interval jobs are skipped. A paused one-time job that is due runs on Resume.
+func (s *Scheduler) Pause(jobID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.hasJobLocked(jobID) {
+		return ErrJobNotFound
+	}
+	if s.paused == nil {
+		s.paused = make(map[string]bool)
+	}
+	s.paused[jobID] = true
+	return nil
+}
+
+// Resume resumes a paused job
+func (s *Scheduler) Resume(jobID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.paused[jobID] {
+		if !s.hasJobLocked(jobID) {
+			return ErrJobNotFound
+		}
+		return nil
+	}
+	delete(s.paused, jobID)
+	s.held = slices.DeleteFunc(s.held, func(t *timer) bool {
+		if t.oneTime.ID != jobID {
+			return false
+		}
+		s.pushTimer(t) // already due. fires right away
+		return true
+	})
+	return nil
+}
+
+// RunNow runs a job right away, outside its schedule and regardless of Pause and coordination.
+// A one-time job is unscheduled, so it runs once.
+func (s *Scheduler) RunNow(jobID string) error {
+	s.mu.Lock()
+	cronJob := s.cronJobs[jobID]
+	intervalJob := s.intervalJobs[jobID]
+	var oneTimeJob *OneTimeJob
+	if cronJob == nil && intervalJob == nil {
+		take := func(t *timer) bool {
+			if t.oneTime == nil || t.oneTime.ID != jobID {
+				return false
+			}
+			oneTimeJob = t.oneTime
+			return true
+		}
+		s.timers.removeFunc(take)
+		s.held = slices.DeleteFunc(s.held, take)
+	}
+	s.mu.Unlock()
+	switch {
+	case cronJob != nil:
+		s.runCronJob(cronJob, time.Time{})
+	case intervalJob != nil:
+		s.runIntervalJob(intervalJob, time.Time{})
+	case oneTimeJob != nil:
+		s.runOneTimeJob(oneTimeJob, time.Time{})
+	default:
+		return ErrJobNotFound
+	}
+	return nil
+}
+
+// Delete removes a job of any kind by its ID, with its run history.
+// The lookup and the removal happen under one lock, so a concurrent Add, Delete or Resume cannot land in between.
+func (s *Scheduler) Delete(jobID string) error {
+	s.mu.Lock()
+	cronJob := s.removeCronJobLocked(jobID)
+	var intervalJob *IntervalJob
+	var oneTimeJobs []*OneTimeJob
+	if cronJob == nil {
+		intervalJob = s.removeIntervalJobLocked(jobID)
+	}
+	if cronJob == nil && intervalJob == nil {
+		oneTimeJobs = s.removeOneTimeJobLocked(jobID)
+	}
+	delete(s.paused, jobID)
+	delete(s.histories, jobID)
+	s.mu.Unlock()
+	// callbacks and the job store outside the lock
+	switch {
+	case cronJob != nil:
+		if s.OnCronJobDeleted != nil {
+			s.OnCronJobDeleted(cronJob)
+		}
+	case intervalJob != nil:
+		if s.OnIntervalJobDeleted != nil {
+			s.OnIntervalJobDeleted(intervalJob)
+		}
+	case len(oneTimeJobs) > 0:
+		s.oneTimeJobsDeleted(jobID, oneTimeJobs)
+	default:
+		return ErrJobNotFound
+	}
+	return nil
+}
@@ -0,0 +1,51 @@
+package schedjobs
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestDeleteConcurrent(t *testing.T) {
+	s := NewScheduler(context.Background())
+	var deleted atomic.Int32
+	s.OnCronJobDeleted = func(*CronJob) { deleted.Add(1) }
+	s.OnOneTimeJobDeleted = func(*OneTimeJob) { deleted.Add(1) }
+	for round := range 50 {
+		var err error
+		if round%2 == 0 {
+			err = s.AddCronJob(NewEveryMinEmptyCronJob("job"))
+		} else {
+			err = s.AddOneTimeJob(&OneTimeJob{ID: "job", ExecTime: time.Now().Add(time.Hour)})
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		var ok atomic.Int32
+		var wg sync.WaitGroup
+		for range 8 {
+			wg.Go(func() {
+				err := s.Delete("job")
+				switch {
+				case err == nil:
+					ok.Add(1)
+				case !errors.Is(err, ErrJobNotFound):
+					t.Error(err)
+				}
+			})
+		}
+		wg.Wait()
+		if ok.Load() != 1 {
+			t.Fatalf("round %d: %d deletes succeeded", round, ok.Load())
+		}
+	}
+	if deleted.Load() != 50 {
+		t.Fatalf("%d delete callbacks, want 50", deleted.Load())
+	}
+	if len(s.timers) != 0 || len(s.cronJobs) != 0 {
+		t.Fatal("job left scheduled")
+	}
+}
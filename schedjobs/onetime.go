@@ -1,12 +1,20 @@
 package schedjobs
 
-import "time"
+import (
+	"context"
+	"time"
+)
 
 type OneTimeJob struct {
 	ID       string
 	ExecTime time.Time
 	Task     func() error
+	TaskCtx  func(ctx context.Context) error // context-aware task. Used instead of Task if set
+	// Execution controls
+	MaxRunDuration time.Duration // deadline of TaskCtx's ctx per attempt. 0 = none. Task (without ctx) cannot be interrupted
+	Retries        int           // extra attempts after a failure
+	RetryBackoff   time.Duration // delay before the first retry, doubled each time. default 1s
 	// Job-specific callbacks
 	OnAdded    func()
-	OnFinished func(error)
+	OnFinished func(error) // err is a *PanicError if the task panicked
 }
@@ -1,10 +1,12 @@
 package schedjobs
 
 import (
+	"container/heap"
 	"context"
 	"fmt"
 	"log"
 	"runtime/debug"
+	"slices"
 	"sync"
 	"time"
 
@@ -12,21 +14,36 @@ import (
 )
 
 type Scheduler struct {
-	Ctx         context.Context    // Service Context
-	cancel      context.CancelFunc // Service Context CancelFunc
-	state       int                // internal service state
-	done        chan error         // Shutdown Error Channel
-	oneTimeJobs map[int64][]*OneTimeJob
-	cronJobs    map[string]*CronJob
-	mu          sync.Mutex
-	wg          sync.WaitGroup
+	Ctx          context.Context    // Service Context
+	cancel       context.CancelFunc // Service Context CancelFunc
+	state        int                // internal service state
+	done         chan error         // Shutdown Error Channel
+	timers       timerHeap          // next occurrence of every job
+	wake         chan struct{}      // signals the run loop that the earliest timer changed
+	cronJobs     map[string]*CronJob
+	intervalJobs map[string]*IntervalJob
+	mu           sync.Mutex
+	wg           sync.WaitGroup
+	store        JobStore                  // UseJobStore
+	catchUp      CatchUpConf               // UseJobStore
+	tasks        map[string]PersistentTask // RegisterTask
+	coordinator  Coordinator               // UseCoordinator. nil = uncoordinated
+	paused       map[string]bool           // Pause
+	held         []*timer                  // due one-time jobs held while paused
+	// Run history
+	historySize         int // SetHistorySize. 0 = DefaultHistorySize
+	histories           map[string]*runHistory
+	oneTimeHistoryOrder []string // IDs of one-time jobs in histories, oldest first
 	// Default Callbacks
-	OnOneTimeJobAdded    func(job *OneTimeJob)
-	OnCronJobAdded       func(job *CronJob)
-	OnOneTimeJobFinished func(job *OneTimeJob, err error)
-	OnCronJobFinished    func(job *CronJob, err error)
-	OnOneTimeJobDeleted  func(job *OneTimeJob)
-	OnCronJobDeleted     func(job *CronJob)
+	OnOneTimeJobAdded     func(job *OneTimeJob)
+	OnCronJobAdded        func(job *CronJob)
+	OnIntervalJobAdded    func(job *IntervalJob)
+	OnOneTimeJobFinished  func(job *OneTimeJob, err error)
+	OnCronJobFinished     func(job *CronJob, err error)
+	OnIntervalJobFinished func(job *IntervalJob, err error)
+	OnOneTimeJobDeleted   func(job *OneTimeJob)
+	OnCronJobDeleted      func(job *CronJob)
+	OnIntervalJobDeleted  func(job *IntervalJob)
 }
 
 func (s *Scheduler) Name() string {
@@ -36,12 +53,14 @@ func (s *Scheduler) Name() string {
 func NewScheduler(parentCtx context.Context) *Scheduler {
 	svcCtx, svcCancel := context.WithCancel(parentCtx)
 	return &Scheduler{
-		Ctx:         svcCtx,
-		cancel:      svcCancel,
-		state:       svc.StateREADY,
-		done:        make(chan error, 1),
-		oneTimeJobs: make(map[int64][]*OneTimeJob),
-		cronJobs:    make(map[string]*CronJob),
+		Ctx:          svcCtx,
+		cancel:       svcCancel,
+		state:        svc.StateREADY,
+		done:         make(chan error, 1),
+		wake:         make(chan struct{}, 1),
+		cronJobs:     make(map[string]*CronJob),
+		intervalJobs: make(map[string]*IntervalJob),
+		tasks:        make(map[string]PersistentTask),
 	}
 }
 
@@ -67,6 +86,14 @@ func (s *Scheduler) UseDefaultLoggers() {
 			log.Printf("[INFO] one-time job finished: %s with error: %v", job.ID, err)
 		}
 	}
+	s.OnIntervalJobAdded = func(job *IntervalJob) {
+		log.Printf("[INFO] interval job added: %s every %s", job.ID, job.Interval)
+	}
+	s.OnIntervalJobFinished = func(job *IntervalJob, err error) {
+		if err != nil { // every few seconds. log failures only
+			log.Printf("[INFO] interval job finished: %s with error: %v", job.ID, err)
+		}
+	}
 }
 
 func (s *Scheduler) Start() error {
@@ -76,6 +103,14 @@ func (s *Scheduler) Start() error {
 	if s.state != svc.StateREADY {
 		return fmt.Errorf("cannot start. not ready")
 	}
+	if s.coordinator != nil {
+		s.coordinator.Start(s.Ctx)
+	}
+	if s.store != nil {
+		if err := s.rehydrate(); err != nil {
+			return fmt.Errorf("rehydrate one-time jobs: %w", err)
+		}
+	}
 	s.state = svc.StateRUNNING
 	log.Println("[INFO][JobScheduler] service started")
 	go s.run()
@@ -96,9 +131,12 @@ func (s *Scheduler) Done() <-chan error {
 	return s.done
 }
 
+// maxIdle bounds the sleep of the run loop when no timer is set
+const maxIdle = time.Hour
+
 func (s *Scheduler) run() {
-	ticker := time.NewTicker(time.Minute)
-	defer ticker.Stop()
+	timer := time.NewTimer(s.untilNext())
+	defer timer.Stop()
 	for {
 		select {
 		case <-s.Ctx.Done():
@@ -106,28 +144,115 @@ func (s *Scheduler) run() {
 			s.wg.Wait()   // wait for all worker goroutines
 			s.done <- nil // clean shutdown
 			return
-		case now := <-ticker.C:
-			func() {
-				defer func() {
-					if r := recover(); r != nil {
-						log.Printf("[PANIC][Scheduler] panic recovered: %v\n%s", r, debug.Stack())
-					}
-				}()
-				s.runOneTimeJobs(now)
-				s.runCronJobs(now)
+		case <-s.wake:
+		case <-timer.C:
+		}
+		func() {
+			defer func() {
+				if r := recover(); r != nil {
+					log.Printf("[PANIC][Scheduler] panic recovered: %v\n%s", r, debug.Stack())
+				}
 			}()
+			s.runDue(time.Now())
+		}()
+		timer.Reset(s.untilNext())
+	}
+}
+
+// untilNext returns the wait until the earliest timer fires
+func (s *Scheduler) untilNext() time.Duration {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.timers) == 0 {
+		return maxIdle
+	}
+	return max(time.Until(s.timers[0].at), 0)
+}
+
+// pushTimer sets a timer and wakes the run loop if it became the earliest. s.mu must be held
+func (s *Scheduler) pushTimer(t *timer) {
+	heap.Push(&s.timers, t)
+	if t.index == 0 {
+		select {
+		case s.wake <- struct{}{}:
+		default: // a wake-up is already pending
+		}
+	}
+}
+
+// popDue removes the timers due at now and sets the next occurrence of their recurring jobs.
+// Occurrences missed by more than a period (e.g. the clock jumped forward) are skipped, as are those of paused jobs.
+func (s *Scheduler) popDue(now time.Time) []*timer {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var due []*timer
+	for len(s.timers) > 0 && !s.timers[0].at.After(now) {
+		t := heap.Pop(&s.timers).(*timer)
+		switch {
+		case !s.paused[t.jobID()]:
+			due = append(due, t)
+		case t.oneTime != nil:
+			s.held = append(s.held, t) // runs on Resume
 		}
+		switch {
+		case t.cron != nil:
+			next := t.cron.NextRun(t.scheduled)
+			if next.Before(now) {
+				next = t.cron.NextRun(now)
+			}
+			s.setCronTimer(t.cron, next)
+		case t.interval != nil:
+			next := t.scheduled.Add(t.interval.Interval)
+			if next.Before(now) {
+				next = t.interval.NextRun(now)
+			}
+			s.pushTimer(&timer{at: withJitter(next, t.interval.Jitter), scheduled: next, interval: t.interval})
+		}
+	}
+	return due
+}
+
+// setCronTimer sets the timer of a cron job's occurrence at next. s.mu must be held
+func (s *Scheduler) setCronTimer(job *CronJob, next time.Time) {
+	if next.IsZero() {
+		log.Printf("[WARN][JobScheduler] cron job %s never runs again", job.ID)
+		return
 	}
+	s.pushTimer(&timer{at: withJitter(next, job.Jitter), scheduled: next, cron: job})
 }
 
-// GetOneTimeJobs returns a copy of all pending one-time jobs, keyed by their scheduled minute-level timestamp.
+// UseCoordinator makes the scheduler cluster-safe with a Coordinator. Call before Start
+func (s *Scheduler) UseCoordinator(coordinator Coordinator) {
+	s.coordinator = coordinator
+}
+
+// coordinated reports whether this instance runs the job occurrence. Zero scheduled = manual run (RunNow)
+func (s *Scheduler) coordinated(jobID string, scheduled time.Time, oneTime bool) bool {
+	if s.coordinator == nil || scheduled.IsZero() {
+		return true
+	}
+	if oneTime {
+		return s.coordinator.ShouldRunOnce(s.Ctx, jobID, scheduled.Truncate(time.Second))
+	}
+	return s.coordinator.ShouldRun(s.Ctx, jobID, scheduled.Truncate(time.Second))
+}
+
+// GetOneTimeJobs returns all pending one-time jobs, keyed by their scheduled minute-level timestamp
+// (unix minutes, rounded up).
 func (s *Scheduler) GetOneTimeJobs() map[int64][]*OneTimeJob {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	result := make(map[int64][]*OneTimeJob, len(s.oneTimeJobs))
-	for key, jobs := range s.oneTimeJobs {
-		result[key] = append([]*OneTimeJob(nil), jobs...) // copy slice to avoid external mutation
+	result := make(map[int64][]*OneTimeJob)
+	for _, t := range s.timers {
+		if t.oneTime == nil {
+			continue
+		}
+		key := t.at.Unix() / 60
+		if t.at.Second() > 0 || t.at.Nanosecond() > 0 {
+			key++
+		}
+		result[key] = append(result[key], t.oneTime)
 	}
 	return result
 }
@@ -144,26 +269,25 @@ func (s *Scheduler) GetCronJobs() map[string]*CronJob {
 	return result
 }
 
+// AddOneTimeJob schedules a job to run once at ExecTime (to the timer's precision)
 func (s *Scheduler) AddOneTimeJob(job *OneTimeJob) error {
-	now := time.Now()
-	margin := 30 * time.Second
-	if job.ExecTime.Before(now.Add(margin)) {
-		return fmt.Errorf(
-			"cannot schedule job %s too close or in the past (ExecTime: %s, now: %s)",
-			job.ID, job.ExecTime, now,
-		)
-	}
-	// Round up to the next minute if ExecTime has seconds/nanoseconds
-	regTime := job.ExecTime
-	if regTime.Second() > 0 || regTime.Nanosecond() > 0 {
-		regTime = regTime.Truncate(time.Minute).Add(time.Minute)
-	}
-	key := regTime.Unix() / 60
-	s.mu.Lock()
-	if s.oneTimeJobs == nil {
-		s.oneTimeJobs = make(map[int64][]*OneTimeJob) // safety net
+	if err := checkExecTime(job.ID, job.ExecTime); err != nil {
+		return err
 	}
-	s.oneTimeJobs[key] = append(s.oneTimeJobs[key], job) // to make this safer?
+	s.addOneTimeJob(job)
+	return nil
+}
+
+func checkExecTime(jobID string, execTime time.Time) error {
+	if now := time.Now(); execTime.Before(now) {
+		return fmt.Errorf("cannot schedule job %s in the past (ExecTime: %s, now: %s)", jobID, execTime, now)
+	}
+	return nil
+}
+
+func (s *Scheduler) addOneTimeJob(job *OneTimeJob) {
+	s.mu.Lock()
+	s.pushTimer(&timer{at: job.ExecTime, scheduled: job.ExecTime, oneTime: job})
 	s.mu.Unlock()
 	if job.OnAdded != nil { // Job-specific callback
 		func() {
@@ -178,7 +302,6 @@ func (s *Scheduler) AddOneTimeJob(job *OneTimeJob) error {
 	if s.OnOneTimeJobAdded != nil { // Scheduler-level default callback
 		s.OnOneTimeJobAdded(job)
 	}
-	return nil
 }
 
 func (s *Scheduler) AddCronJob(job *CronJob) error {
@@ -187,9 +310,11 @@ func (s *Scheduler) AddCronJob(job *CronJob) error {
 		s.cronJobs = make(map[string]*CronJob)
 	}
 	if _, exists := s.cronJobs[job.ID]; exists {
+		s.mu.Unlock()
 		return fmt.Errorf("cron job with ID %q already exists", job.ID)
 	}
 	s.cronJobs[job.ID] = job
+	s.setCronTimer(job, job.NextRun(time.Now()))
 	s.mu.Unlock()
 	// Job-specific callback
 	if job.OnAdded != nil {
@@ -209,25 +334,39 @@ func (s *Scheduler) AddCronJob(job *CronJob) error {
 	return nil
 }
 
-// DeleteOneTimeJob - Delete a job
+// DeleteOneTimeJob - Delete a job (and its persisted copy, if any)
 func (s *Scheduler) DeleteOneTimeJob(jobID string) {
 	s.mu.Lock()
-	defer s.mu.Unlock()
-	for key, jobs := range s.oneTimeJobs {
-		filtered := jobs[:0]
-		for _, job := range jobs {
-			if job.ID == jobID {
-				if s.OnOneTimeJobDeleted != nil {
-					s.OnOneTimeJobDeleted(job)
-				}
-			} else {
-				filtered = append(filtered, job)
-			}
+	removed := s.removeOneTimeJobLocked(jobID)
+	s.mu.Unlock()
+	s.oneTimeJobsDeleted(jobID, removed)
+}
+
+// removeOneTimeJobLocked unschedules the one-time jobs with the ID. s.mu must be held
+func (s *Scheduler) removeOneTimeJobLocked(jobID string) []*OneTimeJob {
+	var removed []*OneTimeJob
+	remove := func(t *timer) bool {
+		if t.oneTime == nil || t.oneTime.ID != jobID {
+			return false
 		}
-		if len(filtered) == 0 {
-			delete(s.oneTimeJobs, key)
-		} else {
-			s.oneTimeJobs[key] = filtered
+		removed = append(removed, t.oneTime)
+		return true
+	}
+	s.timers.removeFunc(remove)
+	s.held = slices.DeleteFunc(s.held, remove)
+	return removed
+}
+
+// oneTimeJobsDeleted deletes the persisted copy of removed one-time jobs and calls the callbacks. Call without s.mu
+func (s *Scheduler) oneTimeJobsDeleted(jobID string, removed []*OneTimeJob) {
+	if s.store != nil {
+		if err := s.store.Delete(context.WithoutCancel(s.Ctx), jobID); err != nil {
+			log.Printf("[ERROR][JobScheduler] failed to delete stored job %s: %v", jobID, err)
+		}
+	}
+	if s.OnOneTimeJobDeleted != nil {
+		for _, job := range removed {
+			s.OnOneTimeJobDeleted(job)
 		}
 	}
 }
@@ -235,15 +374,90 @@ func (s *Scheduler) DeleteOneTimeJob(jobID string) {
 // DeleteCronJob removes a cron job by its ID
 func (s *Scheduler) DeleteCronJob(jobID string) {
 	s.mu.Lock()
+	job := s.removeCronJobLocked(jobID)
+	s.mu.Unlock()
+	// trigger global delete callback outside lock
+	if job != nil && s.OnCronJobDeleted != nil {
+		s.OnCronJobDeleted(job)
+	}
+}
+
+// removeCronJobLocked unregisters a cron job and returns it, or nil if there is none. s.mu must be held
+func (s *Scheduler) removeCronJobLocked(jobID string) *CronJob {
 	job, exists := s.cronJobs[jobID]
 	if !exists {
-		s.mu.Unlock()
-		return
+		return nil
 	}
 	delete(s.cronJobs, jobID)
+	s.timers.removeFunc(func(t *timer) bool { return t.cron == job })
+	return job
+}
+
+// GetIntervalJobs returns a copy of all registered interval jobs, keyed by their ID.
+func (s *Scheduler) GetIntervalJobs() map[string]*IntervalJob {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	result := make(map[string]*IntervalJob, len(s.intervalJobs))
+	for id, job := range s.intervalJobs {
+		result[id] = job // shallow copy of the pointer; job itself is shared
+	}
+	return result
+}
+
+// AddIntervalJob schedules a job to run every job.Interval, starting at the next aligned occurrence
+func (s *Scheduler) AddIntervalJob(job *IntervalJob) error {
+	if job.Interval <= 0 {
+		return fmt.Errorf("interval job %q: interval must be positive", job.ID)
+	}
+	s.mu.Lock()
+	if s.intervalJobs == nil {
+		s.intervalJobs = make(map[string]*IntervalJob)
+	}
+	if _, exists := s.intervalJobs[job.ID]; exists {
+		s.mu.Unlock()
+		return fmt.Errorf("interval job with ID %q already exists", job.ID)
+	}
+	s.intervalJobs[job.ID] = job
+	next := job.NextRun(time.Now())
+	s.pushTimer(&timer{at: withJitter(next, job.Jitter), scheduled: next, interval: job})
+	s.mu.Unlock()
+	// Job-specific callback
+	if job.OnAdded != nil {
+		func() {
+			defer func() {
+				if r := recover(); r != nil {
+					log.Println("[PANIC] Recovered in job.OnAdded:", r)
+				}
+			}()
+			job.OnAdded()
+		}()
+	}
+	// Scheduler-level default callback
+	if s.OnIntervalJobAdded != nil {
+		s.OnIntervalJobAdded(job)
+	}
+	return nil
+}
+
+// DeleteIntervalJob removes an interval job by its ID
+func (s *Scheduler) DeleteIntervalJob(jobID string) {
+	s.mu.Lock()
+	job := s.removeIntervalJobLocked(jobID)
 	s.mu.Unlock()
 	// trigger global delete callback outside lock
-	if s.OnCronJobDeleted != nil {
-		s.OnCronJobDeleted(job)
+	if job != nil && s.OnIntervalJobDeleted != nil {
+		s.OnIntervalJobDeleted(job)
+	}
+}
+
+// removeIntervalJobLocked unregisters an interval job and returns it, or nil if there is none. s.mu must be held
+func (s *Scheduler) removeIntervalJobLocked(jobID string) *IntervalJob {
+	job, exists := s.intervalJobs[jobID]
+	if !exists {
+		return nil
 	}
+	delete(s.intervalJobs, jobID)
+	s.timers.removeFunc(func(t *timer) bool { return t.interval == job })
+	return job
 }
@@ -7,71 +7,91 @@ import (
 	"time"
 )
 
-func (s *Scheduler) runOneTimeJobs(now time.Time) {
-	key := now.Unix() / 60
-	s.mu.Lock()
-	jobs := s.oneTimeJobs[key]
-	delete(s.oneTimeJobs, key)
-	log.Println("[DEBUG] runOneTimeJobs(now) with key: ", key)
-	s.mu.Unlock()
-	for _, job := range jobs {
-		s.runOneTimeJob(job)
+func (s *Scheduler) runDue(now time.Time) {
+	due := s.popDue(now)
+	log.Printf("[DEBUG] runDue called at %v. %d timers due", now, len(due))
+	for _, t := range due {
+		switch {
+		case t.oneTime != nil:
+			s.runOneTimeJob(t.oneTime, t.scheduled)
+		case t.cron != nil:
+			log.Println("[DEBUG] matching cron job spec for ", t.cron.ID)
+			if t.cron.Matches(t.scheduled) { // the schedule may have changed since the timer was set
+				log.Println("[DEBUG] cron job spec MATCHED for ", t.cron.ID)
+				s.runCronJob(t.cron, t.scheduled)
+			}
+		case t.interval != nil:
+			s.runIntervalJob(t.interval, t.scheduled)
+		}
 	}
 }
 
-func (s *Scheduler) runOneTimeJob(job *OneTimeJob) {
+func (s *Scheduler) runOneTimeJob(job *OneTimeJob, scheduled time.Time) {
 	log.Println("[DEBUG] runOneTimeJob() called")
 	s.wg.Add(1)
 	go func() {
 		defer s.wg.Done()
-		err := job.Task()
+		if !s.coordinated(job.ID, scheduled, true) {
+			return
+		}
+		err := s.executeOneTimeJob(job)
 		if job.OnFinished != nil {
-			func() {
-				defer func() {
-					if r := recover(); r != nil {
-						log.Println("[PANIC] Recovered in job.OnFinished:", r)
-					}
-				}()
-				job.OnFinished(err)
-			}()
+			callFinished(job.ID, func() { job.OnFinished(err) })
 		}
 		if s.OnOneTimeJobFinished != nil {
-			s.OnOneTimeJobFinished(job, err)
+			callFinished(job.ID, func() { s.OnOneTimeJobFinished(job, err) })
 		}
 	}()
 }
 
-func (s *Scheduler) runCronJobs(now time.Time) {
-	log.Println("[DEBUG] runCronJobs called at", now)
-	s.mu.Lock()
-	log.Println("[DEBUG] total cron jobs:", len(s.cronJobs))
-	// Copy values to a slice so we can unlock early
-	jobs := make([]*CronJob, 0, len(s.cronJobs))
-	for _, job := range s.cronJobs {
-		jobs = append(jobs, job)
-	}
-	log.Printf("[DEBUG] %d cronjobs copied", len(jobs))
-	s.mu.Unlock()
-	for _, job := range jobs {
-		log.Println("[DEBUG] matching cron job spec for ", job.ID)
-		if job.Matches(now) {
-			log.Println("[DEBUG] cron job spec MATCHED for ", job.ID)
-			s.runCronJob(job)
+func (s *Scheduler) runCronJob(job *CronJob, scheduled time.Time) {
+	log.Println("[DEBUG] runCronJob() called")
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		if !s.coordinated(job.ID, scheduled, false) {
+			return
 		}
-	}
+		if !job.overlap.begin(job.Overlap, job.ID) {
+			return
+		}
+		for {
+			err := s.executeCronJob(job)
+			if job.OnFinished != nil {
+				callFinished(job.ID, func() { job.OnFinished(err) })
+			}
+			if s.OnCronJobFinished != nil {
+				callFinished(job.ID, func() { s.OnCronJobFinished(job, err) })
+			}
+			if !job.overlap.finish() {
+				return
+			}
+		}
+	}()
 }
 
-func (s *Scheduler) runCronJob(job *CronJob) {
-	log.Println("[DEBUG] runCronJob() called")
+func (s *Scheduler) runIntervalJob(job *IntervalJob, scheduled time.Time) {
+	log.Println("[DEBUG] runIntervalJob() called")
 	s.wg.Add(1)
 	go func() {
 		defer s.wg.Done()
-		err := job.Task()
-		if job.OnFinished != nil {
-			job.OnFinished(err)
+		if !s.coordinated(job.ID, scheduled, false) {
+			return
+		}
+		if !job.overlap.begin(job.Overlap, job.ID) {
+			return
 		}
-		if s.OnCronJobFinished != nil {
-			s.OnCronJobFinished(job, err)
+		for {
+			err := s.executeIntervalJob(job)
+			if job.OnFinished != nil {
+				callFinished(job.ID, func() { job.OnFinished(err) })
+			}
+			if s.OnIntervalJobFinished != nil {
+				callFinished(job.ID, func() { s.OnIntervalJobFinished(job, err) })
+			}
+			if !job.overlap.finish() {
+				return
+			}
 		}
 	}()
 }
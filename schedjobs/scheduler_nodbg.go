@@ -4,56 +4,84 @@ package schedjobs
 
 import "time"
 
-func (s *Scheduler) runOneTimeJobs(now time.Time) {
-	key := now.Unix() / 60
-	s.mu.Lock()
-	jobs := s.oneTimeJobs[key]
-	delete(s.oneTimeJobs, key)
-	s.mu.Unlock()
-	for _, job := range jobs {
-		s.runOneTimeJob(job)
+func (s *Scheduler) runDue(now time.Time) {
+	for _, t := range s.popDue(now) {
+		switch {
+		case t.oneTime != nil:
+			s.runOneTimeJob(t.oneTime, t.scheduled)
+		case t.cron != nil:
+			if t.cron.Matches(t.scheduled) { // the schedule may have changed since the timer was set
+				s.runCronJob(t.cron, t.scheduled)
+			}
+		case t.interval != nil:
+			s.runIntervalJob(t.interval, t.scheduled)
+		}
 	}
 }
 
-func (s *Scheduler) runOneTimeJob(job *OneTimeJob) {
+func (s *Scheduler) runOneTimeJob(job *OneTimeJob, scheduled time.Time) {
 	s.wg.Add(1)
 	go func() {
 		defer s.wg.Done()
-		err := job.Task()
+		if !s.coordinated(job.ID, scheduled, true) {
+			return
+		}
+		err := s.executeOneTimeJob(job)
 		if job.OnFinished != nil {
-			job.OnFinished(err)
+			callFinished(job.ID, func() { job.OnFinished(err) })
 		}
 		if s.OnOneTimeJobFinished != nil {
-			s.OnOneTimeJobFinished(job, err)
+			callFinished(job.ID, func() { s.OnOneTimeJobFinished(job, err) })
 		}
 	}()
 }
 
-func (s *Scheduler) runCronJobs(now time.Time) {
-	s.mu.Lock()
-	// Copy values to a slice so we can unlock early
-	jobs := make([]*CronJob, 0, len(s.cronJobs))
-	for _, job := range s.cronJobs {
-		jobs = append(jobs, job)
-	}
-	s.mu.Unlock()
-	for _, job := range jobs {
-		if job.Matches(now) {
-			s.runCronJob(job)
+func (s *Scheduler) runCronJob(job *CronJob, scheduled time.Time) {
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		if !s.coordinated(job.ID, scheduled, false) {
+			return
 		}
-	}
+		if !job.overlap.begin(job.Overlap, job.ID) {
+			return
+		}
+		for {
+			err := s.executeCronJob(job)
+			if job.OnFinished != nil {
+				callFinished(job.ID, func() { job.OnFinished(err) })
+			}
+			if s.OnCronJobFinished != nil {
+				callFinished(job.ID, func() { s.OnCronJobFinished(job, err) })
+			}
+			if !job.overlap.finish() {
+				return
+			}
+		}
+	}()
 }
 
-func (s *Scheduler) runCronJob(job *CronJob) {
+func (s *Scheduler) runIntervalJob(job *IntervalJob, scheduled time.Time) {
 	s.wg.Add(1)
 	go func() {
 		defer s.wg.Done()
-		err := job.Task()
-		if job.OnFinished != nil {
-			job.OnFinished(err)
+		if !s.coordinated(job.ID, scheduled, false) {
+			return
+		}
+		if !job.overlap.begin(job.Overlap, job.ID) {
+			return
 		}
-		if s.OnCronJobFinished != nil {
-			s.OnCronJobFinished(job, err)
+		for {
+			err := s.executeIntervalJob(job)
+			if job.OnFinished != nil {
+				callFinished(job.ID, func() { job.OnFinished(err) })
+			}
+			if s.OnIntervalJobFinished != nil {
+				callFinished(job.ID, func() { s.OnIntervalJobFinished(job, err) })
+			}
+			if !job.overlap.finish() {
+				return
+			}
 		}
 	}()
 }
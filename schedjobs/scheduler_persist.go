@@ -0,0 +1,107 @@
+package schedjobs
+
+import (
+	"context"
+	"encoding/json/v2"
+	"fmt"
+	"log"
+	"time"
+)
+
+// UseJobStore enables persistent one-time jobs. Call before Start.
+// At Start, stored jobs are rehydrated through the task registry, and missed ones are handled by catchUp.
+//
+// A store shared by several instances requires a Coordinator (UseCoordinator): every instance rehydrates every
+// stored job, and without one each of them runs it. With one, a job runs on the first instance locking it.
+// Only the instance adding a job and the ones starting afterwards schedule it, so if that instance stops
+// before the job is due, the job waits in the store for the next instance to start.
+func (s *Scheduler) UseJobStore(store JobStore, catchUp CatchUpConf) {
+	s.store = store
+	s.catchUp = catchUp
+}
+
+// RegisterTask registers a task persistent jobs can refer to by name. Register all tasks before Start
+func (s *Scheduler) RegisterTask(name string, task PersistentTask) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.tasks[name] = task
+}
+
+// AddPersistentOneTimeJob persists a one-time job running a registered task with the JSON-encoded payload, then schedules it.
+// The stored job is removed once it has run.
+func (s *Scheduler) AddPersistentOneTimeJob(jobID string, execTime time.Time, taskName string, payload any) error {
+	if s.store == nil {
+		return fmt.Errorf("no job store. call UseJobStore")
+	}
+	encoded, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	if err = checkExecTime(jobID, execTime); err != nil {
+		return err
+	}
+	stored := StoredJob{ID: jobID, ExecTime: execTime, TaskName: taskName, Payload: encoded}
+	job, err := s.oneTimeJobFromStored(stored)
+	if err != nil {
+		return err
+	}
+	// persist first: the job may run (and delete its stored copy) right after it is scheduled
+	if err = s.store.Save(s.Ctx, stored); err != nil {
+		return err
+	}
+	s.addOneTimeJob(job)
+	return nil
+}
+
+func (s *Scheduler) oneTimeJobFromStored(stored StoredJob) (*OneTimeJob, error) {
+	s.mu.Lock()
+	task, ok := s.tasks[stored.TaskName]
+	s.mu.Unlock()
+	if !ok {
+		return nil, fmt.Errorf("unknown task %q", stored.TaskName)
+	}
+	return &OneTimeJob{
+		ID:       stored.ID,
+		ExecTime: stored.ExecTime,
+		TaskCtx: func(ctx context.Context) error {
+			return task(ctx, stored.Payload)
+		},
+		// delete once all attempts are done
+		OnFinished: func(error) {
+			if err := s.store.Delete(context.WithoutCancel(s.Ctx), stored.ID); err != nil {
+				log.Printf("[ERROR][JobScheduler] failed to delete stored job %s: %v", stored.ID, err)
+			}
+		},
+	}, nil
+}
+
+// rehydrate schedules the stored jobs. Jobs of unknown tasks are left in the store
+func (s *Scheduler) rehydrate() error {
+	storedJobs, err := s.store.LoadAll(s.Ctx)
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	for _, stored := range storedJobs {
+		job, err := s.oneTimeJobFromStored(stored)
+		if err != nil {
+			log.Printf("[ERROR][JobScheduler] stored job %s not restored: %v", stored.ID, err)
+			continue
+		}
+		if stored.ExecTime.After(now) {
+			s.addOneTimeJob(job)
+			continue
+		}
+		missedBy := now.Sub(stored.ExecTime)
+		if s.catchUp.Skip || (s.catchUp.MaxDelay > 0 && missedBy > s.catchUp.MaxDelay) {
+			log.Printf("[WARN][JobScheduler] stored job %s missed by %s. skipped", stored.ID, missedBy)
+			if err = s.store.Delete(context.WithoutCancel(s.Ctx), stored.ID); err != nil {
+				return err
+			}
+			continue
+		}
+		log.Printf("[INFO][JobScheduler] stored job %s missed by %s. catching up", stored.ID, missedBy)
+		s.runOneTimeJob(job, job.ExecTime)
+	}
+	return nil
+}
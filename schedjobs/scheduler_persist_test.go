@@ -0,0 +1,97 @@
+package schedjobs
+
+import (
+	"context"
+	"encoding/json/jsontext"
+	"sync"
+	"testing"
+	"time"
+)
+
+// memJobStore fails like a network store once ctx is done
+type memJobStore struct {
+	mu   sync.Mutex
+	jobs map[string]StoredJob
+}
+
+func (m *memJobStore) Save(ctx context.Context, job StoredJob) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.jobs[job.ID] = job
+	return nil
+}
+
+func (m *memJobStore) Delete(ctx context.Context, id string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.jobs, id)
+	return nil
+}
+
+func (m *memJobStore) LoadAll(ctx context.Context) ([]StoredJob, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var jobs []StoredJob
+	for _, job := range m.jobs {
+		jobs = append(jobs, job)
+	}
+	return jobs, nil
+}
+
+func (m *memJobStore) len() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return len(m.jobs)
+}
+
+func TestStoredJobDeletedAtShutdown(t *testing.T) {
+	store := &memJobStore{jobs: map[string]StoredJob{}}
+	s := NewScheduler(context.Background())
+	s.UseJobStore(store, CatchUpConf{})
+	started := make(chan struct{})
+	s.RegisterTask("wait", func(ctx context.Context, _ jsontext.Value) error {
+		close(started)
+		<-ctx.Done()
+		return nil // finished its work on shutdown
+	})
+	if err := s.Start(); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.AddPersistentOneTimeJob("job", time.Now().Add(time.Hour), "wait", nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.RunNow("job"); err != nil {
+		t.Fatal(err)
+	}
+	<-started
+	s.Stop()
+	s.wg.Wait()
+	if store.len() != 0 {
+		t.Fatal("job finished during shutdown kept in the store")
+	}
+}
+
+func TestSkippedStoredJobDeleted(t *testing.T) {
+	store := &memJobStore{jobs: map[string]StoredJob{
+		"missed": {ID: "missed", ExecTime: time.Now().Add(-time.Hour), TaskName: "noop"},
+	}}
+	s := NewScheduler(context.Background())
+	s.UseJobStore(store, CatchUpConf{Skip: true})
+	s.RegisterTask("noop", func(context.Context, jsontext.Value) error { return nil })
+	if err := s.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer s.Stop()
+	if store.len() != 0 {
+		t.Fatal("skipped job kept in the store")
+	}
+}
@@ -0,0 +1,76 @@
+package schedjobs
+
+import (
+	"container/heap"
+	"math/rand/v2"
+	"time"
+)
+
+// timer is a scheduled occurrence of a job. Exactly one of the job pointers is set
+type timer struct {
+	at        time.Time // when it fires. scheduled + jitter
+	scheduled time.Time // nominal occurrence time. identifies the occurrence for coordination
+	oneTime   *OneTimeJob
+	cron      *CronJob
+	interval  *IntervalJob
+	index     int // in timerHeap
+}
+
+func (t *timer) jobID() string {
+	switch {
+	case t.oneTime != nil:
+		return t.oneTime.ID
+	case t.cron != nil:
+		return t.cron.ID
+	default:
+		return t.interval.ID
+	}
+}
+
+// timerHeap is a min-heap of timers by fire time (container/heap)
+type timerHeap []*timer
+
+func (h timerHeap) Len() int           { return len(h) }
+func (h timerHeap) Less(i, j int) bool { return h[i].at.Before(h[j].at) }
+func (h timerHeap) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+	h[i].index = i
+	h[j].index = j
+}
+
+func (h *timerHeap) Push(x any) {
+	t := x.(*timer)
+	t.index = len(*h)
+	*h = append(*h, t)
+}
+
+func (h *timerHeap) Pop() any {
+	old := *h
+	n := len(old)
+	t := old[n-1]
+	old[n-1] = nil
+	t.index = -1
+	*h = old[:n-1]
+	return t
+}
+
+// removeFunc removes the timers matching fn
+func (h *timerHeap) removeFunc(fn func(t *timer) bool) {
+	kept := (*h)[:0]
+	for _, t := range *h {
+		if !fn(t) {
+			t.index = len(kept)
+			kept = append(kept, t)
+		}
+	}
+	clear((*h)[len(kept):])
+	*h = kept
+	heap.Init(h)
+}
+
+func withJitter(scheduled time.Time, jitter time.Duration) time.Time {
+	if jitter <= 0 {
+		return scheduled
+	}
+	return scheduled.Add(rand.N(jitter))
+}
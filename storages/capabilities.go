@@ -0,0 +1,59 @@
+package storages
+
+import (
+	"context"
+	"io"
+	"time"
+)
+
+// Optional capabilities of Storage. Type-assert a Storage to check availability.
+
+// ObjectInfo is the metadata of a stored object
+type ObjectInfo struct {
+	Path        string
+	Size        int64
+	ModTime     time.Time
+	ContentType string // "" if unknown
+	ETag        string // quoted entity tag. e.g. `"5f3c-1a2b"`
+}
+
+type ListOptions struct {
+	Prefix    string // only paths starting with Prefix
+	Delimiter string // if set, paths containing Delimiter after Prefix are rolled up into CommonPrefixes. e.g. "/"
+	Cursor    string // continuation from ListPage.NextCursor. "" = from the beginning
+	Limit     int    // max# of entries (objects + common prefixes) per page. 0 = backend default
+}
+
+type ListPage struct {
+	Objects        []ObjectInfo
+	CommonPrefixes []string // e.g. "reports/2024/" with Delimiter "/"
+	NextCursor     string   // "" = no more pages
+}
+
+// Lister lists objects by prefix in lexical path order, page by page
+type Lister interface {
+	List(ctx context.Context, opts ListOptions) (*ListPage, error)
+}
+
+// Stater fetches object metadata without reading the content
+type Stater interface {
+	Stat(ctx context.Context, path string) (*ObjectInfo, error)
+}
+
+// RangeGetter reads a byte range of an object
+type RangeGetter interface {
+	// GetRange reads length bytes starting at offset. length < 0 reads to the end
+	GetRange(ctx context.Context, path string, offset int64, length int64) (io.ReadCloser, error)
+}
+
+// lastName returns the greatest path or common prefix in the page (the cursor to continue from)
+func (p *ListPage) lastName() string {
+	var last string
+	if n := len(p.Objects); n > 0 {
+		last = p.Objects[n-1].Path
+	}
+	if n := len(p.CommonPrefixes); n > 0 && p.CommonPrefixes[n-1] > last {
+		last = p.CommonPrefixes[n-1]
+	}
+	return last
+}
@@ -0,0 +1,184 @@
+// Package encstorage provides a storages.Storage decorator encrypting objects at rest.
+// Objects are sealed in 64KiB chunks with XChaCha20-Poly1305 (streaming AEAD), so neither Put nor Get holds a whole object.
+// The key ID is written into each object's header: objects written with older keys stay readable
+// as long as their key is in the key map, and Rekey moves them to the current key.
+package encstorage
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/cipher"
+	"fmt"
+	"io"
+
+	"github.com/x64c/gw/storages"
+	"golang.org/x/crypto/chacha20poly1305"
+)
+
+// EncStorage implements storages.Storage on top of another storage, encrypting on Put and decrypting on Get.
+// Objects are sealed to their path, so Copy and Move decrypt and re-encrypt through this process
+// rather than running server-side on the inner storage.
+type EncStorage struct {
+	inner        storages.Storage
+	aeads        map[string]cipher.AEAD // key ID -> AEAD
+	currentKeyID string
+}
+
+// New wraps inner. keys maps key IDs to 32-byte keys, and currentKeyID selects the key for new objects.
+func New(inner storages.Storage, keys map[string][]byte, currentKeyID string) (*EncStorage, error) {
+	aeads := make(map[string]cipher.AEAD, len(keys))
+	for keyID, key := range keys {
+		if keyID == "" || len(keyID) > maxKeyIDLen {
+			return nil, fmt.Errorf("encstorage: key id must be 1-%d bytes: %q", maxKeyIDLen, keyID)
+		}
+		aead, err := chacha20poly1305.NewX(key)
+		if err != nil {
+			return nil, fmt.Errorf("encstorage: key %q: %w", keyID, err)
+		}
+		aeads[keyID] = aead
+	}
+	if _, ok := aeads[currentKeyID]; !ok {
+		return nil, fmt.Errorf("encstorage: current key %q not in keys", currentKeyID)
+	}
+	return &EncStorage{inner: inner, aeads: aeads, currentKeyID: currentKeyID}, nil
+}
+
+// Inner returns the wrapped storage
+func (s *EncStorage) Inner() storages.Storage {
+	return s.inner
+}
+
+func (s *EncStorage) Exists(ctx context.Context, path string) (bool, error) {
+	return s.inner.Exists(ctx, path)
+}
+
+func (s *EncStorage) Get(ctx context.Context, path string) (io.ReadCloser, error) {
+	rc, err := s.inner.Get(ctx, path)
+	if err != nil {
+		return nil, err
+	}
+	br := bufio.NewReaderSize(rc, sealedChunkSize)
+	header, keyID, err := readHeader(br)
+	if err != nil {
+		_ = rc.Close()
+		return nil, err
+	}
+	aead, ok := s.aeads[keyID]
+	if !ok {
+		_ = rc.Close()
+		return nil, fmt.Errorf("%w: %q", ErrUnknownKey, keyID)
+	}
+	return &decryptReader{
+		src:    br,
+		closer: rc,
+		aead:   aead,
+		header: header,
+		path:   adPath(path),
+		sealed: make([]byte, sealedChunkSize),
+		nonce:  make([]byte, chacha20poly1305.NonceSizeX),
+	}, nil
+}
+
+func (s *EncStorage) Put(ctx context.Context, path string, r io.Reader) error {
+	header, err := newHeader(s.currentKeyID)
+	if err != nil {
+		return err
+	}
+	enc := &encryptReader{
+		src:    bufio.NewReaderSize(r, chunkSize),
+		aead:   s.aeads[s.currentKeyID],
+		header: header,
+		path:   adPath(path),
+		plain:  make([]byte, chunkSize),
+		nonce:  make([]byte, chacha20poly1305.NonceSizeX),
+	}
+	return s.inner.Put(ctx, path, io.MultiReader(bytes.NewReader(header), enc))
+}
+
+func (s *EncStorage) Delete(ctx context.Context, path string) error {
+	return s.inner.Delete(ctx, path)
+}
+
+// Size returns the plaintext size, derived from the ciphertext size and the header
+func (s *EncStorage) Size(ctx context.Context, path string) (int64, error) {
+	keyID, err := s.readObjectHeader(ctx, path)
+	if err != nil {
+		return 0, err
+	}
+	size, err := s.inner.Size(ctx, path)
+	if err != nil {
+		return 0, err
+	}
+	sealed := size - headerLen(keyID)
+	chunks := max((sealed+sealedChunkSize-1)/sealedChunkSize, 1)
+	plain := sealed - chunks*chacha20poly1305.Overhead
+	if plain < 0 {
+		return 0, ErrCorrupt
+	}
+	return plain, nil
+}
+
+// Copy re-encrypts src's plaintext under dst with the current key
+func (s *EncStorage) Copy(ctx context.Context, src string, dst string) error {
+	rc, err := s.Get(ctx, src)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = rc.Close() }()
+	return s.Put(ctx, dst, rc)
+}
+
+// Move copies src to dst, then deletes src. Unlike a server-side move it is not atomic:
+// if the delete fails, both paths hold the object.
+func (s *EncStorage) Move(ctx context.Context, src string, dst string) error {
+	if err := s.Copy(ctx, src, dst); err != nil {
+		return err
+	}
+	return s.inner.Delete(ctx, src)
+}
+
+// KeyID returns the ID of the key an object is encrypted with
+func (s *EncStorage) KeyID(ctx context.Context, path string) (string, error) {
+	return s.readObjectHeader(ctx, path)
+}
+
+// readObjectHeader reads only the header of an object and returns its key ID: a bounded range if the inner
+// storage reads ranges, otherwise the head of the stream, closing it right after
+func (s *EncStorage) readObjectHeader(ctx context.Context, path string) (string, error) {
+	var rc io.ReadCloser
+	var err error
+	if rg, ok := s.inner.(storages.RangeGetter); ok {
+		rc, err = rg.GetRange(ctx, path, 0, int64(maxHeaderLen))
+	} else {
+		rc, err = s.inner.Get(ctx, path)
+	}
+	if err != nil {
+		return "", err
+	}
+	defer func() { _ = rc.Close() }()
+	_, keyID, err := readHeader(rc)
+	return keyID, err
+}
+
+// Rekey re-encrypts an object with the current key, if it is encrypted with another one.
+// Returns whether the object was rewritten.
+func (s *EncStorage) Rekey(ctx context.Context, path string) (bool, error) {
+	keyID, err := s.KeyID(ctx, path)
+	if err != nil {
+		return false, err
+	}
+	if keyID == s.currentKeyID {
+		return false, nil
+	}
+	rc, err := s.Get(ctx, path)
+	if err != nil {
+		return false, err
+	}
+	defer func() { _ = rc.Close() }()
+	// the inner Put must not expose a partial object under path (LocalStorage writes atomically)
+	if err = s.Put(ctx, path, rc); err != nil {
+		return false, err
+	}
+	return true, nil
+}
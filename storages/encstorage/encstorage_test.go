@@ -0,0 +1,215 @@
+package encstorage
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/x64c/gw/storages"
+	"github.com/x64c/gw/storages/memstorage"
+	"golang.org/x/crypto/chacha20poly1305"
+)
+
+var testKeys = map[string][]byte{
+	"k1": bytes.Repeat([]byte{1}, chacha20poly1305.KeySize),
+	"k2": bytes.Repeat([]byte{2}, chacha20poly1305.KeySize),
+}
+
+func newTestStorage(t *testing.T, inner storages.Storage, currentKeyID string) *EncStorage {
+	s, err := New(inner, testKeys, currentKeyID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func randomBytes(n int) []byte {
+	b := make([]byte, n)
+	_, _ = rand.Read(b)
+	return b
+}
+
+func readAll(t *testing.T, s storages.Storage, path string) ([]byte, error) {
+	t.Helper()
+	rc, err := s.Get(context.Background(), path)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = rc.Close() }()
+	return io.ReadAll(rc)
+}
+
+func TestRoundTrip(t *testing.T) {
+	ctx := context.Background()
+	inner := memstorage.New()
+	s := newTestStorage(t, inner, "k1")
+	for _, size := range []int{0, 1, chunkSize - 1, chunkSize, chunkSize + 1, 3*chunkSize + 7} {
+		plain := randomBytes(size)
+		if err := s.Put(ctx, "obj", bytes.NewReader(plain)); err != nil {
+			t.Fatal(err)
+		}
+		got, err := readAll(t, s, "obj")
+		if err != nil || !bytes.Equal(got, plain) {
+			t.Fatalf("size %d: round trip got %d bytes, %v", size, len(got), err)
+		}
+		if n, err := s.Size(ctx, "obj"); err != nil || n != int64(size) {
+			t.Fatalf("size %d: Size = %d, %v", size, n, err)
+		}
+		sealed, _ := readAll(t, inner, "obj")
+		if bytes.Contains(sealed, plain) && size > 0 {
+			t.Fatalf("size %d: plaintext stored", size)
+		}
+	}
+}
+
+func TestTamperAndTruncation(t *testing.T) {
+	ctx := context.Background()
+	inner := memstorage.New()
+	s := newTestStorage(t, inner, "k1")
+	if err := s.Put(ctx, "obj", bytes.NewReader(randomBytes(2*chunkSize+10))); err != nil {
+		t.Fatal(err)
+	}
+	sealed, _ := readAll(t, inner, "obj")
+	hl := int(headerLen("k1"))
+	cases := map[string][]byte{
+		"flipped bit":     append(bytes.Clone(sealed[:hl+5]), append([]byte{sealed[hl+5] ^ 1}, sealed[hl+6:]...)...),
+		"dropped chunk":   append(bytes.Clone(sealed[:hl+sealedChunkSize]), sealed[hl+2*sealedChunkSize:]...),
+		"truncated chunk": sealed[:hl+2*sealedChunkSize],
+		"nonce changed":   append(append(bytes.Clone(sealed[:hl-1]), sealed[hl-1]^1), sealed[hl:]...),
+	}
+	for name, data := range cases {
+		if err := inner.Put(ctx, "bad", bytes.NewReader(data)); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := readAll(t, s, "bad"); !errors.Is(err, ErrCorrupt) {
+			t.Errorf("%s: err = %v", name, err)
+		}
+	}
+	if err := inner.Put(ctx, "plain", bytes.NewReader([]byte("hello"))); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := readAll(t, s, "plain"); !errors.Is(err, ErrNotEncrypted) {
+		t.Errorf("plain object: err = %v", err)
+	}
+}
+
+func TestCiphertextBoundToPath(t *testing.T) {
+	ctx := context.Background()
+	inner := memstorage.New()
+	s := newTestStorage(t, inner, "k1")
+	plain := []byte("secret of a")
+	if err := s.Put(ctx, "a", bytes.NewReader(plain)); err != nil {
+		t.Fatal(err)
+	}
+	// ciphertext moved behind the decorator's back
+	if err := inner.Copy(ctx, "a", "b"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := readAll(t, s, "b"); !errors.Is(err, ErrCorrupt) {
+		t.Fatalf("moved ciphertext: err = %v", err)
+	}
+
+	if err := s.Copy(ctx, "a", "c"); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Move(ctx, "c", "d"); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := readAll(t, s, "d"); err != nil || !bytes.Equal(got, plain) {
+		t.Fatalf("copied and moved: %q, %v", got, err)
+	}
+	if ok, _ := s.Exists(ctx, "c"); ok {
+		t.Fatal("Move kept the source")
+	}
+}
+
+// rangeCounter records how an inner storage is read
+type rangeCounter struct {
+	*storages.LocalStorage
+	gets   int
+	ranges []int64
+}
+
+func (r *rangeCounter) Get(ctx context.Context, path string) (io.ReadCloser, error) {
+	r.gets++
+	return r.LocalStorage.Get(ctx, path)
+}
+
+func (r *rangeCounter) GetRange(ctx context.Context, path string, offset int64, length int64) (io.ReadCloser, error) {
+	r.ranges = append(r.ranges, length)
+	return r.LocalStorage.GetRange(ctx, path, offset, length)
+}
+
+func TestSizeReadsOnlyHeader(t *testing.T) {
+	ctx := context.Background()
+	local, err := storages.OpenLocalStorage(storages.LocalStorageConf{Root: t.TempDir()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = local.Close() }()
+	inner := &rangeCounter{LocalStorage: local}
+	s := newTestStorage(t, inner, "k1")
+	if err = s.Put(ctx, "obj", bytes.NewReader(randomBytes(chunkSize+1))); err != nil {
+		t.Fatal(err)
+	}
+	if n, err := s.Size(ctx, "obj"); err != nil || n != chunkSize+1 {
+		t.Fatalf("Size = %d, %v", n, err)
+	}
+	if inner.gets != 0 || len(inner.ranges) != 1 || inner.ranges[0] != int64(maxHeaderLen) {
+		t.Fatalf("Size read %d objects and ranges %v", inner.gets, inner.ranges)
+	}
+}
+
+func TestRekey(t *testing.T) {
+	ctx := context.Background()
+	inner := memstorage.New()
+	plain := randomBytes(chunkSize + 3)
+	if err := newTestStorage(t, inner, "k1").Put(ctx, "old", bytes.NewReader(plain)); err != nil {
+		t.Fatal(err)
+	}
+
+	s := newTestStorage(t, inner, "k2")
+	if got, err := readAll(t, s, "old"); err != nil || !bytes.Equal(got, plain) {
+		t.Fatalf("before rekey: %d bytes, %v", len(got), err)
+	}
+	if rewritten, err := s.Rekey(ctx, "old"); err != nil || !rewritten {
+		t.Fatalf("Rekey = %v, %v", rewritten, err)
+	}
+	if keyID, err := s.KeyID(ctx, "old"); err != nil || keyID != "k2" {
+		t.Fatalf("KeyID = %q, %v", keyID, err)
+	}
+	if got, err := readAll(t, s, "old"); err != nil || !bytes.Equal(got, plain) {
+		t.Fatalf("after rekey: %d bytes, %v", len(got), err)
+	}
+	if rewritten, err := s.Rekey(ctx, "old"); err != nil || rewritten {
+		t.Fatalf("second Rekey = %v, %v", rewritten, err)
+	}
+}
+
+func TestPathSpellings(t *testing.T) {
+	ctx := context.Background()
+	local, err := storages.OpenLocalStorage(storages.LocalStorageConf{Root: t.TempDir()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = local.Close() }()
+	s := newTestStorage(t, local, "k1")
+	plain := []byte("the same object")
+	if err = s.Put(ctx, "docs/a.pdf", bytes.NewReader(plain)); err != nil {
+		t.Fatal(err)
+	}
+	for _, spelling := range []string{"docs/a.pdf", "/docs/a.pdf", "docs//a.pdf", "./docs/a.pdf", "docs/x/../a.pdf"} {
+		if got, err := readAll(t, s, spelling); err != nil || !bytes.Equal(got, plain) {
+			t.Errorf("%q: %q, %v", spelling, got, err)
+		}
+	}
+	if err = s.Put(ctx, "/docs//b.pdf", bytes.NewReader(plain)); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := readAll(t, s, "docs/b.pdf"); err != nil || !bytes.Equal(got, plain) {
+		t.Errorf("written as /docs//b.pdf: %q, %v", got, err)
+	}
+}
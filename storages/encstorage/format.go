@@ -0,0 +1,193 @@
+package encstorage
+
+import (
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+
+	"golang.org/x/crypto/chacha20poly1305"
+)
+
+// Object format
+//
+//	header: magic "GWE1" | key ID length (1 byte) | key ID | nonce prefix (16 bytes)
+//	chunks: XChaCha20-Poly1305 sealed chunks of chunkSize plaintext bytes. The last one may be shorter (or empty)
+//
+// The nonce of chunk i is nonce prefix | uint64 big-endian i. The additional data of every chunk is
+// header | object path | final flag byte, binding chunks to the key ID and the path (ciphertext moved to
+// another path does not open) and detecting truncation and reordering.
+// The path is cleaned first, so the spellings of a path storages resolve to the same object (e.g. "/a//b") seal alike.
+const (
+	magic           = "GWE1"
+	noncePrefixSize = chacha20poly1305.NonceSizeX - 8
+	chunkSize       = 64 << 10
+	sealedChunkSize = chunkSize + chacha20poly1305.Overhead
+	maxKeyIDLen     = 255
+	maxHeaderLen    = len(magic) + 1 + maxKeyIDLen + noncePrefixSize
+)
+
+var (
+	ErrNotEncrypted = errors.New("encstorage: not an encrypted object")
+	ErrUnknownKey   = errors.New("encstorage: unknown key id")
+	ErrCorrupt      = errors.New("encstorage: object corrupt or tampered")
+)
+
+func newHeader(keyID string) ([]byte, error) {
+	header := make([]byte, 0, len(magic)+1+len(keyID)+noncePrefixSize)
+	header = append(header, magic...)
+	header = append(header, byte(len(keyID)))
+	header = append(header, keyID...)
+	prefix := make([]byte, noncePrefixSize)
+	if _, err := rand.Read(prefix); err != nil {
+		return nil, err
+	}
+	return append(header, prefix...), nil
+}
+
+// readHeader reads the header and returns it with the key ID
+func readHeader(r io.Reader) ([]byte, string, error) {
+	fixed := make([]byte, len(magic)+1)
+	if _, err := io.ReadFull(r, fixed); err != nil {
+		return nil, "", fmt.Errorf("%w: %v", ErrNotEncrypted, err)
+	}
+	if string(fixed[:len(magic)]) != magic {
+		return nil, "", ErrNotEncrypted
+	}
+	rest := make([]byte, int(fixed[len(magic)])+noncePrefixSize)
+	if _, err := io.ReadFull(r, rest); err != nil {
+		return nil, "", fmt.Errorf("%w: %v", ErrNotEncrypted, err)
+	}
+	keyIDLen := int(fixed[len(magic)])
+	return append(fixed, rest...), string(rest[:keyIDLen]), nil
+}
+
+func headerLen(keyID string) int64 {
+	return int64(len(magic) + 1 + len(keyID) + noncePrefixSize)
+}
+
+// chunkNonce fills nonce with the header's nonce prefix and the chunk counter
+func chunkNonce(nonce []byte, header []byte, counter uint64) {
+	copy(nonce, header[len(header)-noncePrefixSize:])
+	binary.BigEndian.PutUint64(nonce[noncePrefixSize:], counter)
+}
+
+// adPath cleans an object path for the additional data
+func adPath(p string) string {
+	return strings.TrimPrefix(path.Clean("/"+p), "/")
+}
+
+// chunkAD builds the additional data of a chunk. objectPath must be cleaned by adPath
+func chunkAD(ad []byte, header []byte, objectPath string, final bool) []byte {
+	ad = append(ad[:0], header...)
+	ad = append(ad, objectPath...)
+	if final {
+		return append(ad, 1)
+	}
+	return append(ad, 0)
+}
+
+// readChunk reads up to len(buf) bytes and reports whether they are the last bytes of r.
+// r must be able to peek one byte ahead to tell a full last chunk from a full middle one.
+func readChunk(r peekReader, buf []byte) (int, bool, error) {
+	n, err := io.ReadFull(r, buf)
+	if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+		return n, true, nil
+	}
+	if err != nil {
+		return n, false, err
+	}
+	if _, err = r.Peek(1); errors.Is(err, io.EOF) {
+		return n, true, nil
+	} else if err != nil {
+		return n, false, err
+	}
+	return n, false, nil
+}
+
+type peekReader interface {
+	io.Reader
+	Peek(n int) ([]byte, error)
+}
+
+// encryptReader encrypts src while it is read
+type encryptReader struct {
+	src     peekReader
+	aead    cipher.AEAD
+	header  []byte
+	path    string // cleaned by adPath
+	counter uint64
+	plain   []byte
+	nonce   []byte
+	ad      []byte
+	buf     []byte // sealed chunk
+	out     []byte // pending part of buf
+	done    bool   // the final chunk is in buf
+}
+
+func (e *encryptReader) Read(p []byte) (int, error) {
+	for len(e.out) == 0 {
+		if e.done {
+			return 0, io.EOF
+		}
+		n, final, err := readChunk(e.src, e.plain)
+		if err != nil {
+			return 0, err
+		}
+		chunkNonce(e.nonce, e.header, e.counter)
+		e.counter++
+		e.buf = e.aead.Seal(e.buf[:0], e.nonce, e.plain[:n], chunkAD(e.ad, e.header, e.path, final))
+		e.out = e.buf
+		e.done = final
+	}
+	n := copy(p, e.out)
+	e.out = e.out[n:]
+	return n, nil
+}
+
+// decryptReader decrypts the chunks following the header
+type decryptReader struct {
+	src     peekReader
+	closer  io.Closer
+	aead    cipher.AEAD
+	header  []byte
+	path    string // cleaned by adPath
+	counter uint64
+	sealed  []byte
+	nonce   []byte
+	ad      []byte
+	buf     []byte // opened chunk
+	out     []byte // pending part of buf
+	done    bool
+}
+
+func (d *decryptReader) Read(p []byte) (int, error) {
+	for len(d.out) == 0 {
+		if d.done {
+			return 0, io.EOF
+		}
+		n, final, err := readChunk(d.src, d.sealed)
+		if err != nil {
+			return 0, err
+		}
+		chunkNonce(d.nonce, d.header, d.counter)
+		d.counter++
+		d.buf, err = d.aead.Open(d.buf[:0], d.nonce, d.sealed[:n], chunkAD(d.ad, d.header, d.path, final))
+		if err != nil {
+			return 0, ErrCorrupt
+		}
+		d.out = d.buf
+		d.done = final
+	}
+	n := copy(p, d.out)
+	d.out = d.out[n:]
+	return n, nil
+}
+
+func (d *decryptReader) Close() error {
+	return d.closer.Close()
+}
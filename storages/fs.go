@@ -0,0 +1,85 @@
+package storages
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/fs"
+	"path"
+	"strings"
+)
+
+// ErrReadOnly is matched by *ReadOnlyError via errors.Is
+var ErrReadOnly = errors.New("storages: read-only storage")
+
+// ReadOnlyError is returned by write methods of read-only storages
+type ReadOnlyError struct {
+	Op   string
+	Path string
+}
+
+func (e *ReadOnlyError) Error() string {
+	return "storages: " + e.Op + " " + e.Path + ": read-only storage"
+}
+
+func (e *ReadOnlyError) Is(target error) bool {
+	return target == ErrReadOnly
+}
+
+// FSStorage exposes an fs.FS (embed.FS, fstest.MapFS, os.DirFS, ...) as a read-only Storage.
+// Write methods return *ReadOnlyError.
+type FSStorage struct {
+	fsys fs.FS
+}
+
+func NewFSStorage(fsys fs.FS) *FSStorage {
+	return &FSStorage{fsys: fsys}
+}
+
+// name maps a storage path to a valid fs.FS name (unrooted, slash-separated)
+func (s *FSStorage) name(p string) string {
+	name := strings.TrimPrefix(path.Clean("/"+p), "/")
+	if name == "" {
+		return "."
+	}
+	return name
+}
+
+func (s *FSStorage) Exists(_ context.Context, path string) (bool, error) {
+	_, err := fs.Stat(s.fsys, s.name(path))
+	if err == nil {
+		return true, nil
+	}
+	if errors.Is(err, fs.ErrNotExist) {
+		return false, nil
+	}
+	return false, err
+}
+
+func (s *FSStorage) Get(_ context.Context, path string) (io.ReadCloser, error) {
+	return s.fsys.Open(s.name(path))
+}
+
+func (s *FSStorage) Put(_ context.Context, path string, _ io.Reader) error {
+	return &ReadOnlyError{Op: "put", Path: path}
+}
+
+func (s *FSStorage) Delete(_ context.Context, path string) error {
+	return &ReadOnlyError{Op: "delete", Path: path}
+}
+
+func (s *FSStorage) Size(_ context.Context, path string) (int64, error) {
+	info, err := fs.Stat(s.fsys, s.name(path))
+	if err != nil {
+		return 0, err
+	}
+	return info.Size(), nil
+}
+
+func (s *FSStorage) Copy(_ context.Context, _ string, dst string) error {
+	return &ReadOnlyError{Op: "copy", Path: dst}
+}
+
+func (s *FSStorage) Move(_ context.Context, src string, _ string) error {
+	return &ReadOnlyError{Op: "move", Path: src}
+}
@@ -0,0 +1,42 @@
+package imgvariants
+
+import (
+	"errors"
+	"image"
+	"io/fs"
+	"log"
+	"net/http"
+
+	"github.com/x64c/gw/errs"
+	"github.com/x64c/gw/web/responses"
+)
+
+// Handler serves variants, generating them on first request.
+// Mount it on a pattern with {variant} and {path...} wildcards. e.g. "GET /images/{variant}/{path...}"
+// Variants are not sources: paths inside a variants directory are not found, so variants do not pile up.
+func (p *Pipeline) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		variantName, srcPath := r.PathValue("variant"), r.PathValue("path")
+		if _, ok := p.variants[variantName]; !ok || srcPath == "" || isVariantPath(srcPath) {
+			responses.WriteErrorJSON(w, http.StatusNotFound, errs.ResourceNotFound)
+			return
+		}
+		dstPath, err := p.Ensure(r.Context(), srcPath, variantName)
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) || errors.Is(err, errs.InvalidStoragePath) {
+				responses.WriteErrorJSON(w, http.StatusNotFound, errs.ResourceNotFound)
+				return
+			}
+			if errors.Is(err, ErrSourceTooLarge) || errors.Is(err, image.ErrFormat) {
+				responses.WriteErrorJSON(w, http.StatusUnprocessableEntity, errs.ResourceUnavailable.WithDetail(err.Error()))
+				return
+			}
+			log.Printf("[ERROR][ImgVariants] %s %q: %v", variantName, srcPath, err)
+			responses.WriteErrorJSON(w, http.StatusInternalServerError, errs.Storage)
+			return
+		}
+		// a variant is generated once per source path, so it is as stable as the path
+		w.Header().Set("Cache-Control", "public, max-age=86400")
+		responses.ServeStorageObject(w, r, p.Storage, dstPath)
+	})
+}
@@ -0,0 +1,56 @@
+package imgvariants
+
+import (
+	"bytes"
+	"context"
+	"image"
+	"image/png"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/x64c/gw/storages/memstorage"
+)
+
+func newTestHandler(t *testing.T) (http.Handler, *Pipeline) {
+	st := memstorage.New()
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, image.NewRGBA(image.Rect(0, 0, 40, 20))); err != nil {
+		t.Fatal(err)
+	}
+	if err := st.Put(context.Background(), "avatars/u1.png", &buf); err != nil {
+		t.Fatal(err)
+	}
+	p := New(st, Variant{Name: "thumb", MaxWidth: 10, Format: FormatJPEG})
+	mux := http.NewServeMux()
+	mux.Handle("GET /images/{variant}/{path...}", p.Handler())
+	return mux, p
+}
+
+func TestHandler(t *testing.T) {
+	h, p := newTestHandler(t)
+	tests := []struct {
+		target string
+		want   int
+	}{
+		{"/images/thumb/avatars/u1.png", http.StatusOK},
+		{"/images/thumb/avatars/missing.png", http.StatusNotFound},
+		{"/images/large/avatars/u1.png", http.StatusNotFound},
+		{"/images/thumb/avatars/_variants/thumb/u1.png.jpg", http.StatusNotFound}, // a variant of a variant
+		{"/images/thumb/_variants/u2.png", http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, tt.target, nil))
+		if rec.Code != tt.want {
+			t.Errorf("%s: status %d, want %d", tt.target, rec.Code, tt.want)
+		}
+	}
+	ctx := context.Background()
+	if ok, _ := p.Storage.Exists(ctx, "avatars/_variants/thumb/u1.png.jpg"); !ok {
+		t.Fatal("variant not stored")
+	}
+	if ok, _ := p.Storage.Exists(ctx, "avatars/_variants/thumb/_variants/thumb/u1.png.jpg.jpg"); ok {
+		t.Fatal("variant of a variant stored")
+	}
+}
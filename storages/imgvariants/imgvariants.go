@@ -0,0 +1,209 @@
+// Package imgvariants produces resized variants of images stored in a storages.Storage.
+// Only the standard library image packages are used: JPEG, PNG and GIF sources, JPEG and PNG variants.
+// Variants are stored next to their source under a deterministic path and generated once.
+package imgvariants
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"image"
+	_ "image/gif" // source format
+	"image/jpeg"
+	"image/png"
+	"io"
+	"path"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/x64c/gw/storages"
+)
+
+const (
+	FormatJPEG = "jpeg"
+	FormatPNG  = "png"
+
+	variantsDir = "_variants" // under the source's directory
+
+	defaultJPEGQuality    = 85
+	defaultMaxSourceBytes = 32 << 20
+	defaultMaxSourcePixel = 40_000_000
+)
+
+var (
+	ErrUnknownVariant = errors.New("imgvariants: unknown variant")
+	ErrSourceTooLarge = errors.New("imgvariants: source image too large")
+)
+
+// Variant is a named output spec
+type Variant struct {
+	Name      string // e.g. "thumb"
+	MaxWidth  int    // px. 0 = unbounded
+	MaxHeight int    // px. 0 = unbounded
+	Format    string // FormatJPEG or FormatPNG. "" = PNG for .png/.gif sources, JPEG otherwise
+	Quality   int    // JPEG quality 1-100. default 85
+}
+
+// Pipeline generates variants of objects in a storage
+type Pipeline struct {
+	Storage        storages.Storage
+	MaxSourceBytes int64 // default 32MiB
+	MaxSourcePixel int   // max width*height of a source. default 40M. guards against decompression bombs
+
+	variants map[string]Variant
+	mu       sync.Mutex
+	inflight map[string]*sync.WaitGroup // variant path -> generation in progress
+}
+
+func New(st storages.Storage, variants ...Variant) *Pipeline {
+	p := &Pipeline{
+		Storage:        st,
+		MaxSourceBytes: defaultMaxSourceBytes,
+		MaxSourcePixel: defaultMaxSourcePixel,
+		variants:       make(map[string]Variant, len(variants)),
+		inflight:       make(map[string]*sync.WaitGroup),
+	}
+	for _, v := range variants {
+		p.variants[v.Name] = v
+	}
+	return p
+}
+
+func (p *Pipeline) Variant(name string) (Variant, bool) {
+	v, ok := p.variants[name]
+	return v, ok
+}
+
+// VariantPath returns where the variant of a source is stored. The extension always matches the output format.
+// "avatars/u1.png" with "thumb" (JPEG) -> "avatars/_variants/thumb/u1.png.jpg"
+// "avatars/u1.png" with "small" (default format) -> "avatars/_variants/small/u1.png"
+func (p *Pipeline) VariantPath(srcPath string, variantName string) (string, error) {
+	v, ok := p.variants[variantName]
+	if !ok {
+		return "", fmt.Errorf("%w: %q", ErrUnknownVariant, variantName)
+	}
+	dir, name := path.Split(srcPath)
+	ext := strings.ToLower(path.Ext(name))
+	switch outputFormat(v, srcPath) {
+	case FormatJPEG:
+		if ext != ".jpg" && ext != ".jpeg" {
+			name += ".jpg"
+		}
+	case FormatPNG:
+		if ext != ".png" {
+			name += ".png"
+		}
+	}
+	return path.Join(dir, variantsDir, v.Name, name), nil
+}
+
+// isVariantPath reports whether a path is inside a variants directory
+func isVariantPath(p string) bool {
+	return slices.Contains(strings.Split(path.Clean(p), "/"), variantsDir)
+}
+
+// outputFormat resolves the format of a variant of a source. It depends on the path only, so VariantPath needs no read
+func outputFormat(v Variant, srcPath string) string {
+	if v.Format != "" {
+		return v.Format
+	}
+	switch strings.ToLower(path.Ext(srcPath)) {
+	case ".png", ".gif":
+		return FormatPNG // keep transparency
+	}
+	return FormatJPEG
+}
+
+// Ensure returns the variant path, generating the variant if it is not stored yet.
+// Concurrent calls for the same variant share one generation.
+func (p *Pipeline) Ensure(ctx context.Context, srcPath string, variantName string) (string, error) {
+	dstPath, err := p.VariantPath(srcPath, variantName)
+	if err != nil {
+		return "", err
+	}
+	if exists, err := p.Storage.Exists(ctx, dstPath); err != nil {
+		return "", err
+	} else if exists {
+		return dstPath, nil
+	}
+
+	p.mu.Lock()
+	if wg, ok := p.inflight[dstPath]; ok {
+		p.mu.Unlock()
+		wg.Wait()
+		// the generator may have failed. re-check rather than sharing its error
+		if exists, err := p.Storage.Exists(ctx, dstPath); err != nil || !exists {
+			return "", errors.Join(errors.New("imgvariants: concurrent generation failed"), err)
+		}
+		return dstPath, nil
+	}
+	wg := &sync.WaitGroup{}
+	wg.Add(1)
+	p.inflight[dstPath] = wg
+	p.mu.Unlock()
+	defer func() {
+		p.mu.Lock()
+		delete(p.inflight, dstPath)
+		p.mu.Unlock()
+		wg.Done()
+	}()
+
+	if err = p.Generate(ctx, srcPath, variantName, dstPath); err != nil {
+		return "", err
+	}
+	return dstPath, nil
+}
+
+// Generate decodes the source, resizes it and stores the variant at dstPath (overwriting)
+func (p *Pipeline) Generate(ctx context.Context, srcPath string, variantName string, dstPath string) error {
+	v, ok := p.variants[variantName]
+	if !ok {
+		return fmt.Errorf("%w: %q", ErrUnknownVariant, variantName)
+	}
+	rc, err := p.Storage.Get(ctx, srcPath)
+	if err != nil {
+		return err
+	}
+	data, err := io.ReadAll(io.LimitReader(rc, p.MaxSourceBytes+1))
+	_ = rc.Close()
+	if err != nil {
+		return err
+	}
+	if int64(len(data)) > p.MaxSourceBytes {
+		return ErrSourceTooLarge
+	}
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return fmt.Errorf("imgvariants: %w", err)
+	}
+	if cfg.Width*cfg.Height > p.MaxSourcePixel {
+		return ErrSourceTooLarge
+	}
+	src, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return fmt.Errorf("imgvariants: %w", err)
+	}
+
+	w, h := fitSize(cfg.Width, cfg.Height, v.MaxWidth, v.MaxHeight)
+	dst := resize(src, w, h)
+
+	var buf bytes.Buffer
+	switch format := outputFormat(v, srcPath); format {
+	case FormatJPEG:
+		quality := v.Quality
+		if quality <= 0 || quality > 100 {
+			quality = defaultJPEGQuality
+		}
+		err = jpeg.Encode(&buf, dst, &jpeg.Options{Quality: quality})
+	case FormatPNG:
+		err = (&png.Encoder{CompressionLevel: png.BestCompression}).Encode(&buf, dst)
+	default:
+		err = fmt.Errorf("imgvariants: unsupported format %q", format)
+	}
+	if err != nil {
+		return err
+	}
+	return p.Storage.Put(ctx, dstPath, &buf)
+}
@@ -0,0 +1,106 @@
+package imgvariants
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+)
+
+// fitSize scales w x h down to fit maxW x maxH keeping the aspect ratio. 0 = unbounded. Never scales up
+func fitSize(w, h, maxW, maxH int) (int, int) {
+	scale := 1.0
+	if maxW > 0 && w > maxW {
+		scale = float64(maxW) / float64(w)
+	}
+	if maxH > 0 && h > maxH {
+		scale = min(scale, float64(maxH)/float64(h))
+	}
+	if scale == 1.0 {
+		return w, h
+	}
+	return max(int(float64(w)*scale+0.5), 1), max(int(float64(h)*scale+0.5), 1)
+}
+
+// resize downscales src to dstW x dstH by area averaging (box filter) in premultiplied RGBA.
+// Each destination pixel is the coverage-weighted mean of the source pixels under it.
+func resize(src image.Image, dstW, dstH int) *image.RGBA {
+	b := src.Bounds()
+	srcW, srcH := b.Dx(), b.Dy()
+	// normalize to RGBA once so the inner loop avoids the color.Color interface
+	rgba, ok := src.(*image.RGBA)
+	if !ok || rgba.Rect.Min != (image.Point{}) {
+		rgba = image.NewRGBA(image.Rect(0, 0, srcW, srcH))
+		draw.Draw(rgba, rgba.Rect, src, b.Min, draw.Src)
+	}
+	dst := image.NewRGBA(image.Rect(0, 0, dstW, dstH))
+	if srcW == dstW && srcH == dstH {
+		copy(dst.Pix, rgba.Pix)
+		return dst
+	}
+	xSpans := boxSpans(srcW, dstW)
+	ySpans := boxSpans(srcH, dstH)
+	row := make([][4]float64, dstW) // horizontal sums of the current source row
+	acc := make([][4]float64, dstW) // vertical accumulation of the current destination row
+	for dy, ySpan := range ySpans {
+		clear(acc)
+		for _, yw := range ySpan {
+			clear(row)
+			off := yw.i * rgba.Stride
+			for dx, xSpan := range xSpans {
+				for _, xw := range xSpan {
+					j := off + xw.i*4
+					p := rgba.Pix[j : j+4]
+					row[dx][0] += float64(p[0]) * xw.w
+					row[dx][1] += float64(p[1]) * xw.w
+					row[dx][2] += float64(p[2]) * xw.w
+					row[dx][3] += float64(p[3]) * xw.w
+				}
+			}
+			for dx := range acc {
+				for c := range 4 {
+					acc[dx][c] += row[dx][c] * yw.w
+				}
+			}
+		}
+		for dx := range acc {
+			dst.SetRGBA(dx, dy, color.RGBA{
+				R: clamp8(acc[dx][0]),
+				G: clamp8(acc[dx][1]),
+				B: clamp8(acc[dx][2]),
+				A: clamp8(acc[dx][3]),
+			})
+		}
+	}
+	return dst
+}
+
+type weight struct {
+	i int     // source index
+	w float64 // coverage share. sums to 1 per destination index
+}
+
+// boxSpans returns, per destination index, the source indexes it covers with their weights
+func boxSpans(srcN, dstN int) [][]weight {
+	scale := float64(srcN) / float64(dstN)
+	spans := make([][]weight, dstN)
+	for d := range dstN {
+		start, end := float64(d)*scale, float64(d+1)*scale
+		for s := int(start); s < srcN && float64(s) < end; s++ {
+			cover := min(end, float64(s+1)) - max(start, float64(s))
+			if cover > 0 {
+				spans[d] = append(spans[d], weight{i: s, w: cover / scale})
+			}
+		}
+	}
+	return spans
+}
+
+func clamp8(v float64) uint8 {
+	if v <= 0 {
+		return 0
+	}
+	if v >= 255 {
+		return 255
+	}
+	return uint8(v + 0.5)
+}
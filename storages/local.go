@@ -2,89 +2,450 @@ package storages
 
 import (
 	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
 	"io"
+	"io/fs"
+	"mime"
 	"os"
+	"path"
 	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/x64c/gw/errs"
+)
+
+const (
+	defaultLocalFilePerm fs.FileMode = 0644
+	defaultLocalDirPerm  fs.FileMode = 0755
 )
 
+// LocalStorageConf is the per-storage config in .storages.json under the "local" client
+//
+//	{ "local": { "uploads": { "root": "/var/app/uploads", "file_perm": "0640", "dir_perm": "0750" } } }
+type LocalStorageConf struct {
+	Root     string `json:"root"`
+	FilePerm string `json:"file_perm"` // octal. default "0644"
+	DirPerm  string `json:"dir_perm"`  // octal. default "0755"
+}
+
 // LocalStorage implements Storage for local disk.
+// All access is confined to the root directory through os.Root, so neither "../" paths nor symlinks escape it.
+// Put and Copy write to a temp file, fsync it and rename it into place, so readers never see partial files.
+//
+// Errors are *errs.Error (StorageObjectNotFound, InvalidStoragePath, Storage) wrapping the os error,
+// so errors.Is(err, fs.ErrNotExist) keeps working.
 type LocalStorage struct {
-	root string
+	root     string
+	dir      *os.Root
+	openErr  error // NewLocalStorage failed to open the root. returned by every op
+	filePerm fs.FileMode
+	dirPerm  fs.FileMode
 }
 
+// NewLocalStorage opens the root directory with the default permissions, creating it if missing.
+// A root that cannot be opened makes every op fail with the error. Use OpenLocalStorage to get it up front
 func NewLocalStorage(root string) *LocalStorage {
-	return &LocalStorage{root: root}
+	s, err := OpenLocalStorage(LocalStorageConf{Root: root})
+	if err != nil {
+		return &LocalStorage{root: root, openErr: errs.Storage.Wrap(err)}
+	}
+	return s
+}
+
+// OpenLocalStorage opens the root directory of the conf, creating it if missing
+func OpenLocalStorage(conf LocalStorageConf) (*LocalStorage, error) {
+	if conf.Root == "" {
+		return nil, errors.New("root is required")
+	}
+	filePerm, err := parsePerm(conf.FilePerm, defaultLocalFilePerm)
+	if err != nil {
+		return nil, fmt.Errorf("file_perm: %w", err)
+	}
+	dirPerm, err := parsePerm(conf.DirPerm, defaultLocalDirPerm)
+	if err != nil {
+		return nil, fmt.Errorf("dir_perm: %w", err)
+	}
+	if err = os.MkdirAll(conf.Root, dirPerm); err != nil {
+		return nil, err
+	}
+	dir, err := os.OpenRoot(conf.Root)
+	if err != nil {
+		return nil, err
+	}
+	return &LocalStorage{
+		root:     conf.Root,
+		dir:      dir,
+		filePerm: filePerm,
+		dirPerm:  dirPerm,
+	}, nil
+}
+
+func parsePerm(s string, defaultPerm fs.FileMode) (fs.FileMode, error) {
+	if s == "" {
+		return defaultPerm, nil
+	}
+	perm, err := strconv.ParseUint(s, 8, 32)
+	if err != nil || perm > 0777 {
+		return 0, fmt.Errorf("invalid permission %q", s)
+	}
+	return fs.FileMode(perm), nil
+}
+
+// Close releases the root directory handle
+func (s *LocalStorage) Close() error {
+	if s.openErr != nil {
+		return nil
+	}
+	return s.dir.Close()
+}
+
+// name maps a storage path to a local name under the root. A leading "/" is relative to the root.
+// Paths escaping the root ("../x") are rejected.
+func (s *LocalStorage) name(p string) (string, error) {
+	if s.openErr != nil {
+		return "", s.openErr
+	}
+	name := filepath.FromSlash(strings.TrimLeft(p, "/"))
+	if name == "" {
+		name = "."
+	}
+	if !filepath.IsLocal(name) {
+		return "", errs.InvalidStoragePath.WithDetail(p)
+	}
+	return filepath.Clean(name), nil
 }
 
-func (s *LocalStorage) path(p string) string {
-	return filepath.Join(s.root, p)
+// mapErr maps os errors into errs codes keeping the cause
+func mapErr(err error) error {
+	if err == nil {
+		return nil
+	}
+	if _, ok := errors.AsType[*errs.Error](err); ok {
+		return err
+	}
+	if errors.Is(err, fs.ErrNotExist) {
+		return errs.StorageObjectNotFound.Wrap(err)
+	}
+	return errs.Storage.Wrap(err)
 }
 
 func (s *LocalStorage) Exists(_ context.Context, path string) (bool, error) {
-	_, err := os.Stat(s.path(path))
+	name, err := s.name(path)
+	if err != nil {
+		return false, err
+	}
+	_, err = s.dir.Stat(name)
 	if err == nil {
 		return true, nil
 	}
-	if os.IsNotExist(err) {
+	if errors.Is(err, fs.ErrNotExist) {
 		return false, nil
 	}
-	return false, err
+	return false, mapErr(err)
 }
 
 func (s *LocalStorage) Get(_ context.Context, path string) (io.ReadCloser, error) {
-	return os.Open(s.path(path))
+	name, err := s.name(path)
+	if err != nil {
+		return nil, err
+	}
+	f, err := s.dir.Open(name)
+	if err != nil {
+		return nil, mapErr(err)
+	}
+	return f, nil
 }
 
 func (s *LocalStorage) Put(_ context.Context, path string, r io.Reader) error {
-	full := s.path(path)
-	if err := os.MkdirAll(filepath.Dir(full), 0755); err != nil {
+	name, err := s.name(path)
+	if err != nil {
 		return err
 	}
-	f, err := os.Create(full)
+	return mapErr(s.writeAtomic(name, r))
+}
+
+// writeAtomic writes r to a temp file next to name, fsyncs it and renames it over name
+func (s *LocalStorage) writeAtomic(name string, r io.Reader) (err error) {
+	dir := filepath.Dir(name)
+	if err = s.dir.MkdirAll(dir, s.dirPerm); err != nil {
+		return err
+	}
+	tmpName := filepath.Join(dir, "."+filepath.Base(name)+".tmp-"+rand.Text())
+	tmp, err := s.dir.OpenFile(tmpName, os.O_WRONLY|os.O_CREATE|os.O_EXCL, s.filePerm)
 	if err != nil {
 		return err
 	}
-	defer func() { _ = f.Close() }()
-	_, err = io.Copy(f, r)
-	return err
+	defer func() {
+		if err != nil {
+			_ = tmp.Close()
+			_ = s.dir.Remove(tmpName)
+		}
+	}()
+	if _, err = io.Copy(tmp, r); err != nil {
+		return err
+	}
+	if err = tmp.Sync(); err != nil {
+		return err
+	}
+	if err = tmp.Close(); err != nil {
+		return err
+	}
+	if err = s.dir.Rename(tmpName, name); err != nil {
+		return err
+	}
+	s.syncDir(dir)
+	return nil
+}
+
+// syncDir persists a rename in the directory entry. Best effort (not supported on every platform)
+func (s *LocalStorage) syncDir(dir string) {
+	d, err := s.dir.Open(dir)
+	if err != nil {
+		return
+	}
+	_ = d.Sync()
+	_ = d.Close()
 }
 
 func (s *LocalStorage) Delete(_ context.Context, path string) error {
-	return os.Remove(s.path(path))
+	name, err := s.name(path)
+	if err != nil {
+		return err
+	}
+	return mapErr(s.dir.Remove(name))
 }
 
 func (s *LocalStorage) Size(_ context.Context, path string) (int64, error) {
-	info, err := os.Stat(s.path(path))
+	name, err := s.name(path)
 	if err != nil {
 		return 0, err
 	}
+	info, err := s.dir.Stat(name)
+	if err != nil {
+		return 0, mapErr(err)
+	}
 	return info.Size(), nil
 }
 
 func (s *LocalStorage) Copy(_ context.Context, src string, dst string) error {
-	srcFile, err := os.Open(s.path(src))
+	srcName, err := s.name(src)
 	if err != nil {
 		return err
 	}
+	dstName, err := s.name(dst)
+	if err != nil {
+		return err
+	}
+	srcFile, err := s.dir.Open(srcName)
+	if err != nil {
+		return mapErr(err)
+	}
 	defer func() { _ = srcFile.Close() }()
-	dstFull := s.path(dst)
-	if err = os.MkdirAll(filepath.Dir(dstFull), 0755); err != nil {
+	return mapErr(s.writeAtomic(dstName, srcFile))
+}
+
+func (s *LocalStorage) Move(_ context.Context, src string, dst string) error {
+	srcName, err := s.name(src)
+	if err != nil {
 		return err
 	}
-	dstFile, err := os.Create(dstFull)
+	dstName, err := s.name(dst)
 	if err != nil {
 		return err
 	}
-	defer func() { _ = dstFile.Close() }()
-	_, err = io.Copy(dstFile, srcFile)
+	if err = s.dir.MkdirAll(filepath.Dir(dstName), s.dirPerm); err != nil {
+		return mapErr(err)
+	}
+	return mapErr(s.dir.Rename(srcName, dstName))
+}
+
+const defaultListLimit = 1000
+
+func (s *LocalStorage) Stat(_ context.Context, path string) (*ObjectInfo, error) {
+	name, err := s.name(path)
+	if err != nil {
+		return nil, err
+	}
+	info, err := s.dir.Stat(name)
+	if err != nil {
+		return nil, mapErr(err)
+	}
+	objInfo := fileObjectInfo(path, info)
+	return &objInfo, nil
+}
+
+// fileObjectInfo builds ObjectInfo from file info. ETag is derived from mtime and size
+func fileObjectInfo(path string, info fs.FileInfo) ObjectInfo {
+	return ObjectInfo{
+		Path:        path,
+		Size:        info.Size(),
+		ModTime:     info.ModTime(),
+		ContentType: mime.TypeByExtension(filepath.Ext(path)),
+		ETag:        fmt.Sprintf(`"%x-%x"`, info.ModTime().UnixNano(), info.Size()),
+	}
+}
+
+func (s *LocalStorage) GetRange(_ context.Context, path string, offset int64, length int64) (io.ReadCloser, error) {
+	name, err := s.name(path)
+	if err != nil {
+		return nil, err
+	}
+	f, err := s.dir.Open(name)
+	if err != nil {
+		return nil, mapErr(err)
+	}
+	if _, err = f.Seek(offset, io.SeekStart); err != nil {
+		_ = f.Close()
+		return nil, mapErr(err)
+	}
+	if length < 0 {
+		return f, nil
+	}
+	return struct {
+		io.Reader
+		io.Closer
+	}{io.LimitReader(f, length), f}, nil
+}
+
+// List walks the directory tree under the prefix in path order, so a page stops once it is full.
+// Directories entirely at or before the cursor, or rolled up under a delimiter, are skipped without being read.
+// The cursor is the last path or common prefix returned. In-flight temp files of Put/Copy are skipped.
+func (s *LocalStorage) List(_ context.Context, opts ListOptions) (*ListPage, error) {
+	limit := opts.Limit
+	if limit <= 0 {
+		limit = defaultListLimit
+	}
+	prefix := strings.TrimLeft(opts.Prefix, "/")
+	// walk from the deepest directory the prefix pins down
+	walkDir := "."
+	if i := strings.LastIndex(prefix, "/"); i >= 0 {
+		walkDir = prefix[:i]
+	}
+	if _, err := s.name(walkDir); err != nil {
+		return nil, err
+	}
+	page := &ListPage{}
+	cnt := 0
+	lastPrefix := ""
+	// add appends an entry, or ends the walk with the next cursor once the page is full
+	add := func(name string, info fs.FileInfo) error {
+		if cnt == limit {
+			page.NextCursor = page.lastName()
+			return fs.SkipAll
+		}
+		cnt++
+		if info == nil {
+			page.CommonPrefixes = append(page.CommonPrefixes, name)
+			lastPrefix = name
+			return nil
+		}
+		page.Objects = append(page.Objects, fileObjectInfo(name, info))
+		return nil
+	}
+	err := s.walkSorted(walkDir, func(p string, d fs.DirEntry) error {
+		if d.IsDir() {
+			dirPrefix := p + "/" // every path under the directory starts with it
+			if !strings.HasPrefix(dirPrefix, prefix) {
+				if strings.HasPrefix(prefix, dirPrefix) {
+					return nil // the prefix goes deeper
+				}
+				return fs.SkipDir
+			}
+			if opts.Cursor >= dirPrefix && !strings.HasPrefix(opts.Cursor, dirPrefix) {
+				return fs.SkipDir // all at or before the cursor
+			}
+			if opts.Delimiter == "" {
+				return nil
+			}
+			i := strings.Index(dirPrefix[len(prefix):], opts.Delimiter)
+			if i < 0 {
+				return nil
+			}
+			// everything under the directory rolls up into one common prefix
+			commonPrefix := dirPrefix[:len(prefix)+i+len(opts.Delimiter)]
+			if commonPrefix != lastPrefix && commonPrefix > opts.Cursor {
+				if err := add(commonPrefix, nil); err != nil {
+					return err
+				}
+			}
+			return fs.SkipDir
+		}
+		if isTempName(path.Base(p)) || !strings.HasPrefix(p, prefix) {
+			return nil
+		}
+		if opts.Delimiter != "" {
+			if i := strings.Index(p[len(prefix):], opts.Delimiter); i >= 0 {
+				commonPrefix := p[:len(prefix)+i+len(opts.Delimiter)]
+				if commonPrefix == lastPrefix || commonPrefix <= opts.Cursor {
+					return nil
+				}
+				return add(commonPrefix, nil)
+			}
+		}
+		if p <= opts.Cursor {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		return add(p, info)
+	})
+	if err != nil {
+		return nil, mapErr(err)
+	}
+	return page, nil
+}
+
+// walkSorted walks the tree under dir like fs.WalkDir, but in lexical order of the full paths.
+// A directory sorts as its name + "/", as its contents do (e.g. "a.txt" < "a/b" although "a" < "a.txt").
+// fn may return fs.SkipDir for a directory, or fs.SkipAll. A missing dir is an empty tree.
+func (s *LocalStorage) walkSorted(dir string, fn func(p string, d fs.DirEntry) error) error {
+	err := s.walkSortedDir(dir, fn)
+	if errors.Is(err, fs.SkipAll) {
+		return nil
+	}
 	return err
 }
 
-func (s *LocalStorage) Move(_ context.Context, src string, dst string) error {
-	dstFull := s.path(dst)
-	if err := os.MkdirAll(filepath.Dir(dstFull), 0755); err != nil {
+func (s *LocalStorage) walkSortedDir(dir string, fn func(p string, d fs.DirEntry) error) error {
+	entries, err := fs.ReadDir(s.dir.FS(), dir)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil
+		}
 		return err
 	}
-	return os.Rename(s.path(src), dstFull)
+	sortKey := func(d fs.DirEntry) string {
+		if d.IsDir() {
+			return d.Name() + "/"
+		}
+		return d.Name()
+	}
+	slices.SortFunc(entries, func(a, b fs.DirEntry) int { return strings.Compare(sortKey(a), sortKey(b)) })
+	for _, d := range entries {
+		p := path.Join(dir, d.Name())
+		err = fn(p, d)
+		if errors.Is(err, fs.SkipDir) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if err = s.walkSortedDir(p, fn); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
 }
 
+// isTempName reports whether a base name is a temp file of writeAtomic
+func isTempName(base string) bool {
+	return strings.HasPrefix(base, ".") && strings.Contains(base, ".tmp-")
+}
@@ -0,0 +1,136 @@
+package storages
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/x64c/gw/errs"
+)
+
+var listTestPaths = []string{
+	"a.txt", "a/b.txt", "a/c/d.txt", "a-b/e.txt", "a0.txt",
+	"docs/1.txt", "docs/2.txt", "docs/sub/3.txt", "docs/sub/deep/4.txt", "docs-old.txt",
+	"z/y/x.txt",
+}
+
+func newTestLocalStorage(t *testing.T, paths []string) *LocalStorage {
+	s, err := OpenLocalStorage(LocalStorageConf{Root: t.TempDir()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = s.Close() })
+	for _, p := range paths {
+		if err = s.Put(context.Background(), p, strings.NewReader(p)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return s
+}
+
+// listPaged pages through List, returning the object paths and common prefixes in order
+func listPaged(t *testing.T, s *LocalStorage, opts ListOptions) []string {
+	var names []string
+	for range 100 {
+		page, err := s.List(context.Background(), opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var pageNames []string
+		for _, o := range page.Objects {
+			pageNames = append(pageNames, o.Path)
+		}
+		pageNames = append(pageNames, page.CommonPrefixes...)
+		slices.Sort(pageNames)
+		if opts.Limit > 0 && len(pageNames) > opts.Limit {
+			t.Fatalf("page of %d > limit %d", len(pageNames), opts.Limit)
+		}
+		names = append(names, pageNames...)
+		if page.NextCursor == "" {
+			return names
+		}
+		opts.Cursor = page.NextCursor
+	}
+	t.Fatal("paging did not end")
+	return nil
+}
+
+// listReference lists the paths by brute force
+func listReference(paths []string, prefix string, delimiter string) []string {
+	var names []string
+	for _, p := range paths {
+		if !strings.HasPrefix(p, prefix) {
+			continue
+		}
+		if delimiter != "" {
+			if i := strings.Index(p[len(prefix):], delimiter); i >= 0 {
+				p = p[:len(prefix)+i+len(delimiter)]
+			}
+		}
+		names = append(names, p)
+	}
+	slices.Sort(names)
+	return slices.Compact(names)
+}
+
+func TestLocalStorageList(t *testing.T) {
+	s := newTestLocalStorage(t, listTestPaths)
+	for _, prefix := range []string{"", "a", "a/", "docs", "docs/", "docs/sub/", "docs/s", "missing/", "z/y/"} {
+		for _, delimiter := range []string{"", "/", "-", ".txt"} {
+			want := listReference(listTestPaths, prefix, delimiter)
+			for _, limit := range []int{0, 1, 2, 3} {
+				got := listPaged(t, s, ListOptions{Prefix: prefix, Delimiter: delimiter, Limit: limit})
+				if !slices.Equal(got, want) {
+					t.Errorf("prefix %q delimiter %q limit %d:\n got %v\nwant %v", prefix, delimiter, limit, got, want)
+				}
+			}
+		}
+	}
+}
+
+func TestLocalStorageListSkipsTempFiles(t *testing.T) {
+	s := newTestLocalStorage(t, []string{"a.txt"})
+	f, err := s.dir.Create(".a.txt.tmp-123")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = f.Close()
+	if got := listPaged(t, s, ListOptions{}); !slices.Equal(got, []string{"a.txt"}) {
+		t.Fatalf("got %v", got)
+	}
+}
+
+func TestLocalStorageInvalidPath(t *testing.T) {
+	s := newTestLocalStorage(t, nil)
+	_, err := s.Get(context.Background(), "../escape")
+	if !errors.Is(err, errs.InvalidStoragePath) {
+		t.Fatalf("Get ../escape: %v", err)
+	}
+	if _, err = s.Stat(context.Background(), "missing"); !errors.Is(err, fs.ErrNotExist) {
+		t.Fatalf("Stat missing: %v", err)
+	}
+}
+
+func TestNewLocalStorage(t *testing.T) {
+	root := t.TempDir() + "/new"
+	s := NewLocalStorage(root)
+	defer func() { _ = s.Close() }()
+	if err := s.Put(context.Background(), "x/y.txt", strings.NewReader("y")); err != nil {
+		t.Fatal(err)
+	}
+	if size, err := s.Size(context.Background(), "x/y.txt"); err != nil || size != 1 {
+		t.Fatalf("Size = %d, %v", size, err)
+	}
+
+	// a file in place of the root: every op reports the open error
+	broken := NewLocalStorage(root + "/x/y.txt")
+	if _, err := broken.Exists(context.Background(), "a"); !errors.Is(err, errs.Storage) {
+		t.Fatalf("Exists on a broken root: %v", err)
+	}
+	if _, err := broken.List(context.Background(), ListOptions{}); !errors.Is(err, errs.Storage) {
+		t.Fatalf("List on a broken root: %v", err)
+	}
+}
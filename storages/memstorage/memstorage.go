@@ -0,0 +1,105 @@
+// Package memstorage provides an in-memory storages.Storage.
+// Contents live in the process memory and vanish on restart. Intended for tests, scratch data and small caches.
+package memstorage
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"io/fs"
+	"path"
+	"strings"
+	"sync"
+)
+
+// MemStorage implements storages.Storage in memory
+type MemStorage struct {
+	mu      sync.RWMutex
+	objects map[string][]byte // cleaned path -> content
+}
+
+func New() *MemStorage {
+	return &MemStorage{objects: make(map[string][]byte)}
+}
+
+func cleanPath(p string) string {
+	return strings.TrimPrefix(path.Clean("/"+p), "/")
+}
+
+func notExist(op string, p string) error {
+	return &fs.PathError{Op: op, Path: p, Err: fs.ErrNotExist}
+}
+
+func (s *MemStorage) Exists(_ context.Context, path string) (bool, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	_, ok := s.objects[cleanPath(path)]
+	return ok, nil
+}
+
+func (s *MemStorage) Get(_ context.Context, path string) (io.ReadCloser, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	content, ok := s.objects[cleanPath(path)]
+	if !ok {
+		return nil, notExist("open", path)
+	}
+	// contents are never mutated in place (Put replaces the slice), so sharing is safe
+	return io.NopCloser(bytes.NewReader(content)), nil
+}
+
+func (s *MemStorage) Put(_ context.Context, path string, r io.Reader) error {
+	content, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.objects[cleanPath(path)] = content
+	return nil
+}
+
+func (s *MemStorage) Delete(_ context.Context, path string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	key := cleanPath(path)
+	if _, ok := s.objects[key]; !ok {
+		return notExist("remove", path)
+	}
+	delete(s.objects, key)
+	return nil
+}
+
+func (s *MemStorage) Size(_ context.Context, path string) (int64, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	content, ok := s.objects[cleanPath(path)]
+	if !ok {
+		return 0, notExist("stat", path)
+	}
+	return int64(len(content)), nil
+}
+
+func (s *MemStorage) Copy(_ context.Context, src string, dst string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	content, ok := s.objects[cleanPath(src)]
+	if !ok {
+		return notExist("open", src)
+	}
+	s.objects[cleanPath(dst)] = content
+	return nil
+}
+
+func (s *MemStorage) Move(_ context.Context, src string, dst string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	srcKey := cleanPath(src)
+	content, ok := s.objects[srcKey]
+	if !ok {
+		return notExist("rename", src)
+	}
+	delete(s.objects, srcKey)
+	s.objects[cleanPath(dst)] = content
+	return nil
+}
@@ -0,0 +1,58 @@
+package mirrorstorage
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Failure is a write that did not reach a backend. The backend diverges on Path until repaired
+type Failure struct {
+	Path    string
+	Backend string
+	Op      string // put, delete, copy, move
+	Time    time.Time
+	Err     string
+}
+
+// FailureLog records failed replications for Repair
+type FailureLog interface {
+	Record(ctx context.Context, f Failure) error
+	Pending(ctx context.Context) ([]Failure, error)
+	// Resolve removes the failures of the backend on the path
+	Resolve(ctx context.Context, backend string, path string) error
+}
+
+// MemFailureLog is an in-process FailureLog. Failures are lost on restart
+type MemFailureLog struct {
+	mu       sync.Mutex
+	failures map[[2]string]Failure // [backend, path] -> latest failure
+}
+
+func NewMemFailureLog() *MemFailureLog {
+	return &MemFailureLog{failures: make(map[[2]string]Failure)}
+}
+
+func (l *MemFailureLog) Record(_ context.Context, f Failure) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.failures[[2]string{f.Backend, f.Path}] = f
+	return nil
+}
+
+func (l *MemFailureLog) Pending(_ context.Context) ([]Failure, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	failures := make([]Failure, 0, len(l.failures))
+	for _, f := range l.failures {
+		failures = append(failures, f)
+	}
+	return failures, nil
+}
+
+func (l *MemFailureLog) Resolve(_ context.Context, backend string, path string) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	delete(l.failures, [2]string{backend, path})
+	return nil
+}
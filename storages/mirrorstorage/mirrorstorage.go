@@ -0,0 +1,383 @@
+// Package mirrorstorage provides a storages.Storage replicating objects over several backends.
+// Writes fan out to every backend and succeed once a quorum of them succeeds. Backends that missed a write
+// are recorded in a FailureLog, and Repair re-syncs them from the others.
+// Reads go to the first healthy backend in order.
+package mirrorstorage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/x64c/gw/schedjobs"
+	"github.com/x64c/gw/storages"
+)
+
+// unhealthyCooldown is how long a backend is skipped for reads after it fails
+const unhealthyCooldown = 30 * time.Second
+
+var ErrQuorumNotReached = errors.New("mirrorstorage: write quorum not reached")
+
+type Backend struct {
+	Name    string // e.g. "local/uploads"
+	Storage storages.Storage
+}
+
+// MirrorStorage implements storages.Storage over several backends
+type MirrorStorage struct {
+	backends []Backend
+	quorum   int
+	failures FailureLog
+
+	mu             sync.Mutex
+	unhealthyUntil map[string]time.Time // backend name -> end of cooldown
+}
+
+// New creates a MirrorStorage. quorum <= 0 means all backends. failures nil means a MemFailureLog
+func New(backends []Backend, quorum int, failures FailureLog) (*MirrorStorage, error) {
+	if len(backends) == 0 {
+		return nil, errors.New("mirrorstorage: no backends")
+	}
+	if quorum <= 0 {
+		quorum = len(backends)
+	}
+	if quorum > len(backends) {
+		return nil, fmt.Errorf("mirrorstorage: quorum %d > %d backends", quorum, len(backends))
+	}
+	if failures == nil {
+		failures = NewMemFailureLog()
+	}
+	return &MirrorStorage{
+		backends:       backends,
+		quorum:         quorum,
+		failures:       failures,
+		unhealthyUntil: make(map[string]time.Time),
+	}, nil
+}
+
+func (s *MirrorStorage) Backends() []Backend {
+	return s.backends
+}
+
+func (s *MirrorStorage) FailureLog() FailureLog {
+	return s.failures
+}
+
+func (s *MirrorStorage) markUnhealthy(name string) {
+	s.mu.Lock()
+	s.unhealthyUntil[name] = time.Now().Add(unhealthyCooldown)
+	s.mu.Unlock()
+}
+
+func (s *MirrorStorage) markHealthy(name string) {
+	s.mu.Lock()
+	delete(s.unhealthyUntil, name)
+	s.mu.Unlock()
+}
+
+// readOrder returns the healthy backends first, then the ones cooling down
+func (s *MirrorStorage) readOrder() []Backend {
+	now := time.Now()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	healthy := make([]Backend, 0, len(s.backends))
+	var cooling []Backend
+	for _, b := range s.backends {
+		if now.Before(s.unhealthyUntil[b.Name]) {
+			cooling = append(cooling, b)
+			continue
+		}
+		healthy = append(healthy, b)
+	}
+	return append(healthy, cooling...)
+}
+
+// read runs fn on backends in read order until one succeeds.
+// A "not exist" answer is returned only if no backend has the object, since a backend may have missed a write.
+func read[T any](s *MirrorStorage, fn func(b Backend) (T, error)) (T, error) {
+	var zero T
+	var errList, failed []error
+	for _, b := range s.readOrder() {
+		v, err := fn(b)
+		if err == nil {
+			s.markHealthy(b.Name)
+			return v, nil
+		}
+		err = fmt.Errorf("%s: %w", b.Name, err)
+		if !errors.Is(err, fs.ErrNotExist) {
+			s.markUnhealthy(b.Name)
+			failed = append(failed, err)
+		}
+		errList = append(errList, err)
+	}
+	if len(failed) == 0 {
+		return zero, errList[0]
+	}
+	if len(failed) < len(errList) {
+		// the failed backends may hold the object
+		return zero, fmt.Errorf("cannot tell whether the object exists: %w", errors.Join(failed...))
+	}
+	return zero, errors.Join(errList...)
+}
+
+// write runs fn on every backend concurrently and records the failures
+func (s *MirrorStorage) write(ctx context.Context, op string, path string, fn func(b Backend) error) error {
+	errList := make([]error, len(s.backends))
+	var wg sync.WaitGroup
+	for i, b := range s.backends {
+		wg.Go(func() {
+			errList[i] = fn(b)
+		})
+	}
+	wg.Wait()
+	return s.settle(ctx, op, path, errList)
+}
+
+// settle records the failed backends and checks the quorum
+func (s *MirrorStorage) settle(ctx context.Context, op string, path string, errList []error) error {
+	succeeded := 0
+	var failed []error
+	for i, err := range errList {
+		b := s.backends[i]
+		if err == nil {
+			succeeded++
+			continue
+		}
+		s.markUnhealthy(b.Name)
+		failed = append(failed, fmt.Errorf("%s: %w", b.Name, err))
+		f := Failure{Path: path, Backend: b.Name, Op: op, Time: time.Now(), Err: err.Error()}
+		if recErr := s.failures.Record(context.WithoutCancel(ctx), f); recErr != nil {
+			log.Printf("[ERROR][MirrorStorage] failed to record failure %+v: %v", f, recErr)
+		}
+	}
+	if succeeded < s.quorum {
+		return fmt.Errorf("%w (%d/%d): %w", ErrQuorumNotReached, succeeded, s.quorum, errors.Join(failed...))
+	}
+	if len(failed) > 0 {
+		log.Printf("[WARN][MirrorStorage] %s %q: %d backends failed: %v", op, path, len(failed), errors.Join(failed...))
+	}
+	return nil
+}
+
+func (s *MirrorStorage) Exists(ctx context.Context, path string) (bool, error) {
+	exists, err := read(s, func(b Backend) (bool, error) {
+		exists, err := b.Storage.Exists(ctx, path)
+		if err == nil && !exists {
+			return false, fs.ErrNotExist // let the next backend answer
+		}
+		return exists, err
+	})
+	if errors.Is(err, fs.ErrNotExist) {
+		return false, nil
+	}
+	return exists, err
+}
+
+func (s *MirrorStorage) Get(ctx context.Context, path string) (io.ReadCloser, error) {
+	return read(s, func(b Backend) (io.ReadCloser, error) {
+		return b.Storage.Get(ctx, path)
+	})
+}
+
+func (s *MirrorStorage) Size(ctx context.Context, path string) (int64, error) {
+	return read(s, func(b Backend) (int64, error) {
+		return b.Storage.Size(ctx, path)
+	})
+}
+
+// Put streams r to every backend at once through pipes. A slow backend slows down the others
+func (s *MirrorStorage) Put(ctx context.Context, path string, r io.Reader) error {
+	writers := make([]*io.PipeWriter, len(s.backends))
+	errList := make([]error, len(s.backends))
+	var wg sync.WaitGroup
+	for i, b := range s.backends {
+		pr, pw := io.Pipe()
+		writers[i] = pw
+		wg.Go(func() {
+			err := b.Storage.Put(ctx, path, pr)
+			errList[i] = err
+			_ = pr.CloseWithError(err) // unblock the fan-out if the backend quit early
+		})
+	}
+	copyErr := fanOut(r, writers)
+	wg.Wait()
+	if copyErr != nil {
+		// the source failed. nothing was written properly, and the backends were told so through the pipes
+		return copyErr
+	}
+	return s.settle(ctx, "put", path, errList)
+}
+
+// fanOut copies r into every writer, dropping writers that fail. Returns the read error of r, if any
+func fanOut(r io.Reader, writers []*io.PipeWriter) error {
+	buf := make([]byte, 32<<10)
+	alive := len(writers)
+	for alive > 0 {
+		n, readErr := r.Read(buf)
+		if n > 0 {
+			for i, w := range writers {
+				if w == nil {
+					continue
+				}
+				if _, err := w.Write(buf[:n]); err != nil {
+					writers[i] = nil
+					alive--
+				}
+			}
+		}
+		if readErr == io.EOF {
+			break
+		}
+		if readErr != nil {
+			for _, w := range writers {
+				if w != nil {
+					_ = w.CloseWithError(readErr)
+				}
+			}
+			return readErr
+		}
+	}
+	for _, w := range writers {
+		if w != nil {
+			_ = w.Close()
+		}
+	}
+	return nil
+}
+
+func (s *MirrorStorage) Delete(ctx context.Context, path string) error {
+	return s.write(ctx, "delete", path, func(b Backend) error {
+		err := b.Storage.Delete(ctx, path)
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil
+		}
+		return err
+	})
+}
+
+func (s *MirrorStorage) Copy(ctx context.Context, src string, dst string) error {
+	return s.write(ctx, "copy", dst, func(b Backend) error {
+		return b.Storage.Copy(ctx, src, dst)
+	})
+}
+
+// Move runs per backend. A backend failing it may keep src and miss dst, so both are recorded for repair
+func (s *MirrorStorage) Move(ctx context.Context, src string, dst string) error {
+	errList := make([]error, len(s.backends))
+	var wg sync.WaitGroup
+	for i, b := range s.backends {
+		wg.Go(func() {
+			errList[i] = b.Storage.Move(ctx, src, dst)
+		})
+	}
+	wg.Wait()
+	for i, err := range errList {
+		if err != nil {
+			f := Failure{Path: src, Backend: s.backends[i].Name, Op: "move", Time: time.Now(), Err: err.Error()}
+			if recErr := s.failures.Record(context.WithoutCancel(ctx), f); recErr != nil {
+				log.Printf("[ERROR][MirrorStorage] failed to record failure %+v: %v", f, recErr)
+			}
+		}
+	}
+	return s.settle(ctx, "move", dst, errList)
+}
+
+// Repair re-syncs the backends recorded in the FailureLog.
+// The state of a path is taken from the other backends without pending failures on it, since a backend
+// with one may have missed the same write: the object is copied over from any of them having it,
+// and deleted from the diverged backend only if all of them answer that it does not exist.
+// Returns the number of repaired entries.
+func (s *MirrorStorage) Repair(ctx context.Context) (int, error) {
+	failures, err := s.failures.Pending(ctx)
+	if err != nil {
+		return 0, err
+	}
+	diverged := make(map[string]map[string]bool) // path -> backend names with pending failures
+	for _, f := range failures {
+		if diverged[f.Path] == nil {
+			diverged[f.Path] = make(map[string]bool)
+		}
+		diverged[f.Path][f.Backend] = true
+	}
+	repaired := 0
+	var errList []error
+	for _, f := range failures {
+		if err = ctx.Err(); err != nil {
+			errList = append(errList, err)
+			break
+		}
+		if err = s.repairPath(ctx, f.Backend, f.Path, diverged[f.Path]); err != nil {
+			errList = append(errList, fmt.Errorf("%s %q: %w", f.Backend, f.Path, err))
+			continue
+		}
+		if err = s.failures.Resolve(ctx, f.Backend, f.Path); err != nil {
+			errList = append(errList, err)
+			continue
+		}
+		repaired++
+	}
+	return repaired, errors.Join(errList...)
+}
+
+func (s *MirrorStorage) repairPath(ctx context.Context, backendName string, path string, diverged map[string]bool) error {
+	var target storages.Storage
+	for _, b := range s.backends {
+		if b.Name == backendName {
+			target = b.Storage
+		}
+	}
+	if target == nil {
+		return nil // backend removed from the mirror since
+	}
+	sources := 0
+	var errList []error
+	for _, b := range s.backends {
+		if b.Name == backendName || diverged[b.Name] {
+			continue
+		}
+		sources++
+		rc, err := b.Storage.Get(ctx, path)
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			errList = append(errList, fmt.Errorf("%s: %w", b.Name, err))
+			continue // ask the next backend
+		}
+		err = target.Put(ctx, path, rc)
+		_ = rc.Close()
+		return err
+	}
+	if sources == 0 {
+		return errors.New("no source backend without pending failures on the path")
+	}
+	if len(errList) > 0 {
+		// a backend that did not answer may have the object
+		return fmt.Errorf("cannot tell whether the object exists: %w", errors.Join(errList...))
+	}
+	err := target.Delete(ctx, path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	return err
+}
+
+// RepairCronJob returns a cron job running Repair every 10 minutes. Add it to a schedjobs.Scheduler
+func (s *MirrorStorage) RepairCronJob(ctx context.Context, jobID string) *schedjobs.CronJob {
+	job := schedjobs.NewEveryMinEmptyCronJob(jobID)
+	job.Minutes = schedjobs.BitsFromMinutes([]int{0, 10, 20, 30, 40, 50})
+	job.Overlap = schedjobs.OverlapSkip
+	job.Task = func() error {
+		repaired, err := s.Repair(ctx)
+		if repaired > 0 {
+			log.Printf("[INFO][MirrorStorage] %s: %d diverged objects repaired", jobID, repaired)
+		}
+		return err
+	}
+	return job
+}
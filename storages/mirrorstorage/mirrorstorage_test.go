@@ -0,0 +1,185 @@
+package mirrorstorage
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/fs"
+	"strings"
+	"testing"
+
+	"github.com/x64c/gw/storages"
+	"github.com/x64c/gw/storages/memstorage"
+)
+
+var errDown = errors.New("backend down")
+
+// flakyStorage fails every operation while down
+type flakyStorage struct {
+	storages.Storage
+	down bool
+}
+
+func (f *flakyStorage) Get(ctx context.Context, path string) (io.ReadCloser, error) {
+	if f.down {
+		return nil, errDown
+	}
+	return f.Storage.Get(ctx, path)
+}
+
+func (f *flakyStorage) Exists(ctx context.Context, path string) (bool, error) {
+	if f.down {
+		return false, errDown
+	}
+	return f.Storage.Exists(ctx, path)
+}
+
+func (f *flakyStorage) Put(ctx context.Context, path string, r io.Reader) error {
+	if f.down {
+		return errDown
+	}
+	return f.Storage.Put(ctx, path, r)
+}
+
+func (f *flakyStorage) Delete(ctx context.Context, path string) error {
+	if f.down {
+		return errDown
+	}
+	return f.Storage.Delete(ctx, path)
+}
+
+func newTestMirror(t *testing.T, quorum int) (*MirrorStorage, []*flakyStorage) {
+	var backends []Backend
+	var flaky []*flakyStorage
+	for _, name := range []string{"a", "b", "c"} {
+		f := &flakyStorage{Storage: memstorage.New()}
+		flaky = append(flaky, f)
+		backends = append(backends, Backend{Name: name, Storage: f})
+	}
+	s, err := New(backends, quorum, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s, flaky
+}
+
+func content(t *testing.T, st storages.Storage, path string) string {
+	t.Helper()
+	rc, err := st.Get(context.Background(), path)
+	if err != nil {
+		return ""
+	}
+	defer func() { _ = rc.Close() }()
+	b, _ := io.ReadAll(rc)
+	return string(b)
+}
+
+func pendingCount(t *testing.T, s *MirrorStorage) int {
+	t.Helper()
+	failures, err := s.FailureLog().Pending(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return len(failures)
+}
+
+func TestRepairSkipsDivergedSources(t *testing.T) {
+	ctx := context.Background()
+	s, flaky := newTestMirror(t, 1)
+	// the write reaches c only
+	flaky[0].down, flaky[1].down = true, true
+	if err := s.Put(ctx, "obj", strings.NewReader("v1")); err != nil {
+		t.Fatal(err)
+	}
+	flaky[0].down, flaky[1].down = false, false
+
+	repaired, err := s.Repair(ctx)
+	if err != nil || repaired != 2 {
+		t.Fatalf("Repair = %d, %v", repaired, err)
+	}
+	for i, f := range flaky {
+		if got := content(t, f, "obj"); got != "v1" {
+			t.Errorf("backend %d holds %q", i, got)
+		}
+	}
+}
+
+func TestRepairDeletesOnlyWhenAllAgree(t *testing.T) {
+	ctx := context.Background()
+	s, flaky := newTestMirror(t, 1)
+	if err := s.Put(ctx, "obj", strings.NewReader("v1")); err != nil {
+		t.Fatal(err)
+	}
+	// the delete misses a
+	flaky[0].down = true
+	if err := s.Delete(ctx, "obj"); err != nil {
+		t.Fatal(err)
+	}
+	flaky[0].down = false
+
+	// c cannot answer: a keeps the object and its failure stays pending
+	flaky[2].down = true
+	if repaired, err := s.Repair(ctx); err == nil || repaired != 0 {
+		t.Fatalf("Repair with a source down = %d, %v", repaired, err)
+	}
+	if content(t, flaky[0], "obj") != "v1" || pendingCount(t, s) != 1 {
+		t.Fatal("object deleted without every source agreeing")
+	}
+
+	flaky[2].down = false
+	if repaired, err := s.Repair(ctx); err != nil || repaired != 1 {
+		t.Fatalf("Repair = %d, %v", repaired, err)
+	}
+	if ok, _ := flaky[0].Exists(ctx, "obj"); ok {
+		t.Fatal("deleted object kept on the diverged backend")
+	}
+	if pendingCount(t, s) != 0 {
+		t.Fatal("failure left pending")
+	}
+}
+
+func TestRepairWithoutSource(t *testing.T) {
+	ctx := context.Background()
+	s, flaky := newTestMirror(t, 1)
+	// every backend but c missed the write, and c is gone since
+	flaky[0].down, flaky[1].down = true, true
+	if err := s.Put(ctx, "obj", strings.NewReader("v1")); err != nil {
+		t.Fatal(err)
+	}
+	flaky[0].down, flaky[1].down, flaky[2].down = false, false, true
+	if repaired, err := s.Repair(ctx); err == nil || repaired != 0 {
+		t.Fatalf("Repair = %d, %v", repaired, err)
+	}
+	if pendingCount(t, s) != 2 {
+		t.Fatal("failures resolved without a source")
+	}
+}
+
+func TestReadMissingOnSomeFailingOnOthers(t *testing.T) {
+	ctx := context.Background()
+	s, flaky := newTestMirror(t, 1)
+	// b and c missed the write, and a, holding the object, is down
+	flaky[1].down, flaky[2].down = true, true
+	if err := s.Put(ctx, "obj", strings.NewReader("v1")); err != nil {
+		t.Fatal(err)
+	}
+	flaky[0].down, flaky[1].down, flaky[2].down = true, false, false
+
+	if _, err := s.Get(ctx, "obj"); err == nil || errors.Is(err, fs.ErrNotExist) {
+		t.Fatalf("Get = %v, want an error other than not exist", err)
+	}
+	if exists, err := s.Exists(ctx, "obj"); err == nil {
+		t.Fatalf("Exists = %v, nil", exists)
+	}
+	if _, err := s.Get(ctx, "never"); !errors.Is(err, errDown) || errors.Is(err, fs.ErrNotExist) {
+		t.Fatalf("Get of a missing object with a backend down = %v", err)
+	}
+
+	flaky[0].down = false
+	if _, err := s.Get(ctx, "never"); !errors.Is(err, fs.ErrNotExist) {
+		t.Fatalf("Get of a missing object = %v", err)
+	}
+	if exists, err := s.Exists(ctx, "never"); err != nil || exists {
+		t.Fatalf("Exists of a missing object = %v, %v", exists, err)
+	}
+}
@@ -0,0 +1,159 @@
+package storages
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"slices"
+	"strings"
+	"time"
+)
+
+// RetentionRule limits how long objects under a prefix are kept.
+// With both MaxAge and KeepLast, the newest KeepLast objects are always kept and older ones beyond them
+// are deleted once they exceed MaxAge.
+//
+// KeepLast counts the objects under the whole Prefix, sub-prefixes included: with "reports/" and keep_last 30,
+// the newest 30 objects across reports/a/, reports/b/, ... are kept, not 30 per sub-prefix. Add a rule per
+// sub-prefix to keep N of each.
+//
+//	{ "local": { "exports": { "root": "...", "retention": [ { "prefix": "tmp/", "max_age": 86400 }, { "prefix": "reports/", "keep_last": 30 } ] } } }
+type RetentionRule struct {
+	Prefix   string `json:"prefix"`    // "" = the whole storage
+	MaxAge   int    `json:"max_age"`   // seconds. delete objects modified longer ago than this. 0 = no age limit
+	KeepLast int    `json:"keep_last"` // keep only the newest N objects under Prefix. 0 = no count limit
+}
+
+// Retention is the set of retention rules of a storage. The storage must be a Lister.
+// The rules apply as a union: an object is deleted if any rule deletes it. KeepLast protects objects only
+// within its own rule, so another rule whose prefix covers them (a wider prefix with a MaxAge) still deletes them.
+type Retention struct {
+	Storage Storage
+	Rules   []RetentionRule
+}
+
+var ErrNotLister = errors.New("storages: storage does not support listing")
+
+// Plan returns the objects the rules would delete at now, in path order
+func (r *Retention) Plan(ctx context.Context, now time.Time) ([]ObjectInfo, error) {
+	lister, ok := r.Storage.(Lister)
+	if !ok {
+		return nil, ErrNotLister
+	}
+	expired := make(map[string]ObjectInfo)
+	for _, rule := range r.Rules {
+		if rule.MaxAge <= 0 && rule.KeepLast <= 0 {
+			continue
+		}
+		objects, err := listAll(ctx, lister, rule.Prefix)
+		if err != nil {
+			return nil, err
+		}
+		// newest first
+		slices.SortFunc(objects, func(a, b ObjectInfo) int { return b.ModTime.Compare(a.ModTime) })
+		cutoff := now.Add(-time.Duration(rule.MaxAge) * time.Second)
+		for i, obj := range objects {
+			if rule.KeepLast > 0 && i < rule.KeepLast {
+				continue
+			}
+			if rule.MaxAge > 0 && !obj.ModTime.Before(cutoff) {
+				continue
+			}
+			expired[obj.Path] = obj
+		}
+	}
+	plan := make([]ObjectInfo, 0, len(expired))
+	for _, obj := range expired {
+		plan = append(plan, obj)
+	}
+	slices.SortFunc(plan, func(a, b ObjectInfo) int { return strings.Compare(a.Path, b.Path) })
+	return plan, nil
+}
+
+// Apply deletes the planned objects. It keeps going on delete failures and returns them joined
+func (r *Retention) Apply(ctx context.Context, now time.Time) ([]ObjectInfo, error) {
+	plan, err := r.Plan(ctx, now)
+	if err != nil {
+		return nil, err
+	}
+	deleted := make([]ObjectInfo, 0, len(plan))
+	var errs []error
+	for _, obj := range plan {
+		if err = ctx.Err(); err != nil {
+			errs = append(errs, err)
+			break
+		}
+		if err = r.Storage.Delete(ctx, obj.Path); err != nil {
+			errs = append(errs, fmt.Errorf("delete %q: %w", obj.Path, err))
+			continue
+		}
+		deleted = append(deleted, obj)
+	}
+	return deleted, errors.Join(errs...)
+}
+
+func listAll(ctx context.Context, lister Lister, prefix string) ([]ObjectInfo, error) {
+	var objects []ObjectInfo
+	opts := ListOptions{Prefix: prefix}
+	for {
+		page, err := lister.List(ctx, opts)
+		if err != nil {
+			return nil, err
+		}
+		objects = append(objects, page.Objects...)
+		if page.NextCursor == "" {
+			return objects, nil
+		}
+		opts.Cursor = page.NextCursor
+	}
+}
+
+// RetentionCommand is a uds.CommandHandler dry-running retention rules.
+// Reports what would be deleted now without deleting anything.
+type RetentionCommand struct {
+	Retentions map[string]*Retention // "<client>/<storage>" -> Retention
+}
+
+func (c *RetentionCommand) Command() string {
+	return "storage-retention"
+}
+
+func (c *RetentionCommand) GroupName() string {
+	return "Storages"
+}
+
+func (c *RetentionCommand) Desc() string {
+	return "dry-run storage retention rules"
+}
+
+func (c *RetentionCommand) Usage() string {
+	return "storage-retention [<client>/<storage>]"
+}
+
+func (c *RetentionCommand) HandleCommand(args []string, w io.Writer) error {
+	refs := slices.Sorted(maps.Keys(c.Retentions))
+	if len(args) > 0 {
+		if _, ok := c.Retentions[args[0]]; !ok {
+			return fmt.Errorf("no retention rules for %q", args[0])
+		}
+		refs = []string{args[0]}
+	}
+	ctx := context.Background()
+	now := time.Now()
+	for _, ref := range refs {
+		plan, err := c.Retentions[ref].Plan(ctx, now)
+		if err != nil {
+			_, _ = fmt.Fprintf(w, "%s: %v\n", ref, err)
+			continue
+		}
+		total := int64(0)
+		for _, obj := range plan {
+			total += obj.Size
+			_, _ = fmt.Fprintf(w, "%s  %10d  %s  %s\n", ref, obj.Size, obj.ModTime.Format(time.DateTime), obj.Path)
+		}
+		_, _ = fmt.Fprintf(w, "%s: %d objects, %d bytes would be deleted\n", ref, len(plan), total)
+	}
+	return nil
+}
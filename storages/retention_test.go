@@ -0,0 +1,50 @@
+package storages
+
+import (
+	"context"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestRetentionPlan(t *testing.T) {
+	paths := []string{"tmp/1", "reports/a/1", "reports/a/2", "reports/b/1", "reports/b/2"}
+	s := newTestLocalStorage(t, paths)
+	now := time.Now()
+	// ages in hours, in the order of paths
+	for i, hours := range []int{48, 1, 72, 2, 96} {
+		mtime := now.Add(-time.Duration(hours) * time.Hour)
+		if err := s.dir.Chtimes(paths[i], mtime, mtime); err != nil {
+			t.Fatal(err)
+		}
+	}
+	plan := func(rules ...RetentionRule) []string {
+		objects, err := (&Retention{Storage: s, Rules: rules}).Plan(context.Background(), now)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var names []string
+		for _, obj := range objects {
+			names = append(names, obj.Path)
+		}
+		return names
+	}
+	day := 24 * 3600
+	tests := []struct {
+		name  string
+		rules []RetentionRule
+		want  []string
+	}{
+		{"max age", []RetentionRule{{Prefix: "tmp/", MaxAge: day}}, []string{"tmp/1"}},
+		// KeepLast counts across reports/a/ and reports/b/ together
+		{"keep last over the whole prefix", []RetentionRule{{Prefix: "reports/", KeepLast: 2}}, []string{"reports/a/2", "reports/b/2"}},
+		{"keep last with max age", []RetentionRule{{Prefix: "reports/", KeepLast: 1, MaxAge: 80 * 3600}}, []string{"reports/b/2"}},
+		// a wider MaxAge rule deletes objects a KeepLast rule keeps
+		{"union", []RetentionRule{{Prefix: "reports/", KeepLast: 5}, {MaxAge: day}}, []string{"reports/a/2", "reports/b/2", "tmp/1"}},
+	}
+	for _, tt := range tests {
+		if got := plan(tt.rules...); !slices.Equal(got, tt.want) {
+			t.Errorf("%s: got %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,58 @@
+package s3storage
+
+import (
+	"encoding/json/jsontext"
+	"encoding/json/v2"
+	"errors"
+	"net/http"
+
+	"github.com/x64c/gw/storages"
+)
+
+// ClientName is the client name of S3 storages in .storages.json
+const ClientName = "s3"
+
+// Client implements storages.Client for S3-compatible object storages
+type Client struct {
+	httpClient *http.Client
+	storages   map[string]*Storage
+}
+
+func NewClient(httpClient *http.Client) *Client {
+	return &Client{
+		httpClient: httpClient,
+		storages:   make(map[string]*Storage),
+	}
+}
+
+// Preparer returns a preparer for Core.PrepareStorageClients registering a Client as "s3"
+// e.g. core.PrepareStorageClients(s3storage.Preparer(core.BaseHttpClient))
+func Preparer(httpClient *http.Client) func(string, map[string]storages.Client) error {
+	return func(_ string, clients map[string]storages.Client) error {
+		clients[ClientName] = NewClient(httpClient)
+		return nil
+	}
+}
+
+func (c *Client) CreateStorage(name string, conf jsontext.Value) error {
+	var storageConf Conf
+	if err := json.Unmarshal(conf, &storageConf); err != nil {
+		return err
+	}
+	s, err := NewStorage(c.httpClient, storageConf)
+	if err != nil {
+		return err
+	}
+	c.storages[name] = s
+	return nil
+}
+
+func (c *Client) Storage(name string) (storages.Storage, bool) {
+	s, ok := c.storages[name]
+	if !ok {
+		return nil, false
+	}
+	return s, true
+}
+
+var errConfRequired = errors.New("s3: endpoint, region, bucket, access_key_id and secret_access_key are required")
@@ -0,0 +1,22 @@
+package s3storage
+
+// Conf is the per-storage config in .storages.json under the "s3" client
+//
+//	{ "s3": { "uploads": { "endpoint": "https://s3.ap-northeast-2.amazonaws.com", "region": "ap-northeast-2", "bucket": "my-uploads", ... } } }
+type Conf struct {
+	Endpoint           string `json:"endpoint"`            // scheme://host[:port]
+	Region             string `json:"region"`              // signing region
+	Bucket             string `json:"bucket"`              // bucket name
+	AccessKeyID        string `json:"access_key_id"`       // credentials
+	SecretAccessKey    string `json:"secret_access_key"`   // credentials
+	PathStyle          bool   `json:"path_style"`          // endpoint/bucket/key instead of bucket.endpoint/key. Most S3-compatible servers need this
+	Prefix             string `json:"prefix"`              // optional key prefix for all paths of this storage. e.g. "uploads/"
+	MultipartThreshold int64  `json:"multipart_threshold"` // bytes. Put switches to multipart upload above this size. default 16MiB
+	PartSize           int64  `json:"part_size"`           // bytes. multipart part size (min 5MiB by S3). default 8MiB
+}
+
+const (
+	defaultMultipartThreshold int64 = 16 << 20
+	defaultPartSize           int64 = 8 << 20
+	minPartSize               int64 = 5 << 20
+)
@@ -0,0 +1,55 @@
+package s3storage
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+)
+
+// Error is an error response from the S3 server
+type Error struct {
+	StatusCode int
+	Code       string `xml:"Code"`
+	Message    string `xml:"Message"`
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("s3: %d %s: %s", e.StatusCode, e.Code, e.Message)
+}
+
+// Is maps 404s to fs.ErrNotExist, so errors.Is(err, fs.ErrNotExist) works as with LocalStorage
+func (e *Error) Is(target error) bool {
+	return target == fs.ErrNotExist && e.StatusCode == http.StatusNotFound
+}
+
+// errorFromResponse reads and closes the body of a non-2xx response
+func errorFromResponse(res *http.Response) error {
+	defer func() { _ = res.Body.Close() }()
+	e := &Error{StatusCode: res.StatusCode}
+	body, _ := io.ReadAll(io.LimitReader(res.Body, 64<<10))
+	if len(body) > 0 {
+		_ = xml.Unmarshal(body, e) // best effort. HEAD responses have no body
+	}
+	if e.Code == "" {
+		e.Code = http.StatusText(res.StatusCode)
+	}
+	return e
+}
+
+// errorInBody reads and closes the body of a 2xx response, returning the <Error> it may carry.
+// CopyObject and CompleteMultipartUpload can fail after the 200 status is sent
+func errorInBody(res *http.Response) error {
+	defer func() { _ = res.Body.Close() }()
+	body, err := io.ReadAll(io.LimitReader(res.Body, 64<<10))
+	if err != nil {
+		return err
+	}
+	var e Error
+	if xml.Unmarshal(body, &e) == nil && e.Code != "" {
+		e.StatusCode = res.StatusCode
+		return &e
+	}
+	return nil
+}
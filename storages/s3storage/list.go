@@ -0,0 +1,69 @@
+package s3storage
+
+import (
+	"context"
+	"encoding/xml"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/x64c/gw/storages"
+)
+
+type listBucketResult struct {
+	Contents []struct {
+		Key          string    `xml:"Key"`
+		LastModified time.Time `xml:"LastModified"`
+		ETag         string    `xml:"ETag"`
+		Size         int64     `xml:"Size"`
+	} `xml:"Contents"`
+	CommonPrefixes []struct {
+		Prefix string `xml:"Prefix"`
+	} `xml:"CommonPrefixes"`
+	IsTruncated           bool   `xml:"IsTruncated"`
+	NextContinuationToken string `xml:"NextContinuationToken"`
+}
+
+// List lists objects with ListObjectsV2. The cursor is the S3 continuation token. Implements storages.Lister
+func (s *Storage) List(ctx context.Context, opts storages.ListOptions) (*storages.ListPage, error) {
+	query := url.Values{
+		"list-type": {"2"},
+		"prefix":    {s.conf.Prefix + strings.TrimLeft(opts.Prefix, "/")},
+	}
+	if opts.Delimiter != "" {
+		query.Set("delimiter", opts.Delimiter)
+	}
+	if opts.Cursor != "" {
+		query.Set("continuation-token", opts.Cursor)
+	}
+	if opts.Limit > 0 {
+		query.Set("max-keys", strconv.Itoa(opts.Limit))
+	}
+	res, err := s.do(ctx, http.MethodGet, "", query, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = res.Body.Close() }()
+	var result listBucketResult
+	if err = xml.NewDecoder(res.Body).Decode(&result); err != nil {
+		return nil, err
+	}
+	page := &storages.ListPage{}
+	for _, c := range result.Contents {
+		page.Objects = append(page.Objects, storages.ObjectInfo{
+			Path:    strings.TrimPrefix(c.Key, s.conf.Prefix),
+			Size:    c.Size,
+			ModTime: c.LastModified,
+			ETag:    c.ETag,
+		})
+	}
+	for _, p := range result.CommonPrefixes {
+		page.CommonPrefixes = append(page.CommonPrefixes, strings.TrimPrefix(p.Prefix, s.conf.Prefix))
+	}
+	if result.IsTruncated {
+		page.NextCursor = result.NextContinuationToken
+	}
+	return page, nil
+}
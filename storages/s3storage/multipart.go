@@ -0,0 +1,90 @@
+package s3storage
+
+import (
+	"context"
+	"encoding/xml"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+)
+
+type initiateMultipartUploadResult struct {
+	UploadID string `xml:"UploadId"`
+}
+
+type completedPart struct {
+	PartNumber int    `xml:"PartNumber"`
+	ETag       string `xml:"ETag"`
+}
+
+type completeMultipartUpload struct {
+	XMLName xml.Name        `xml:"CompleteMultipartUpload"`
+	Parts   []completedPart `xml:"Part"`
+}
+
+// putMultipart uploads r in PartSize parts. The upload is aborted on failure.
+func (s *Storage) putMultipart(ctx context.Context, key string, r io.Reader) error {
+	uploadID, err := s.initiateMultipart(ctx, key)
+	if err != nil {
+		return err
+	}
+	if err = s.uploadParts(ctx, key, uploadID, r); err != nil {
+		abortQuery := url.Values{"uploadId": {uploadID}}
+		if _, abortErr := s.doDiscard(context.WithoutCancel(ctx), http.MethodDelete, key, abortQuery, nil, nil); abortErr != nil {
+			log.Printf("[WARN][S3] abort multipart upload %q: %v", key, abortErr)
+		}
+		return err
+	}
+	return nil
+}
+
+func (s *Storage) initiateMultipart(ctx context.Context, key string) (string, error) {
+	res, err := s.do(ctx, http.MethodPost, key, url.Values{"uploads": {""}}, nil, nil)
+	if err != nil {
+		return "", err
+	}
+	defer func() { _ = res.Body.Close() }()
+	var result initiateMultipartUploadResult
+	if err = xml.NewDecoder(res.Body).Decode(&result); err != nil {
+		return "", err
+	}
+	if result.UploadID == "" {
+		return "", errors.New("s3: empty upload id")
+	}
+	return result.UploadID, nil
+}
+
+func (s *Storage) uploadParts(ctx context.Context, key string, uploadID string, r io.Reader) error {
+	var complete completeMultipartUpload
+	buf := make([]byte, s.conf.PartSize)
+	for partNumber := 1; ; partNumber++ {
+		n, readErr := io.ReadFull(r, buf)
+		if n == 0 && errors.Is(readErr, io.EOF) {
+			break
+		}
+		if readErr != nil && !errors.Is(readErr, io.ErrUnexpectedEOF) {
+			return readErr
+		}
+		query := url.Values{"partNumber": {strconv.Itoa(partNumber)}, "uploadId": {uploadID}}
+		res, err := s.doDiscard(ctx, http.MethodPut, key, query, nil, buf[:n])
+		if err != nil {
+			return err
+		}
+		complete.Parts = append(complete.Parts, completedPart{PartNumber: partNumber, ETag: res.Header.Get("ETag")})
+		if errors.Is(readErr, io.ErrUnexpectedEOF) { // short last part
+			break
+		}
+	}
+	body, err := xml.Marshal(complete)
+	if err != nil {
+		return err
+	}
+	res, err := s.do(ctx, http.MethodPost, key, url.Values{"uploadId": {uploadID}}, nil, body)
+	if err != nil {
+		return err
+	}
+	return errorInBody(res)
+}
@@ -0,0 +1,207 @@
+package s3storage
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/x64c/gw/storages"
+)
+
+// Storage implements storages.Storage on a bucket of an S3-compatible server.
+// Requests are signed with AWS SigV4 over plain net/http.
+type Storage struct {
+	httpClient *http.Client
+	conf       Conf
+	endpoint   *url.URL
+	signer     *signer
+}
+
+func NewStorage(httpClient *http.Client, conf Conf) (*Storage, error) {
+	if conf.Endpoint == "" || conf.Region == "" || conf.Bucket == "" || conf.AccessKeyID == "" || conf.SecretAccessKey == "" {
+		return nil, errConfRequired
+	}
+	endpoint, err := url.Parse(conf.Endpoint)
+	if err != nil {
+		return nil, fmt.Errorf("s3: endpoint: %w", err)
+	}
+	if conf.MultipartThreshold <= 0 {
+		conf.MultipartThreshold = defaultMultipartThreshold
+	}
+	if conf.PartSize <= 0 {
+		conf.PartSize = defaultPartSize
+	}
+	if conf.PartSize < minPartSize {
+		return nil, fmt.Errorf("s3: part_size must be at least %d bytes", minPartSize)
+	}
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	return &Storage{
+		httpClient: httpClient,
+		conf:       conf,
+		endpoint:   endpoint,
+		signer: &signer{
+			accessKeyID:     conf.AccessKeyID,
+			secretAccessKey: conf.SecretAccessKey,
+			region:          conf.Region,
+		},
+	}, nil
+}
+
+// objectKey maps a storage path to the object key
+func (s *Storage) objectKey(p string) string {
+	return s.conf.Prefix + strings.TrimPrefix(path.Clean("/"+p), "/")
+}
+
+// objectURL builds the URL of an object key (path-style or virtual-hosted style)
+func (s *Storage) objectURL(key string, query url.Values) *url.URL {
+	u := *s.endpoint
+	objPath := "/" + key
+	if s.conf.PathStyle {
+		objPath = "/" + s.conf.Bucket + objPath
+	} else {
+		u.Host = s.conf.Bucket + "." + u.Host
+	}
+	u.Path = objPath
+	u.RawPath = uriEncode(objPath, false)
+	u.RawQuery = canonicalQuery(query)
+	return &u
+}
+
+// do signs and sends a request with an in-memory body. Non-2xx responses are returned as *Error
+func (s *Storage) do(ctx context.Context, method string, key string, query url.Values, header http.Header, body []byte) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, method, s.objectURL(key, query).String(), bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	if body == nil {
+		req.Body = http.NoBody
+	}
+	req.ContentLength = int64(len(body))
+	for name, vals := range header {
+		req.Header[name] = vals
+	}
+	payloadHash := emptyPayloadSHA
+	if len(body) > 0 {
+		payloadHash = hashHex(body)
+	}
+	s.signer.sign(req, payloadHash, time.Now())
+	res, err := s.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return nil, errorFromResponse(res)
+	}
+	return res, nil
+}
+
+// doDiscard is do for responses whose body is not needed
+func (s *Storage) doDiscard(ctx context.Context, method string, key string, query url.Values, header http.Header, body []byte) (*http.Response, error) {
+	res, err := s.do(ctx, method, key, query, header, body)
+	if err != nil {
+		return nil, err
+	}
+	_, _ = io.Copy(io.Discard, res.Body)
+	_ = res.Body.Close()
+	return res, nil
+}
+
+func (s *Storage) head(ctx context.Context, path string) (*http.Response, error) {
+	return s.doDiscard(ctx, http.MethodHead, s.objectKey(path), nil, nil, nil)
+}
+
+func (s *Storage) Exists(ctx context.Context, path string) (bool, error) {
+	_, err := s.head(ctx, path)
+	if err == nil {
+		return true, nil
+	}
+	var s3Err *Error
+	if errors.As(err, &s3Err) && s3Err.StatusCode == http.StatusNotFound {
+		return false, nil
+	}
+	return false, err
+}
+
+func (s *Storage) Get(ctx context.Context, path string) (io.ReadCloser, error) {
+	res, err := s.do(ctx, http.MethodGet, s.objectKey(path), nil, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	return res.Body, nil
+}
+
+// Put uploads r. Up to MultipartThreshold bytes are sent as a single PUT;
+// larger content is streamed as a multipart upload, buffering one part at a time.
+func (s *Storage) Put(ctx context.Context, path string, r io.Reader) error {
+	key := s.objectKey(path)
+	// the buffer grows with the content, so small objects do not pay for the threshold
+	var head bytes.Buffer
+	n, err := io.CopyN(&head, r, s.conf.MultipartThreshold+1)
+	if err != nil && !errors.Is(err, io.EOF) {
+		return err
+	}
+	if n <= s.conf.MultipartThreshold {
+		_, err = s.doDiscard(ctx, http.MethodPut, key, nil, nil, head.Bytes())
+		return err
+	}
+	return s.putMultipart(ctx, key, io.MultiReader(&head, r))
+}
+
+func (s *Storage) Delete(ctx context.Context, path string) error {
+	_, err := s.doDiscard(ctx, http.MethodDelete, s.objectKey(path), nil, nil, nil)
+	return err
+}
+
+func (s *Storage) Size(ctx context.Context, path string) (int64, error) {
+	res, err := s.head(ctx, path)
+	if err != nil {
+		return 0, err
+	}
+	return strconv.ParseInt(res.Header.Get("Content-Length"), 10, 64)
+}
+
+// Copy is a server-side copy (no download/upload). Objects over 5GiB are rejected by S3.
+func (s *Storage) Copy(ctx context.Context, src string, dst string) error {
+	header := http.Header{}
+	header.Set("X-Amz-Copy-Source", uriEncode("/"+s.conf.Bucket+"/"+s.objectKey(src), false))
+	res, err := s.do(ctx, http.MethodPut, s.objectKey(dst), nil, header, nil)
+	if err != nil {
+		return err
+	}
+	return errorInBody(res)
+}
+
+// Move is a server-side copy followed by a delete. Not atomic.
+func (s *Storage) Move(ctx context.Context, src string, dst string) error {
+	if err := s.Copy(ctx, src, dst); err != nil {
+		return err
+	}
+	return s.Delete(ctx, src)
+}
+
+// maxPresignExpires is the longest validity S3 accepts for a presigned url
+const maxPresignExpires = 7 * 24 * time.Hour
+
+// PresignGet returns a SigV4 presigned GET url of the object. Implements storages.Presigner
+func (s *Storage) PresignGet(path string, ttl time.Duration, opts storages.PresignOptions) (string, error) {
+	if ttl <= 0 || ttl > maxPresignExpires {
+		return "", fmt.Errorf("s3: presign ttl must be in (0, %s]", maxPresignExpires)
+	}
+	query := url.Values{}
+	if opts.ContentDisposition != "" {
+		query.Set("response-content-disposition", opts.ContentDisposition)
+	}
+	u := s.objectURL(s.objectKey(path), query)
+	s.signer.presign(u, http.MethodGet, ttl, time.Now())
+	return u.String(), nil
+}
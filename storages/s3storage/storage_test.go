@@ -0,0 +1,418 @@
+package s3storage
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/x64c/gw/storages"
+)
+
+// fakeS3 is a path-style S3 stand-in keeping objects in memory.
+// Every request must carry a valid SigV4 signature and payload hash.
+type fakeS3 struct {
+	bucket string
+	signer *signer
+
+	mu            sync.Mutex
+	objects       map[string][]byte
+	uploads       map[string]map[int][]byte // upload id -> part number -> content
+	nextUploadID  int
+	partPuts      int
+	failCopyInOK  bool // answer CopyObject with 200 and an <Error> body
+	singlePutSize []int
+}
+
+func newFakeS3(t *testing.T) (*fakeS3, *httptest.Server) {
+	f := &fakeS3{
+		bucket:  "bucket",
+		signer:  &signer{accessKeyID: "AKID", secretAccessKey: "SECRET", region: "test-1"},
+		objects: make(map[string][]byte),
+		uploads: make(map[string]map[int][]byte),
+	}
+	srv := httptest.NewServer(f)
+	t.Cleanup(srv.Close)
+	return f, srv
+}
+
+func (f *fakeS3) storage(t *testing.T, srv *httptest.Server, conf Conf) *Storage {
+	conf.Endpoint = srv.URL
+	conf.Region = f.signer.region
+	conf.Bucket = f.bucket
+	conf.AccessKeyID = f.signer.accessKeyID
+	conf.SecretAccessKey = f.signer.secretAccessKey
+	conf.PathStyle = true
+	s, err := NewStorage(srv.Client(), conf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func writeS3Error(w http.ResponseWriter, status int, code string) {
+	w.WriteHeader(status)
+	_, _ = fmt.Fprintf(w, "<Error><Code>%s</Code><Message>%s</Message></Error>", code, code)
+}
+
+// verify re-signs the request as received and compares the Authorization
+func (f *fakeS3) verify(r *http.Request, body []byte) bool {
+	if hashHex(body) != r.Header.Get("X-Amz-Content-Sha256") && len(body) > 0 {
+		return false
+	}
+	signedAt, err := time.Parse(amzDateLayout, r.Header.Get("X-Amz-Date"))
+	if err != nil {
+		return false
+	}
+	req, _ := http.NewRequest(r.Method, "http://"+r.Host+r.URL.RequestURI(), nil)
+	for name, vals := range r.Header {
+		if strings.HasPrefix(strings.ToLower(name), "x-amz-") {
+			req.Header[name] = vals
+		}
+	}
+	f.signer.sign(req, r.Header.Get("X-Amz-Content-Sha256"), signedAt)
+	return req.Header.Get("Authorization") == r.Header.Get("Authorization")
+}
+
+func (f *fakeS3) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+	if !f.verify(r, body) {
+		writeS3Error(w, http.StatusForbidden, "SignatureDoesNotMatch")
+		return
+	}
+	key, ok := strings.CutPrefix(r.URL.Path, "/"+f.bucket+"/")
+	if !ok {
+		writeS3Error(w, http.StatusNotFound, "NoSuchBucket")
+		return
+	}
+	q := r.URL.Query()
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	switch {
+	case r.Method == http.MethodGet && q.Get("list-type") == "2":
+		f.list(w, q)
+	case r.Method == http.MethodPost && q.Has("uploads"):
+		f.nextUploadID++
+		id := "upload-" + strconv.Itoa(f.nextUploadID)
+		f.uploads[id] = make(map[int][]byte)
+		_, _ = fmt.Fprintf(w, "<InitiateMultipartUploadResult><UploadId>%s</UploadId></InitiateMultipartUploadResult>", id)
+	case r.Method == http.MethodPut && q.Has("partNumber"):
+		parts, ok := f.uploads[q.Get("uploadId")]
+		if !ok {
+			writeS3Error(w, http.StatusNotFound, "NoSuchUpload")
+			return
+		}
+		n, _ := strconv.Atoi(q.Get("partNumber"))
+		parts[n] = body
+		f.partPuts++
+		w.Header().Set("ETag", fmt.Sprintf(`"etag-%d"`, n))
+	case r.Method == http.MethodPost && q.Has("uploadId"):
+		parts, ok := f.uploads[q.Get("uploadId")]
+		if !ok {
+			writeS3Error(w, http.StatusNotFound, "NoSuchUpload")
+			return
+		}
+		var complete completeMultipartUpload
+		if err := xml.Unmarshal(body, &complete); err != nil {
+			writeS3Error(w, http.StatusBadRequest, "MalformedXML")
+			return
+		}
+		var content []byte
+		for i, p := range complete.Parts {
+			if p.PartNumber != i+1 || p.ETag != fmt.Sprintf(`"etag-%d"`, p.PartNumber) {
+				writeS3Error(w, http.StatusBadRequest, "InvalidPart")
+				return
+			}
+			content = append(content, parts[p.PartNumber]...)
+		}
+		f.objects[key] = content
+		delete(f.uploads, q.Get("uploadId"))
+		_, _ = io.WriteString(w, "<CompleteMultipartUploadResult/>")
+	case r.Method == http.MethodDelete && q.Has("uploadId"):
+		delete(f.uploads, q.Get("uploadId"))
+		w.WriteHeader(http.StatusNoContent)
+	case r.Method == http.MethodPut && r.Header.Get("X-Amz-Copy-Source") != "":
+		if f.failCopyInOK {
+			writeS3Error(w, http.StatusOK, "InternalError")
+			return
+		}
+		src, _ := url.PathUnescape(strings.TrimPrefix(r.Header.Get("X-Amz-Copy-Source"), "/"+f.bucket+"/"))
+		content, ok := f.objects[src]
+		if !ok {
+			writeS3Error(w, http.StatusNotFound, "NoSuchKey")
+			return
+		}
+		f.objects[key] = slices.Clone(content)
+		_, _ = io.WriteString(w, "<CopyObjectResult/>")
+	case r.Method == http.MethodPut:
+		f.objects[key] = body
+		f.singlePutSize = append(f.singlePutSize, len(body))
+	case r.Method == http.MethodGet, r.Method == http.MethodHead:
+		content, ok := f.objects[key]
+		if !ok {
+			writeS3Error(w, http.StatusNotFound, "NoSuchKey")
+			return
+		}
+		w.Header().Set("Content-Length", strconv.Itoa(len(content)))
+		if r.Method == http.MethodGet {
+			_, _ = w.Write(content)
+		}
+	case r.Method == http.MethodDelete:
+		delete(f.objects, key)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		writeS3Error(w, http.StatusMethodNotAllowed, "MethodNotAllowed")
+	}
+}
+
+// list answers ListObjectsV2. The continuation token is the last key or prefix returned
+func (f *fakeS3) list(w http.ResponseWriter, q map[string][]string) {
+	get := func(name string) string {
+		if vals := q[name]; len(vals) > 0 {
+			return vals[0]
+		}
+		return ""
+	}
+	prefix, delimiter, token := get("prefix"), get("delimiter"), get("continuation-token")
+	maxKeys := 1000
+	if v := get("max-keys"); v != "" {
+		maxKeys, _ = strconv.Atoi(v)
+	}
+	keys := slices.Sorted(func(yield func(string) bool) {
+		for k := range f.objects {
+			if !yield(k) {
+				return
+			}
+		}
+	})
+	var b strings.Builder
+	b.WriteString("<ListBucketResult>")
+	cnt := 0
+	last := ""
+	truncated := false
+	for _, k := range keys {
+		if !strings.HasPrefix(k, prefix) {
+			continue
+		}
+		name := k
+		isPrefix := false
+		if delimiter != "" {
+			if i := strings.Index(k[len(prefix):], delimiter); i >= 0 {
+				name = k[:len(prefix)+i+len(delimiter)]
+				isPrefix = true
+			}
+		}
+		if name <= token || name == last {
+			continue
+		}
+		if cnt == maxKeys {
+			truncated = true
+			break
+		}
+		cnt++
+		last = name
+		if isPrefix {
+			_, _ = fmt.Fprintf(&b, "<CommonPrefixes><Prefix>%s</Prefix></CommonPrefixes>", name)
+			continue
+		}
+		_, _ = fmt.Fprintf(&b, "<Contents><Key>%s</Key><LastModified>2024-01-02T03:04:05.000Z</LastModified><ETag>&quot;e&quot;</ETag><Size>%d</Size></Contents>",
+			name, len(f.objects[k]))
+	}
+	if truncated {
+		_, _ = fmt.Fprintf(&b, "<IsTruncated>true</IsTruncated><NextContinuationToken>%s</NextContinuationToken>", last)
+	}
+	b.WriteString("</ListBucketResult>")
+	_, _ = io.WriteString(w, b.String())
+}
+
+func TestStorageRoundTrip(t *testing.T) {
+	f, srv := newFakeS3(t)
+	s := f.storage(t, srv, Conf{Prefix: "pre/"})
+	ctx := context.Background()
+
+	if err := s.Put(ctx, "dir/a b.txt", strings.NewReader("hello")); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := f.objects["pre/dir/a b.txt"]; !ok {
+		t.Fatalf("object not stored under the prefix: %v", f.objects)
+	}
+	exists, err := s.Exists(ctx, "dir/a b.txt")
+	if err != nil || !exists {
+		t.Fatalf("Exists = %v, %v", exists, err)
+	}
+	size, err := s.Size(ctx, "dir/a b.txt")
+	if err != nil || size != 5 {
+		t.Fatalf("Size = %d, %v", size, err)
+	}
+	rc, err := s.Get(ctx, "dir/a b.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, _ := io.ReadAll(rc)
+	_ = rc.Close()
+	if string(got) != "hello" {
+		t.Fatalf("Get = %q", got)
+	}
+	if err = s.Move(ctx, "dir/a b.txt", "moved.txt"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = s.Get(ctx, "dir/a b.txt"); !errors.Is(err, fs.ErrNotExist) {
+		t.Fatalf("Get after move: %v", err)
+	}
+	if exists, _ = s.Exists(ctx, "moved.txt"); !exists {
+		t.Fatal("moved object missing")
+	}
+}
+
+func TestSignatureRejected(t *testing.T) {
+	f, srv := newFakeS3(t)
+	s := f.storage(t, srv, Conf{})
+	s.signer = &signer{accessKeyID: "AKID", secretAccessKey: "WRONG", region: "test-1"}
+	var s3Err *Error
+	if err := s.Put(context.Background(), "x", strings.NewReader("x")); !errors.As(err, &s3Err) || s3Err.Code != "SignatureDoesNotMatch" {
+		t.Fatalf("Put with a wrong secret: %v", err)
+	}
+}
+
+func TestPutMultipart(t *testing.T) {
+	f, srv := newFakeS3(t)
+	s := f.storage(t, srv, Conf{MultipartThreshold: 1 << 10, PartSize: minPartSize})
+	content := bytes.Repeat([]byte("0123456789"), int(minPartSize)/10+100) // 2 parts, the last one short
+	if err := s.Put(context.Background(), "big", bytes.NewReader(content)); err != nil {
+		t.Fatal(err)
+	}
+	if f.partPuts != 2 || len(f.singlePutSize) != 0 {
+		t.Fatalf("%d part puts, %d single puts", f.partPuts, len(f.singlePutSize))
+	}
+	if !bytes.Equal(f.objects["big"], content) {
+		t.Fatal("multipart content mismatch")
+	}
+	if len(f.uploads) != 0 {
+		t.Fatal("upload left open")
+	}
+
+	// at the threshold: a single put
+	if err := s.Put(context.Background(), "edge", bytes.NewReader(content[:1<<10])); err != nil {
+		t.Fatal(err)
+	}
+	if len(f.singlePutSize) != 1 || f.singlePutSize[0] != 1<<10 {
+		t.Fatalf("single puts %v", f.singlePutSize)
+	}
+}
+
+type failingReader struct {
+	r   io.Reader
+	err error
+}
+
+func (r *failingReader) Read(p []byte) (int, error) {
+	n, err := r.r.Read(p)
+	if err == io.EOF {
+		return n, r.err
+	}
+	return n, err
+}
+
+func TestPutMultipartAbortsOnReadError(t *testing.T) {
+	f, srv := newFakeS3(t)
+	s := f.storage(t, srv, Conf{MultipartThreshold: 1 << 10, PartSize: minPartSize})
+	readErr := errors.New("source broken")
+	src := &failingReader{r: bytes.NewReader(make([]byte, minPartSize+10)), err: readErr}
+	if err := s.Put(context.Background(), "big", src); !errors.Is(err, readErr) {
+		t.Fatalf("Put = %v", err)
+	}
+	if len(f.uploads) != 0 {
+		t.Fatal("failed upload not aborted")
+	}
+	if _, ok := f.objects["big"]; ok {
+		t.Fatal("failed upload completed")
+	}
+}
+
+func TestCopy(t *testing.T) {
+	f, srv := newFakeS3(t)
+	s := f.storage(t, srv, Conf{})
+	ctx := context.Background()
+	_ = s.Put(ctx, "src", strings.NewReader("data"))
+	if err := s.Copy(ctx, "src", "dst"); err != nil {
+		t.Fatal(err)
+	}
+	if string(f.objects["dst"]) != "data" {
+		t.Fatal("copy content mismatch")
+	}
+	if err := s.Copy(ctx, "missing", "dst2"); !errors.Is(err, fs.ErrNotExist) {
+		t.Fatalf("Copy of a missing object: %v", err)
+	}
+
+	// S3 may report a failed copy in the body of a 200
+	f.failCopyInOK = true
+	var s3Err *Error
+	if err := s.Copy(ctx, "src", "dst3"); !errors.As(err, &s3Err) || s3Err.Code != "InternalError" {
+		t.Fatalf("Copy with an error body: %v", err)
+	}
+	if err := s.Move(ctx, "src", "dst3"); err == nil {
+		t.Fatal("Move went on after a failed copy")
+	}
+	if _, ok := f.objects["src"]; !ok {
+		t.Fatal("Move deleted the source after a failed copy")
+	}
+}
+
+func TestList(t *testing.T) {
+	f, srv := newFakeS3(t)
+	s := f.storage(t, srv, Conf{Prefix: "pre/"})
+	ctx := context.Background()
+	for _, p := range []string{"a.txt", "docs/1.txt", "docs/2.txt", "docs/sub/3.txt", "z.txt"} {
+		if err := s.Put(ctx, p, strings.NewReader(p)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	f.objects["other/x"] = []byte("outside the storage prefix")
+
+	page, err := s.List(ctx, storages.ListOptions{Delimiter: "/"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var paths []string
+	for _, o := range page.Objects {
+		paths = append(paths, o.Path)
+	}
+	if !slices.Equal(paths, []string{"a.txt", "z.txt"}) || !slices.Equal(page.CommonPrefixes, []string{"docs/"}) {
+		t.Fatalf("objects %v, prefixes %v", paths, page.CommonPrefixes)
+	}
+	if o := page.Objects[0]; o.Size != 5 || o.ETag != `"e"` || o.ModTime.IsZero() {
+		t.Fatalf("object info %+v", o)
+	}
+
+	// pages of 2 under docs/, without delimiter
+	var all []string
+	opts := storages.ListOptions{Prefix: "docs/", Limit: 2}
+	for {
+		page, err = s.List(ctx, opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, o := range page.Objects {
+			all = append(all, o.Path)
+		}
+		if page.NextCursor == "" {
+			break
+		}
+		opts.Cursor = page.NextCursor
+	}
+	if !slices.Equal(all, []string{"docs/1.txt", "docs/2.txt", "docs/sub/3.txt"}) {
+		t.Fatalf("paged %v", all)
+	}
+}
@@ -0,0 +1,43 @@
+package throttle
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+type Backend string
+
+const (
+	BackendMemory Backend = "memory" // MemoryBucketBackend
+	BackendKVDB   Backend = "kvdb"   // KVDBBucketBackend
+)
+
+// Conf is the throttle config (.throttle.json)
+type Conf struct {
+	Backend Backend `json:"backend"`
+}
+
+// BucketBackend holds the limiter states of a BucketStore
+type BucketBackend interface {
+	// Take takes a unit from the bucket by the group's Algorithm. A new bucket starts full.
+	// If allowed, Decision.Release must be called once the request ends (frees an AlgorithmConcurrency slot)
+	Take(ctx context.Context, g *BucketGroup, bucketID string, now time.Time) (Decision, error)
+}
+
+// MemoryBucketBackend keeps buckets in the BucketGroup maps of this process.
+// Behind a load balancer, the effective limit is multiplied by the number of instances.
+type MemoryBucketBackend struct{}
+
+func (MemoryBucketBackend) Take(_ context.Context, g *BucketGroup, bucketID string, now time.Time) (Decision, error) {
+	lAny, ok := g.buckets.Load(bucketID)
+	if !ok {
+		lAny, _ = g.buckets.LoadOrStore(bucketID, newLimiter(g, now))
+	}
+	l := lAny.(limiter)
+	d := l.take(now)
+	if d.Allowed && g.conf.Algorithm == AlgorithmConcurrency {
+		d.release = sync.OnceFunc(l.release)
+	}
+	return d, nil
+}
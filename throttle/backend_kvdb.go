@@ -0,0 +1,256 @@
+package throttle
+
+import (
+	"context"
+	"crypto/rand"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/x64c/gw/kvdbs"
+)
+
+// KeyThrottleBucket - token bucket state (KVDBBucketBackend). id = "<group ID>:<bucket ID>"
+var KeyThrottleBucket = &kvdbs.KeyFamily{
+	Name:      "throttle_bucket",
+	Pattern:   "throttle:{id}",
+	ValueType: kvdbs.ValueHash,
+	Fields:    []string{"tokens", "last"},
+	TTLPolicy: kvdbs.TTLSliding,
+	Desc:      "throttle token bucket by \"group:bucket\"",
+}
+
+// KeyThrottleWindow - sliding window log of request times in unix millis, oldest first (KVDBBucketBackend). id = "<group ID>:<bucket ID>"
+var KeyThrottleWindow = &kvdbs.KeyFamily{
+	Name:      "throttle_window",
+	Pattern:   "throttle_window:{id}",
+	ValueType: kvdbs.ValueList,
+	TTLPolicy: kvdbs.TTLSliding,
+	Desc:      "throttle sliding window log by \"group:bucket\"",
+}
+
+// KeyThrottleGCRA - GCRA theoretical arrival time in unix millis (KVDBBucketBackend). id = "<group ID>:<bucket ID>"
+var KeyThrottleGCRA = &kvdbs.KeyFamily{
+	Name:      "throttle_gcra",
+	Pattern:   "throttle_gcra:{id}",
+	ValueType: kvdbs.ValueString,
+	TTLPolicy: kvdbs.TTLSliding,
+	Desc:      "throttle GCRA arrival time by \"group:bucket\"",
+}
+
+// KeyThrottleConcurrency - in-flight request tokens -> slot expiry in unix millis (KVDBBucketBackend). id = "<group ID>:<bucket ID>"
+var KeyThrottleConcurrency = &kvdbs.KeyFamily{
+	Name:      "throttle_concurrency",
+	Pattern:   "throttle_concurrency:{id}",
+	ValueType: kvdbs.ValueHash,
+	TTLPolicy: kvdbs.TTLSliding,
+	Desc:      "throttle in-flight requests by \"group:bucket\"",
+}
+
+// KeyFamilies returns all KVDB key families of KVDBBucketBackend
+func KeyFamilies() []*kvdbs.KeyFamily {
+	return []*kvdbs.KeyFamily{KeyThrottleBucket, KeyThrottleWindow, KeyThrottleGCRA, KeyThrottleConcurrency}
+}
+
+// Limiter scripts return {allowed (1|0), remaining, reset (ms), retry after (ms)}. See Decision
+
+// takeScript refills and takes a token as Bucket.Decide does, in one atomic round trip.
+// The key expires once the bucket would be full again, which is the same as a missing bucket.
+// KEYS[1] bucket. ARGV: burst, increment, period (ms), now (unix ms)
+const takeScript = `
+local burst = tonumber(ARGV[1])
+local incr = tonumber(ARGV[2])
+local period = tonumber(ARGV[3])
+local now = tonumber(ARGV[4])
+local state = redis.call('HMGET', KEYS[1], 'tokens', 'last')
+local tokens = tonumber(state[1])
+local last = tonumber(state[2])
+if tokens == nil or last == nil then
+	tokens = burst
+	last = now
+elseif now - last >= period then
+	local times = math.floor((now - last) / period)
+	tokens = math.min(burst, tokens + times * incr)
+	last = last + times * period
+end
+local allowed = 0
+local retry = 0
+if tokens > 0 then
+	tokens = tokens - 1
+	allowed = 1
+else
+	retry = last + period - now
+end
+local reset = 0
+if incr > 0 and tokens < burst then
+	reset = last + math.ceil((burst - tokens) / incr) * period - now
+end
+redis.call('HSET', KEYS[1], 'tokens', tokens, 'last', last)
+redis.call('PEXPIRE', KEYS[1], math.max(reset, 0) + period)
+return {allowed, math.max(tokens, 0), reset, retry}
+`
+
+// windowScript drops the times out of the window and appends now if fewer than limit remain.
+// KEYS[1] log. ARGV: limit, window (ms), now (unix ms)
+const windowScript = `
+local limit = tonumber(ARGV[1])
+local window = tonumber(ARGV[2])
+local now = tonumber(ARGV[3])
+while true do
+	local oldest = redis.call('LINDEX', KEYS[1], 0)
+	if not oldest or tonumber(oldest) > now - window then
+		break
+	end
+	redis.call('LPOP', KEYS[1])
+end
+local count = redis.call('LLEN', KEYS[1])
+local allowed = 0
+local retry = 0
+if count < limit then
+	redis.call('RPUSH', KEYS[1], now)
+	redis.call('PEXPIRE', KEYS[1], window)
+	count = count + 1
+	allowed = 1
+elseif count > 0 then
+	retry = tonumber(redis.call('LINDEX', KEYS[1], 0)) + window - now
+end
+local reset = 0
+if count > 0 then
+	reset = tonumber(redis.call('LINDEX', KEYS[1], -1)) + window - now
+end
+return {allowed, math.max(limit - count, 0), reset, retry}
+`
+
+// gcraScript advances the theoretical arrival time by the emission interval if within the burst tolerance.
+// KEYS[1] TAT. ARGV: interval (ms), tolerance (ms), now (unix ms)
+const gcraScript = `
+local interval = tonumber(ARGV[1])
+local tolerance = tonumber(ARGV[2])
+local now = tonumber(ARGV[3])
+local tat = tonumber(redis.call('GET', KEYS[1]))
+if tat == nil or tat < now then
+	tat = now
+end
+local new_tat = tat + interval
+local allowed = 0
+local retry = 0
+if new_tat - now <= tolerance then
+	tat = new_tat
+	redis.call('SET', KEYS[1], tat, 'PX', math.max(tat - now, 1))
+	allowed = 1
+else
+	retry = new_tat - tolerance - now
+end
+local remaining = 0
+if interval > 0 then
+	remaining = math.floor((tolerance - (tat - now)) / interval)
+end
+return {allowed, remaining, tat - now, retry}
+`
+
+// concurrencyScript drops expired slots and takes one for the token if fewer than limit are held.
+// KEYS[1] slots. ARGV: limit, hold (ms), now (unix ms), token, retry after (ms)
+const concurrencyScript = `
+local limit = tonumber(ARGV[1])
+local hold = tonumber(ARGV[2])
+local now = tonumber(ARGV[3])
+local slots = redis.call('HGETALL', KEYS[1])
+local held = 0
+for i = 1, #slots, 2 do
+	if tonumber(slots[i + 1]) <= now then
+		redis.call('HDEL', KEYS[1], slots[i])
+	else
+		held = held + 1
+	end
+end
+if held >= limit then
+	return {0, 0, 0, tonumber(ARGV[5])}
+end
+redis.call('HSET', KEYS[1], ARGV[4], now + hold)
+redis.call('PEXPIRE', KEYS[1], hold)
+return {1, limit - held - 1, 0, 0}
+`
+
+// releaseScript frees the slot of the token. KEYS[1] slots. ARGV: token
+const releaseScript = `
+return redis.call('HDEL', KEYS[1], ARGV[1])
+`
+
+// KVDBBucketBackend keeps buckets in a KVDB shared by all app instances, so limits hold across a cluster.
+// The DB must implement kvdbs.ScriptDB.
+type KVDBBucketBackend struct {
+	db      kvdbs.ScriptDB
+	appName string
+}
+
+func NewKVDBBucketBackend(db kvdbs.DB, appName string) (*KVDBBucketBackend, error) {
+	scriptDB, ok := db.(kvdbs.ScriptDB)
+	if !ok {
+		return nil, kvdbs.ErrNotSupported
+	}
+	return &KVDBBucketBackend{db: scriptDB, appName: appName}, nil
+}
+
+func (b *KVDBBucketBackend) Take(ctx context.Context, g *BucketGroup, bucketID string, now time.Time) (Decision, error) {
+	id := g.id + ":" + bucketID
+	conf := g.conf
+	switch conf.Algorithm {
+	case AlgorithmSlidingWindow:
+		return b.eval(ctx, conf.Limit, windowScript, KeyThrottleWindow.Key(b.appName, id),
+			conf.Limit, conf.Window.Milliseconds(), now.UnixMilli())
+	case AlgorithmGCRA:
+		interval, tolerance := gcraParams(conf)
+		return b.eval(ctx, max(conf.Burst, 1), gcraScript, KeyThrottleGCRA.Key(b.appName, id),
+			interval.Milliseconds(), tolerance.Milliseconds(), now.UnixMilli())
+	case AlgorithmConcurrency:
+		return b.takeSlot(ctx, KeyThrottleConcurrency.Key(b.appName, id), conf, now)
+	default:
+		return b.eval(ctx, conf.Burst, takeScript, KeyThrottleBucket.Key(b.appName, id),
+			conf.Burst, conf.Increment, conf.IncrPeriod.Milliseconds(), now.UnixMilli())
+	}
+}
+
+func (b *KVDBBucketBackend) takeSlot(ctx context.Context, key string, conf *BucketConf, now time.Time) (Decision, error) {
+	hold := conf.Window
+	if hold <= 0 {
+		hold = defaultConcurrencyHold
+	}
+	token := rand.Text()
+	d, err := b.eval(ctx, conf.Limit, concurrencyScript, key,
+		conf.Limit, hold.Milliseconds(), now.UnixMilli(), token, concurrencyRetryAfter.Milliseconds())
+	if err != nil || !d.Allowed {
+		return d, err
+	}
+	d.release = sync.OnceFunc(func() {
+		if _, err := b.db.Eval(context.WithoutCancel(ctx), releaseScript, []string{key}, token); err != nil {
+			log.Printf("[WARN][Throttle] release %s: %v. freed on expiry", key, err)
+		}
+	})
+	return d, nil
+}
+
+// eval runs a limiter script
+func (b *KVDBBucketBackend) eval(ctx context.Context, limit int, script string, key string, args ...any) (Decision, error) {
+	res, err := b.db.Eval(ctx, script, []string{key}, args...)
+	if err != nil {
+		return Decision{}, err
+	}
+	vals, ok := res.([]any)
+	if !ok || len(vals) != 4 {
+		return Decision{}, fmt.Errorf("throttle: unexpected script result %v", res)
+	}
+	var nums [4]int64
+	for i, v := range vals {
+		if nums[i], ok = v.(int64); !ok {
+			return Decision{}, fmt.Errorf("throttle: unexpected script result %v", res)
+		}
+	}
+	return Decision{
+		Allowed:    nums[0] == 1,
+		Limit:      limit,
+		Remaining:  int(nums[1]),
+		Reset:      time.Duration(nums[2]) * time.Millisecond,
+		RetryAfter: time.Duration(nums[3]) * time.Millisecond,
+	}, nil
+}
@@ -0,0 +1,152 @@
+package throttle
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/x64c/gw/kvdbs"
+	"github.com/x64c/gw/kvdbs/kvdbtest"
+)
+
+type evalCall struct {
+	script string
+	keys   []string
+	args   []any
+	ctxErr error
+}
+
+// fakeScriptDB records the scripts run and answers with results in order
+type fakeScriptDB struct {
+	kvdbs.DB
+	calls   []evalCall
+	results []any
+	err     error
+}
+
+func (f *fakeScriptDB) Eval(ctx context.Context, script string, keys []string, args ...any) (any, error) {
+	f.calls = append(f.calls, evalCall{script: script, keys: keys, args: args, ctxErr: ctx.Err()})
+	if f.err != nil {
+		return nil, f.err
+	}
+	if len(f.results) == 0 {
+		return []any{int64(1), int64(0), int64(0), int64(0)}, nil
+	}
+	res := f.results[0]
+	f.results = f.results[1:]
+	return res, nil
+}
+
+func newTestKVDBBackend(t *testing.T) (*KVDBBucketBackend, *fakeScriptDB) {
+	if _, err := NewKVDBBucketBackend(kvdbtest.New(), "app"); !errors.Is(err, kvdbs.ErrNotSupported) {
+		t.Fatalf("DB without scripts: %v", err)
+	}
+	db := &fakeScriptDB{DB: kvdbtest.New()}
+	b, err := NewKVDBBucketBackend(db, "app")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b, db
+}
+
+func TestKVDBBackendScriptArgs(t *testing.T) {
+	now := time.UnixMilli(1_700_000_000_000)
+	tests := []struct {
+		conf   *BucketConf
+		script string
+		key    string
+		args   []any
+		limit  int
+	}{
+		{&BucketConf{Burst: 5, Increment: 2, IncrPeriod: time.Second}, takeScript,
+			"app:throttle:g%3Ab", []any{5, 2, int64(1000), now.UnixMilli()}, 5},
+		{&BucketConf{Algorithm: AlgorithmSlidingWindow, Limit: 3, Window: time.Minute}, windowScript,
+			"app:throttle_window:g%3Ab", []any{3, int64(60_000), now.UnixMilli()}, 3},
+		{&BucketConf{Algorithm: AlgorithmGCRA, Limit: 10, Window: 10 * time.Second, Burst: 3}, gcraScript,
+			"app:throttle_gcra:g%3Ab", []any{int64(1000), int64(3000), now.UnixMilli()}, 3},
+	}
+	for _, tt := range tests {
+		b, db := newTestKVDBBackend(t)
+		db.results = []any{[]any{int64(0), int64(2), int64(1500), int64(250)}}
+		d, err := b.Take(context.Background(), &BucketGroup{id: "g", conf: tt.conf}, "b", now)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if d.Allowed || d.Limit != tt.limit || d.Remaining != 2 || d.Reset != 1500*time.Millisecond || d.RetryAfter != 250*time.Millisecond {
+			t.Errorf("%s: decision %+v", tt.key, d)
+		}
+		if c := db.calls[0]; c.script != tt.script || !reflect.DeepEqual(c.keys, []string{tt.key}) || !reflect.DeepEqual(c.args, tt.args) {
+			t.Errorf("%s: ran keys %v args %v", tt.key, c.keys, c.args)
+		}
+	}
+}
+
+func TestKVDBBackendBadResults(t *testing.T) {
+	g := &BucketGroup{id: "g", conf: &BucketConf{Burst: 1, Increment: 1, IncrPeriod: time.Second}}
+	for _, res := range []any{
+		nil,
+		int64(1),
+		[]any{int64(1), int64(0), int64(0)},
+		[]any{int64(1), int64(0), int64(0), int64(0), int64(0)},
+		[]any{int64(1), "0", int64(0), int64(0)},
+		[]any{int64(1), int64(0), nil, int64(0)},
+	} {
+		b, db := newTestKVDBBackend(t)
+		db.results = []any{res}
+		if d, err := b.Take(context.Background(), g, "b", time.Now()); err == nil {
+			t.Errorf("%#v: decision %+v", res, d)
+		}
+	}
+	b, db := newTestKVDBBackend(t)
+	db.err = errors.New("connection refused")
+	if _, err := b.Take(context.Background(), g, "b", time.Now()); !errors.Is(err, db.err) {
+		t.Errorf("eval error: %v", err)
+	}
+}
+
+func TestKVDBBackendReleasesSlot(t *testing.T) {
+	now := time.UnixMilli(1_700_000_000_000)
+	g := &BucketGroup{id: "g", conf: &BucketConf{Algorithm: AlgorithmConcurrency, Limit: 2}}
+	b, db := newTestKVDBBackend(t)
+	db.results = []any{[]any{int64(1), int64(1), int64(0), int64(0)}}
+	ctx, cancel := context.WithCancel(context.Background())
+	d, err := b.Take(ctx, g, "b", now)
+	if err != nil || !d.Allowed {
+		t.Fatalf("Take = %+v, %v", d, err)
+	}
+	take := db.calls[0]
+	key := []string{"app:throttle_concurrency:g%3Ab"}
+	if take.script != concurrencyScript || !reflect.DeepEqual(take.keys, key) || len(take.args) != 5 {
+		t.Fatalf("ran keys %v args %v", take.keys, take.args)
+	}
+	if take.args[0] != 2 || take.args[1] != defaultConcurrencyHold.Milliseconds() || take.args[2] != now.UnixMilli() ||
+		take.args[4] != concurrencyRetryAfter.Milliseconds() {
+		t.Fatalf("args %v", take.args)
+	}
+
+	cancel() // the request is over before the slot is released
+	d.Release()
+	d.Release()
+	if len(db.calls) != 2 {
+		t.Fatalf("%d scripts run", len(db.calls))
+	}
+	release := db.calls[1]
+	if release.script != releaseScript || !reflect.DeepEqual(release.keys, key) || !reflect.DeepEqual(release.args, []any{take.args[3]}) {
+		t.Fatalf("released keys %v args %v", release.keys, release.args)
+	}
+	if release.ctxErr != nil {
+		t.Fatalf("released with a done context: %v", release.ctxErr)
+	}
+
+	// a refused request holds no slot
+	db.results = []any{[]any{int64(0), int64(0), int64(0), int64(1000)}}
+	if d, err = b.Take(context.Background(), g, "b", now); err != nil || d.Allowed {
+		t.Fatalf("Take = %+v, %v", d, err)
+	}
+	d.Release()
+	if len(db.calls) != 3 {
+		t.Fatalf("%d scripts run", len(db.calls))
+	}
+}
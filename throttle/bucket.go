@@ -28,13 +28,27 @@ func (b *Bucket) refill(now time.Time) {
 }
 
 func (b *Bucket) Allow(now time.Time) bool {
+	return b.Decide(now).Allowed
+}
+
+// Decide takes a token if any, reporting the bucket state
+func (b *Bucket) Decide(now time.Time) Decision {
 	b.mu.Lock()
 	defer b.mu.Unlock()
 
 	b.refill(now)
-	if b.tokens <= 0 {
-		return false
+	conf := b.parentGroup.conf
+	d := Decision{Limit: conf.Burst}
+	if b.tokens > 0 {
+		b.tokens--
+		d.Allowed = true
+	} else {
+		d.RetryAfter = b.lastCheck.Add(conf.IncrPeriod).Sub(now)
+	}
+	d.Remaining = max(b.tokens, 0)
+	if missing := conf.Burst - b.tokens; missing > 0 && conf.Increment > 0 {
+		periods := (missing + conf.Increment - 1) / conf.Increment
+		d.Reset = b.lastCheck.Add(time.Duration(periods) * conf.IncrPeriod).Sub(now)
 	}
-	b.tokens--
-	return true
+	return d
 }
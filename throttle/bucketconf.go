@@ -1,9 +1,54 @@
 package throttle
 
-import "time"
+import (
+	"fmt"
+	"time"
+)
+
+type Algorithm string
+
+const (
+	AlgorithmTokenBucket   Algorithm = "token_bucket"   // Burst tokens, refilled by Increment every IncrPeriod
+	AlgorithmSlidingWindow Algorithm = "sliding_window" // sliding window log: at most Limit requests in any Window
+	AlgorithmGCRA          Algorithm = "gcra"           // generic cell rate: Limit requests per Window, evenly paced, bursts up to Burst
+	AlgorithmConcurrency   Algorithm = "concurrency"    // at most Limit requests in flight
+)
+
+// defaultConcurrencyHold bounds how long a concurrency slot is held on the KVDB backend if never released (crashed instance)
+const defaultConcurrencyHold = 5 * time.Minute
 
 type BucketConf struct {
-	Burst      int           // maximum number of tokens in the bucket
+	Algorithm  Algorithm     // "" = AlgorithmTokenBucket
+	Burst      int           // maximum number of tokens in the bucket. GCRA: max burst (default 1)
 	Increment  int           // how many tokens to add each period
 	IncrPeriod time.Duration // how often to add Increment
+	Limit      int           // sliding window, GCRA: requests per Window. concurrency: requests in flight
+	Window     time.Duration // sliding window, GCRA: the period of Limit. concurrency (KVDB): max slot hold. default 5m
+}
+
+// Validate reports a conf its Algorithm cannot limit with: fields left zero would allow or block every request
+func (c *BucketConf) Validate() error {
+	switch c.Algorithm {
+	case AlgorithmTokenBucket, "":
+		// Increment 0 is a fixed quota of Burst
+		if c.Burst <= 0 || c.Increment < 0 || c.IncrPeriod <= 0 {
+			return fmt.Errorf("throttle: token bucket needs positive Burst and IncrPeriod, and Increment >= 0: %+v", *c)
+		}
+	case AlgorithmSlidingWindow:
+		if c.Limit <= 0 || c.Window <= 0 {
+			return fmt.Errorf("throttle: sliding window needs positive Limit and Window: %+v", *c)
+		}
+	case AlgorithmGCRA:
+		// the KVDB backend paces in milliseconds
+		if c.Limit <= 0 || c.Window/time.Duration(c.Limit) < time.Millisecond || c.Burst < 0 {
+			return fmt.Errorf("throttle: gcra needs positive Limit, Window of at least Limit ms, and Burst >= 0: %+v", *c)
+		}
+	case AlgorithmConcurrency:
+		if c.Limit <= 0 || c.Window < 0 {
+			return fmt.Errorf("throttle: concurrency needs positive Limit: %+v", *c)
+		}
+	default:
+		return fmt.Errorf("throttle: unknown algorithm %q", c.Algorithm)
+	}
+	return nil
 }
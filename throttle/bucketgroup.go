@@ -6,8 +6,9 @@ import (
 )
 
 type BucketGroup struct {
+	id      string
 	conf    *BucketConf
-	buckets *sync.Map // string -> *Bucket
+	buckets *sync.Map // string -> limiter (*Bucket for AlgorithmTokenBucket). MemoryBucketBackend
 }
 
 func (g *BucketGroup) GetBucket(id string) (*Bucket, bool) {
@@ -15,7 +16,8 @@ func (g *BucketGroup) GetBucket(id string) (*Bucket, bool) {
 	if !ok {
 		return nil, false
 	}
-	return bAny.(*Bucket), true
+	b, ok := bAny.(*Bucket)
+	return b, ok
 }
 
 func (g *BucketGroup) SetBucket(id string, tokens int, now time.Time) {
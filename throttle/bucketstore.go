@@ -18,6 +18,7 @@ type BucketStore struct {
 	cleanupCycle     time.Duration
 	cleanupOlderThan time.Duration
 	groups           map[string]*BucketGroup // groupID -> *BucketGroup
+	backend          BucketBackend           // UseBackend. MemoryBucketBackend by default
 }
 
 func (s *BucketStore) Name() string {
@@ -34,9 +35,15 @@ func NewBucketStore(parentCtx context.Context, cleanupCycle time.Duration, clean
 		cleanupCycle:     cleanupCycle,
 		cleanupOlderThan: cleanupOlderThan,
 		groups:           make(map[string]*BucketGroup),
+		backend:          MemoryBucketBackend{},
 	}
 }
 
+// UseBackend switches where buckets are kept. Call before setting up the bucket groups' traffic
+func (s *BucketStore) UseBackend(backend BucketBackend) {
+	s.backend = backend
+}
+
 // Start starts a service that manages buckets
 func (s *BucketStore) Start() error {
 	if s.state == svc.StateRUNNING {
@@ -101,28 +108,40 @@ func (s *BucketStore) GetBucket(groupID string, bucketID string) (*Bucket, bool)
 	return g.GetBucket(bucketID)
 }
 
+// SetBucketGroup sets up a bucket group. It panics if the conf does not Validate, as a setup mistake
 func (s *BucketStore) SetBucketGroup(id string, conf *BucketConf) {
+	if err := conf.Validate(); err != nil {
+		panic(fmt.Errorf("bucket group %q: %w", id, err))
+	}
 	s.groups[id] = &BucketGroup{
+		id:      id,
 		conf:    conf,
 		buckets: &sync.Map{},
 	}
 }
 
+// Allow takes a unit from the bucket. For rate algorithms only: an AlgorithmConcurrency slot taken by Allow is never freed. Use Decide
 func (s *BucketStore) Allow(groupID string, bucketID string, now time.Time) bool {
+	return s.Decide(groupID, bucketID, now).Allowed
+}
+
+// Decide takes a unit from the bucket by the group's Algorithm, reporting the quota state.
+// If allowed, call Decision.Release once the request ends (frees an AlgorithmConcurrency slot. no-op otherwise)
+func (s *BucketStore) Decide(groupID string, bucketID string, now time.Time) Decision {
 	g, ok := s.GetBucketGroup(groupID)
 	if !ok {
-		return false // Invalid groupID -> always Blocked
+		return Decision{} // Invalid groupID -> always Blocked
 	}
-	b, ok := g.GetBucket(bucketID)
-	if ok {
-		return b.Allow(now)
+	d, err := s.backend.Take(s.Ctx, g, bucketID, now)
+	if err != nil {
+		// fail open. an unavailable backend must not take the app down with it
+		log.Printf("[WARN][Throttle] bucket %s/%s: %v. allowed", groupID, bucketID, err)
+		return Decision{Allowed: true}
 	}
-	// consume 1 token from the fresh bucket
-	g.SetBucket(bucketID, g.conf.Burst-1, now)
-	return true
+	return d
 }
 
-// Inspect returns a snapshot of all BucketGroup IDs and their local Bucket IDs.
+// Inspect returns a snapshot of all BucketGroup IDs and their local Bucket IDs (MemoryBucketBackend).
 // It does not lock globally, so results may be slightly inconsistent
 // if buckets are being modified concurrently — which is fine for inspection.
 func (s *BucketStore) Inspect() map[string][]string {
@@ -13,15 +13,10 @@ func (s *BucketStore) Cleanup(now time.Time) {
 	for gid, g := range s.groups {
 		log.Printf("[DEBUG][Throttle] cleaning BucketGroup %q", gid)
 		g.buckets.Range(func(id, value any) bool {
-			b := value.(*Bucket)
+			log.Printf("[DEBUG][Throttle] inspecting Bucket id=%q (%T)", id, value)
 
-			// lock per bucket while checking/removing
-			b.mu.Lock()
-			last := b.lastCheck
-			log.Printf("[DEBUG][Throttle] inspecting Bucket id=%q lastCheck=%v", id, last)
-			b.mu.Unlock()
-
-			if now.Sub(last) > s.cleanupOlderThan {
+			// locks per bucket while checking
+			if value.(limiter).idle(now, s.cleanupOlderThan) {
 				g.buckets.Delete(id)
 				cleanCnt++
 				log.Println("[DEBUG][Throttle] Bucket REMOVED")
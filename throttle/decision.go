@@ -0,0 +1,24 @@
+package throttle
+
+import "time"
+
+// Decision is the outcome of taking a unit from a bucket, with the quota state for rate-limit headers
+type Decision struct {
+	Allowed    bool
+	Limit      int           // quota size. token bucket: Burst. sliding window, concurrency: Limit. GCRA: Burst
+	Remaining  int           // units left after this request
+	Reset      time.Duration // until the full quota is available again. 0 for concurrency limits
+	RetryAfter time.Duration // until the next unit is available. 0 if allowed
+
+	release func()
+}
+
+// Release ends the request, freeing its AlgorithmConcurrency slot. No-op otherwise, and safe to call more than once
+func (d Decision) Release() {
+	if d.release != nil {
+		d.release()
+	}
+}
+
+// concurrencyRetryAfter is the retry hint of a denied concurrency limit. When a slot frees is unknown
+const concurrencyRetryAfter = time.Second
@@ -0,0 +1,164 @@
+package throttle
+
+import (
+	"sync"
+	"time"
+)
+
+// limiter is the in-memory state of a bucket ID in a BucketGroup (MemoryBucketBackend)
+type limiter interface {
+	take(now time.Time) Decision
+	release()
+	// idle reports whether the state is unused for olderThan and may be dropped (Cleanup)
+	idle(now time.Time, olderThan time.Duration) bool
+}
+
+func newLimiter(g *BucketGroup, now time.Time) limiter {
+	conf := g.conf
+	switch conf.Algorithm {
+	case AlgorithmSlidingWindow:
+		return &windowLog{conf: conf}
+	case AlgorithmGCRA:
+		return &gcra{conf: conf, tat: now}
+	case AlgorithmConcurrency:
+		return &concurrency{conf: conf, lastUsed: now}
+	default:
+		return &Bucket{tokens: conf.Burst, lastCheck: now, parentGroup: g}
+	}
+}
+
+func (b *Bucket) take(now time.Time) Decision {
+	return b.Decide(now)
+}
+
+func (b *Bucket) release() {}
+
+func (b *Bucket) idle(now time.Time, olderThan time.Duration) bool {
+	b.mu.Lock()
+	last := b.lastCheck
+	b.mu.Unlock()
+	return now.Sub(last) > olderThan
+}
+
+// windowLog keeps the times of the requests allowed within the last Window (at most Limit)
+type windowLog struct {
+	mu    sync.Mutex
+	conf  *BucketConf
+	times []time.Time // oldest first
+}
+
+func (l *windowLog) take(now time.Time) Decision {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	start := now.Add(-l.conf.Window)
+	expired := 0
+	for expired < len(l.times) && !l.times[expired].After(start) {
+		expired++
+	}
+	l.times = append(l.times[:0], l.times[expired:]...)
+	d := Decision{Limit: l.conf.Limit}
+	if len(l.times) < l.conf.Limit {
+		l.times = append(l.times, now)
+		d.Allowed = true
+	} else if len(l.times) > 0 {
+		d.RetryAfter = l.times[0].Add(l.conf.Window).Sub(now)
+	}
+	d.Remaining = max(l.conf.Limit-len(l.times), 0)
+	if n := len(l.times); n > 0 {
+		d.Reset = l.times[n-1].Add(l.conf.Window).Sub(now)
+	}
+	return d
+}
+
+func (l *windowLog) release() {}
+
+func (l *windowLog) idle(now time.Time, olderThan time.Duration) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	// dropping a log still within its window would reset the limit
+	return len(l.times) == 0 || now.Sub(l.times[len(l.times)-1]) > max(olderThan, l.conf.Window)
+}
+
+// gcra keeps the theoretical arrival time (TAT) of the next request
+type gcra struct {
+	mu   sync.Mutex
+	conf *BucketConf
+	tat  time.Time
+}
+
+// gcraParams returns the emission interval and the burst tolerance of a GCRA conf
+func gcraParams(conf *BucketConf) (interval time.Duration, tolerance time.Duration) {
+	interval = conf.Window / time.Duration(max(conf.Limit, 1))
+	return interval, interval * time.Duration(max(conf.Burst, 1))
+}
+
+func (l *gcra) take(now time.Time) Decision {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	interval, tolerance := gcraParams(l.conf)
+	tat := l.tat
+	if tat.Before(now) {
+		tat = now
+	}
+	d := Decision{Limit: max(l.conf.Burst, 1)}
+	if next := tat.Add(interval); next.Sub(now) <= tolerance {
+		tat = next
+		l.tat = next
+		d.Allowed = true
+	} else {
+		d.RetryAfter = next.Add(-tolerance).Sub(now)
+	}
+	d.Remaining, d.Reset = gcraState(interval, tolerance, tat.Sub(now))
+	return d
+}
+
+// gcraState returns the remaining burst and the time until it is full, given how far the TAT is ahead of now
+func gcraState(interval time.Duration, tolerance time.Duration, ahead time.Duration) (int, time.Duration) {
+	if interval <= 0 {
+		return 0, 0
+	}
+	return int((tolerance - ahead) / interval), ahead
+}
+
+func (l *gcra) release() {}
+
+func (l *gcra) idle(now time.Time, olderThan time.Duration) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return now.Sub(l.tat) > olderThan
+}
+
+// concurrency counts the requests in flight
+type concurrency struct {
+	mu       sync.Mutex
+	conf     *BucketConf
+	inFlight int
+	lastUsed time.Time
+}
+
+func (l *concurrency) take(now time.Time) Decision {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.lastUsed = now
+	d := Decision{Limit: l.conf.Limit}
+	if l.inFlight < l.conf.Limit {
+		l.inFlight++
+		d.Allowed = true
+	} else {
+		d.RetryAfter = concurrencyRetryAfter
+	}
+	d.Remaining = max(l.conf.Limit-l.inFlight, 0)
+	return d
+}
+
+func (l *concurrency) release() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.inFlight--
+}
+
+func (l *concurrency) idle(now time.Time, olderThan time.Duration) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.inFlight == 0 && now.Sub(l.lastUsed) > olderThan
+}
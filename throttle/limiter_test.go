@@ -0,0 +1,128 @@
+package throttle
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+var testNow = time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+
+func newTestStore(t *testing.T, conf *BucketConf) *BucketStore {
+	s := NewBucketStore(context.Background(), time.Minute, time.Minute)
+	s.SetBucketGroup("g", conf)
+	return s
+}
+
+// expect checks a decision taken at testNow + at
+func expect(t *testing.T, s *BucketStore, at time.Duration, allowed bool, remaining int, retryAfter time.Duration) Decision {
+	t.Helper()
+	d := s.Decide("g", "b", testNow.Add(at))
+	if d.Allowed != allowed || d.Remaining != remaining || d.RetryAfter != retryAfter {
+		t.Fatalf("at %s: got allowed %v remaining %d retry after %s, want %v %d %s", at, d.Allowed, d.Remaining, d.RetryAfter, allowed, remaining, retryAfter)
+	}
+	return d
+}
+
+func TestTokenBucket(t *testing.T) {
+	s := newTestStore(t, &BucketConf{Burst: 2, Increment: 1, IncrPeriod: time.Second})
+	d := expect(t, s, 0, true, 1, 0)
+	if d.Limit != 2 || d.Reset != time.Second {
+		t.Fatalf("limit %d reset %s", d.Limit, d.Reset)
+	}
+	d = expect(t, s, 0, true, 0, 0)
+	if d.Reset != 2*time.Second {
+		t.Fatalf("reset %s", d.Reset)
+	}
+	expect(t, s, 500*time.Millisecond, false, 0, 500*time.Millisecond)
+	expect(t, s, time.Second, true, 0, 0)
+	// buckets are independent
+	if !s.Decide("g", "other", testNow).Allowed {
+		t.Fatal("other bucket blocked")
+	}
+}
+
+func TestSlidingWindow(t *testing.T) {
+	s := newTestStore(t, &BucketConf{Algorithm: AlgorithmSlidingWindow, Limit: 2, Window: 10 * time.Second})
+	expect(t, s, 0, true, 1, 0)
+	d := expect(t, s, time.Second, true, 0, 0)
+	if d.Limit != 2 || d.Reset != 10*time.Second {
+		t.Fatalf("limit %d reset %s", d.Limit, d.Reset)
+	}
+	expect(t, s, 2*time.Second, false, 0, 8*time.Second)
+	// the first request leaves the window
+	expect(t, s, 10*time.Second, true, 0, 0)
+	expect(t, s, 10*time.Second, false, 0, time.Second)
+}
+
+func TestGCRA(t *testing.T) {
+	// one request per second, bursts of 3
+	s := newTestStore(t, &BucketConf{Algorithm: AlgorithmGCRA, Limit: 10, Window: 10 * time.Second, Burst: 3})
+	expect(t, s, 0, true, 2, 0)
+	expect(t, s, 0, true, 1, 0)
+	d := expect(t, s, 0, true, 0, 0)
+	if d.Limit != 3 || d.Reset != 3*time.Second {
+		t.Fatalf("limit %d reset %s", d.Limit, d.Reset)
+	}
+	expect(t, s, 0, false, 0, time.Second)
+	expect(t, s, time.Second, true, 0, 0)
+	expect(t, s, 10*time.Second, true, 2, 0)
+}
+
+func TestConcurrency(t *testing.T) {
+	s := newTestStore(t, &BucketConf{Algorithm: AlgorithmConcurrency, Limit: 2})
+	d1 := expect(t, s, 0, true, 1, 0)
+	expect(t, s, 0, true, 0, 0)
+	expect(t, s, 0, false, 0, concurrencyRetryAfter)
+	d1.Release()
+	d1.Release() // no-op
+	expect(t, s, 0, true, 0, 0)
+	expect(t, s, 0, false, 0, concurrencyRetryAfter)
+}
+
+func TestUnknownGroupBlocked(t *testing.T) {
+	s := newTestStore(t, &BucketConf{Burst: 1, Increment: 1, IncrPeriod: time.Second})
+	if s.Decide("missing", "b", testNow).Allowed {
+		t.Fatal("unknown group allowed")
+	}
+}
+
+func TestSetBucketGroupValidates(t *testing.T) {
+	invalid := map[string]*BucketConf{
+		"token bucket without burst":       {Increment: 1, IncrPeriod: time.Second},
+		"token bucket without period":      {Burst: 1, Increment: 1},
+		"token bucket with negative incr":  {Burst: 1, Increment: -1, IncrPeriod: time.Second},
+		"sliding window without limit":     {Algorithm: AlgorithmSlidingWindow, Window: time.Second},
+		"sliding window without window":    {Algorithm: AlgorithmSlidingWindow, Limit: 1},
+		"gcra without window":              {Algorithm: AlgorithmGCRA, Limit: 10},
+		"gcra without limit":               {Algorithm: AlgorithmGCRA, Window: time.Second},
+		"gcra with a zero interval":        {Algorithm: AlgorithmGCRA, Limit: 10, Window: 5},
+		"gcra with a sub-ms interval":      {Algorithm: AlgorithmGCRA, Limit: 1000, Window: 999 * time.Millisecond},
+		"concurrency without limit":        {Algorithm: AlgorithmConcurrency},
+		"concurrency with negative window": {Algorithm: AlgorithmConcurrency, Limit: 1, Window: -1},
+		"unknown algorithm":                {Algorithm: "leaky", Limit: 1, Window: time.Second},
+	}
+	for name, conf := range invalid {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("%s: accepted", name)
+				}
+			}()
+			newTestStore(t, conf)
+		}()
+	}
+	valid := []*BucketConf{
+		{Burst: 1, Increment: 1, IncrPeriod: time.Second},
+		{Burst: 1, IncrPeriod: time.Second}, // fixed quota
+		{Algorithm: AlgorithmSlidingWindow, Limit: 1, Window: time.Second},
+		{Algorithm: AlgorithmGCRA, Limit: 1, Window: time.Second},
+		{Algorithm: AlgorithmGCRA, Limit: 1000, Window: time.Second},
+		{Algorithm: AlgorithmConcurrency, Limit: 1},
+	}
+	for _, conf := range valid {
+		if err := conf.Validate(); err != nil {
+			t.Errorf("%+v: %v", conf, err)
+		}
+	}
+}
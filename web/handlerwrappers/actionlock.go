@@ -1,24 +1,37 @@
 package handlerwrappers
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
 	"strings"
+	"time"
 
+	"github.com/x64c/gw/errs"
 	"github.com/x64c/gw/framework"
 	"github.com/x64c/gw/namedlocks"
 	"github.com/x64c/gw/web/responses"
 )
 
 // runActionLocks acquires the given locks; on conflict writes 409 and returns.
+// If waitTimeout > 0 (WAIT mode), blocks up to waitTimeout for the locks instead of failing fast;
+// 409 is written only when the wait times out. Requires the store to implement namedlocks.WaitingStore.
 // On success, attaches acquired locks to ctx, runs inner, releases on defer.
+// Locks go through appCore.ActionLockStore (process-local MapStore unless switched by PrepareActionLockStore).
 // authUIDStr is included in panic logs (empty if not auth-keyed).
 // Used by ActionLockPathOnly, ActionLockBearerUser, and ActionLockCookieUser to share the lock-acquire logic.
-func runActionLocks(w http.ResponseWriter, r *http.Request, inner http.Handler, appCore *framework.Core, lockKeys []string, authUIDStr string) {
-	acquired, ok := namedlocks.AcquireLocks(appCore.ActionLocks, lockKeys)
+func runActionLocks(w http.ResponseWriter, r *http.Request, inner http.Handler, appCore *framework.Core, lockKeys []string, authUIDStr string, waitTimeout time.Duration) {
+	lockConf := appCore.ActionLockConf
+	leaseTTL := time.Duration(lockConf.LeaseTTL) * time.Second
+	lease, ok, err := acquireActionLocks(r.Context(), appCore.ActionLockStore, lockKeys, leaseTTL, waitTimeout)
+	if err != nil {
+		responses.WriteErrorJSON(w, http.StatusInternalServerError, errs.LockStore.Wrap(err))
+		return
+	}
 	if !ok {
-		// Fail-fast: resource is already locked
+		// Fail-fast (or wait timed out): resource is already locked
 		if len(lockKeys) == 1 {
 			responses.WriteSimpleErrorJSON(w, http.StatusConflict, fmt.Sprintf("action [%s] locked by another request", lockKeys[0]))
 			return
@@ -27,13 +40,45 @@ func runActionLocks(w http.ResponseWriter, r *http.Request, inner http.Handler,
 		responses.WriteSimpleErrorJSON(w, http.StatusConflict, fmt.Sprintf("some of actions in [%s] locked by another request", lockedActionsStr))
 		return
 	}
+	stopRenewal := func() {}
+	if lockConf.RenewInterval > 0 {
+		renewInterval := time.Duration(lockConf.RenewInterval) * time.Second
+		// the handler may keep running after the client is gone. renew until it returns
+		stopRenewal = namedlocks.KeepAlive(context.WithoutCancel(r.Context()), appCore.ActionLockStore, lease, leaseTTL, renewInterval)
+	}
 	defer func() {
-		namedlocks.ReleaseLocks(appCore.ActionLocks, acquired)
+		stopRenewal()
+		// release even if the request ctx is already canceled (client gone)
+		if releaseErr := appCore.ActionLockStore.Release(context.WithoutCancel(r.Context()), lease); releaseErr != nil {
+			log.Printf("[ERROR] release action locks %v: %v", lease.Names, releaseErr)
+		}
 		if rcv := recover(); rcv != nil {
 			log.Printf("[PANIC] user=%s method=%s path=%s locks=%v err=%v",
-				authUIDStr, r.Method, r.URL.Path, acquired, rcv)
+				authUIDStr, r.Method, r.URL.Path, lease.Names, rcv)
 		}
 	}()
-	ctx := namedlocks.ContextWithAcquiredLocks(r.Context(), acquired)
+	ctx := namedlocks.ContextWithAcquiredLocks(r.Context(), lease.Names)
 	inner.ServeHTTP(w, r.WithContext(ctx))
 }
+
+// acquireActionLocks acquires fail-fast, or waits up to waitTimeout if waitTimeout > 0.
+// Returns ok = false on conflict, including a wait that timed out or was abandoned by the client.
+func acquireActionLocks(ctx context.Context, store namedlocks.Store, lockKeys []string, leaseTTL time.Duration, waitTimeout time.Duration) (*namedlocks.Lease, bool, error) {
+	if waitTimeout <= 0 {
+		return store.TryAcquire(ctx, lockKeys, leaseTTL)
+	}
+	waitingStore, ok := store.(namedlocks.WaitingStore)
+	if !ok {
+		return nil, false, errors.New("wait mode not supported by the action lock store")
+	}
+	waitCtx, cancel := context.WithTimeout(ctx, waitTimeout)
+	defer cancel()
+	lease, err := waitingStore.Acquire(waitCtx, lockKeys, leaseTTL)
+	if err != nil {
+		if errors.Is(err, context.DeadlineExceeded) || errors.Is(err, context.Canceled) {
+			return nil, false, nil
+		}
+		return nil, false, err
+	}
+	return lease, true, nil
+}
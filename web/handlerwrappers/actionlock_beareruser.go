@@ -3,6 +3,7 @@ package handlerwrappers
 import (
 	"fmt"
 	"net/http"
+	"time"
 
 	"github.com/x64c/gw/errs"
 	"github.com/x64c/gw/framework"
@@ -17,6 +18,7 @@ import (
 type ActionLockBearerUser[UID comparable] struct {
 	AppProvider framework.AppProviderFunc
 	Actions     map[string]string
+	WaitTimeout time.Duration // opt-in WAIT mode: block up to this long for the locks. 0 = fail-fast (409)
 }
 
 func (m *ActionLockBearerUser[UID]) Wrap(inner http.Handler) http.Handler {
@@ -50,6 +52,6 @@ func (m *ActionLockBearerUser[UID]) Wrap(inner http.Handler) http.Handler {
 			}
 			lockKeys = append(lockKeys, fmt.Sprintf("%s:%s", action, target))
 		}
-		runActionLocks(w, r, inner, appCore, lockKeys, uidStr)
+		runActionLocks(w, r, inner, appCore, lockKeys, uidStr, m.WaitTimeout)
 	})
 }
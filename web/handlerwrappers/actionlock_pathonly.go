@@ -3,6 +3,7 @@ package handlerwrappers
 import (
 	"fmt"
 	"net/http"
+	"time"
 
 	"github.com/x64c/gw/framework"
 )
@@ -18,6 +19,7 @@ import (
 type ActionLockPathOnly struct {
 	AppProvider framework.AppProviderFunc
 	Actions     map[string]string
+	WaitTimeout time.Duration // opt-in WAIT mode: block up to this long for the locks. 0 = fail-fast (409)
 }
 
 func (m *ActionLockPathOnly) Wrap(inner http.Handler) http.Handler {
@@ -27,6 +29,6 @@ func (m *ActionLockPathOnly) Wrap(inner http.Handler) http.Handler {
 		for action, targetKey := range m.Actions {
 			lockKeys = append(lockKeys, fmt.Sprintf("%s:%s", action, r.PathValue(targetKey)))
 		}
-		runActionLocks(w, r, inner, appCore, lockKeys, "")
+		runActionLocks(w, r, inner, appCore, lockKeys, "", m.WaitTimeout)
 	})
 }
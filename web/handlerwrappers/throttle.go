@@ -0,0 +1,39 @@
+package handlerwrappers
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/x64c/gw/errs"
+	"github.com/x64c/gw/framework"
+	"github.com/x64c/gw/throttle"
+	"github.com/x64c/gw/web/responses"
+)
+
+// checkThrottle takes a unit from the bucket and sets the RateLimit-Limit/Remaining/Reset headers.
+// If rate-limited, it also sets Retry-After and writes a 429 with resErr.
+// Call Release on the returned decision once an allowed request ends.
+func checkThrottle(w http.ResponseWriter, appCore *framework.Core, bucketGroupID, bucketID string, resErr *errs.Error) throttle.Decision {
+	d := appCore.ThrottleBucketStore.Decide(bucketGroupID, bucketID, time.Now())
+	if d.Limit > 0 {
+		h := w.Header()
+		h.Set("RateLimit-Limit", strconv.Itoa(d.Limit))
+		h.Set("RateLimit-Remaining", strconv.Itoa(max(d.Remaining, 0)))
+		h.Set("RateLimit-Reset", strconv.Itoa(ceilSeconds(d.Reset)))
+	}
+	if !d.Allowed {
+		w.Header().Set("Retry-After", strconv.Itoa(max(ceilSeconds(d.RetryAfter), 1)))
+		responses.WriteErrorJSON(w, http.StatusTooManyRequests, resErr)
+	}
+	return d
+}
+
+// ceilSeconds rounds d up to whole seconds, as the rate-limit headers take delta-seconds
+func ceilSeconds(d time.Duration) int {
+	if d <= 0 {
+		return 0
+	}
+	return int(math.Ceil(d.Seconds()))
+}
@@ -2,8 +2,8 @@ package handlerwrappers
 
 import (
 	"net/http"
-	"time"
 
+	"github.com/x64c/gw/errs"
 	"github.com/x64c/gw/framework"
 	"github.com/x64c/gw/web/responses"
 	"github.com/x64c/gw/web/usercookiesession"
@@ -25,10 +25,11 @@ func (m *ThrottleCookieSession) Wrap(inner http.Handler) http.Handler {
 			return
 		}
 		// Check Throttle Bucket
-		if !appCore.ThrottleBucketStore.Allow(m.BucketGroupID, sessionID, time.Now()) {
-			responses.WriteSimpleErrorJSON(w, http.StatusTooManyRequests, "session rate limited")
+		d := checkThrottle(w, appCore, m.BucketGroupID, sessionID, errs.RateLimited.WithDetail("session"))
+		if !d.Allowed {
 			return
 		}
+		defer d.Release() // frees a concurrency slot
 
 		// Inner
 		inner.ServeHTTP(w, r)
@@ -23,9 +23,11 @@ func (m *ThrottleCookieUser[UID]) Wrap(inner http.Handler) http.Handler {
 			responses.WriteErrorJSON(w, http.StatusInternalServerError, errs.DataMissingInContext.WithDetail("SessionData"))
 			return
 		}
-		if !throttleUser(w, appCore, sd.UIDStr, m.BucketGroupID) {
+		d := throttleUser(w, appCore, sd.UIDStr, m.BucketGroupID)
+		if !d.Allowed {
 			return
 		}
+		defer d.Release() // frees a concurrency slot
 		inner.ServeHTTP(w, r)
 	})
 }
@@ -2,11 +2,10 @@ package handlerwrappers
 
 import (
 	"net/http"
-	"time"
 
+	"github.com/x64c/gw/errs"
 	"github.com/x64c/gw/framework"
 	"github.com/x64c/gw/web/requests"
-	"github.com/x64c/gw/web/responses"
 )
 
 type ThrottleIP struct {
@@ -20,10 +19,11 @@ func (m *ThrottleIP) Wrap(inner http.Handler) http.Handler {
 		// Requested IP
 		ip := requests.GetClientIP(r)
 		// Check Throttle Bucket
-		if !appCore.ThrottleBucketStore.Allow(m.BucketGroupID, ip, time.Now()) {
-			responses.WriteSimpleErrorJSON(w, http.StatusTooManyRequests, "access rate limited - ip "+ip)
+		d := checkThrottle(w, appCore, m.BucketGroupID, ip, errs.RateLimited.WithDetail("ip "+ip))
+		if !d.Allowed {
 			return
 		}
+		defer d.Release() // frees a concurrency slot
 
 		// Inner
 		inner.ServeHTTP(w, r)
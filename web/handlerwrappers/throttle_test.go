@@ -0,0 +1,36 @@
+package handlerwrappers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/x64c/gw/errs"
+	"github.com/x64c/gw/framework"
+	"github.com/x64c/gw/throttle"
+)
+
+func TestCheckThrottleHeaders(t *testing.T) {
+	store := throttle.NewBucketStore(context.Background(), time.Minute, time.Minute)
+	store.SetBucketGroup("g", &throttle.BucketConf{Algorithm: throttle.AlgorithmSlidingWindow, Limit: 1, Window: 90 * time.Second})
+	appCore := &framework.Core{ThrottleBucketStore: store}
+
+	w := httptest.NewRecorder()
+	if d := checkThrottle(w, appCore, "g", "b", errs.RateLimited); !d.Allowed {
+		t.Fatal("first request blocked")
+	}
+	h := w.Header()
+	if h.Get("RateLimit-Limit") != "1" || h.Get("RateLimit-Remaining") != "0" || h.Get("RateLimit-Reset") != "90" || h.Get("Retry-After") != "" {
+		t.Fatalf("allowed headers %v", h)
+	}
+
+	w = httptest.NewRecorder()
+	if d := checkThrottle(w, appCore, "g", "b", errs.RateLimited); d.Allowed {
+		t.Fatal("second request allowed")
+	}
+	if w.Code != http.StatusTooManyRequests || w.Header().Get("Retry-After") != "90" {
+		t.Fatalf("blocked: status %d headers %v", w.Code, w.Header())
+	}
+}
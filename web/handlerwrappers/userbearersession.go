@@ -26,7 +26,7 @@ func (m *UserBearerSession[UID]) Wrap(inner http.Handler) http.Handler {
 			responses.WriteErrorJSON(w, http.StatusUnauthorized, errs.InvalidAccessToken)
 			return
 		}
-		key := appCore.AppName + ":access:" + security.HashHexSHA256(accessToken)
+		key := userbearersession.KeyAccessToken.Key(appCore.AppName, security.HashHexSHA256(accessToken))
 		uidStr, ok, err := appCore.MainKVDB.Get(ctx, key)
 		if err != nil {
 			responses.WriteErrorJSON(w, http.StatusInternalServerError, errs.KVDB.Wrap(err))
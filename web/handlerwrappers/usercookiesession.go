@@ -115,16 +115,7 @@ func (m *UserCookieSession[UID]) slidingExpHandler(inner http.Handler, cookieSes
 		baseKey := cookieSessionMgr.SessionIDToKVDBKey(sessionID)
 		ttl, state, err := cookieSessionMgr.KVDB.TTL(ctx, baseKey)
 		if err == nil && state == kvdbs.TTLExpiring && ttl < time.Duration(cookieSessionMgr.Conf.ExtendThreshold)*time.Second {
-			slidingExpiration := time.Duration(cookieSessionMgr.Conf.ExpireIn) * time.Second
-			_, _ = cookieSessionMgr.KVDB.Expire(ctx, baseKey, slidingExpiration)
-			if cookieSessionMgr.Conf.WithExternalTokens {
-				_, _ = cookieSessionMgr.KVDB.Expire(ctx, baseKey+":access_tokens", slidingExpiration)
-				_, _ = cookieSessionMgr.KVDB.Expire(ctx, baseKey+":refresh_tokens", slidingExpiration)
-			}
-			if cookieSessionMgr.Conf.MaxCntPerUser > 0 {
-				usrSessionListKey := fmt.Sprintf("%s:ucookie_sessions:%s", cookieSessionMgr.AppName, uidStr)
-				_, _ = cookieSessionMgr.KVDB.Expire(ctx, usrSessionListKey, slidingExpiration)
-			}
+			cookieSessionMgr.ExtendSession(ctx, sessionID, uidStr)
 			encSessionID := sessionCookie.Value
 			cookieSessionMgr.RefreshSessionCookie(w, encSessionID)
 		}
@@ -0,0 +1,185 @@
+package responses
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"log"
+	"mime"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/x64c/gw/errs"
+	"github.com/x64c/gw/storages"
+)
+
+// ServeStorageObject streams an object of a storages.Storage into the response.
+//   - ETag / Last-Modified with If-None-Match / If-Modified-Since (304) if the storage is a storages.Stater
+//   - a single byte range (206) via storages.RangeGetter, or by skipping bytes of Get otherwise. Multiple ranges get the full object
+//   - HEAD writes headers only
+func ServeStorageObject(w http.ResponseWriter, r *http.Request, st storages.Storage, path string) {
+	ctx := r.Context()
+	info, err := statStorageObject(ctx, st, path)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			WriteErrorJSON(w, http.StatusNotFound, errs.ResourceNotFound)
+			return
+		}
+		if errors.Is(err, errs.InvalidStoragePath) {
+			WriteErrorJSON(w, http.StatusBadRequest, errs.InvalidStoragePath)
+			return
+		}
+		WriteSimpleErrorJSON(w, http.StatusInternalServerError, fmt.Sprintf("failed to stat object. %v", err))
+		return
+	}
+
+	h := w.Header()
+	contentType := info.ContentType
+	if contentType == "" {
+		contentType = mime.TypeByExtension(filepath.Ext(path))
+	}
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+	h.Set("Accept-Ranges", "bytes")
+	if info.ETag != "" {
+		h.Set("ETag", info.ETag)
+	}
+	if !info.ModTime.IsZero() {
+		h.Set("Last-Modified", info.ModTime.UTC().Format(http.TimeFormat))
+	}
+	if isNotModified(r, info) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	offset, length, partial, satisfiable := parseByteRange(r.Header.Get("Range"), info.Size)
+	if partial {
+		if ifRange := r.Header.Get("If-Range"); ifRange != "" && ifRange != info.ETag {
+			offset, length, partial = 0, info.Size, false // changed since the client's copy. send it all
+		} else if !satisfiable {
+			h.Set("Content-Range", fmt.Sprintf("bytes */%d", info.Size))
+			WriteSimpleErrorJSON(w, http.StatusRequestedRangeNotSatisfiable, "requested range not satisfiable")
+			return
+		}
+	}
+
+	var body io.ReadCloser
+	if partial {
+		body, err = getStorageObjectRange(ctx, st, path, offset, length)
+	} else {
+		body, err = st.Get(ctx, path)
+	}
+	if err != nil {
+		WriteSimpleErrorJSON(w, http.StatusInternalServerError, fmt.Sprintf("failed to read object. %v", err))
+		return
+	}
+	defer func() { _ = body.Close() }()
+
+	h.Set("Content-Type", contentType)
+	h.Set("Content-Length", strconv.FormatInt(length, 10))
+	status := http.StatusOK
+	if partial {
+		h.Set("Content-Range", fmt.Sprintf("bytes %d-%d/%d", offset, offset+length-1, info.Size))
+		status = http.StatusPartialContent
+	}
+	w.WriteHeader(status) // Response Header Sent & Frozen
+	if r.Method == http.MethodHead {
+		return
+	}
+	if _, err = io.CopyN(w, body, length); err != nil {
+		log.Printf("[ERROR] streaming storage object %q: %v", path, err)
+	}
+}
+
+func statStorageObject(ctx context.Context, st storages.Storage, path string) (*storages.ObjectInfo, error) {
+	if stater, ok := st.(storages.Stater); ok {
+		return stater.Stat(ctx, path)
+	}
+	size, err := st.Size(ctx, path)
+	if err != nil {
+		return nil, err
+	}
+	return &storages.ObjectInfo{Path: path, Size: size}, nil
+}
+
+func getStorageObjectRange(ctx context.Context, st storages.Storage, path string, offset int64, length int64) (io.ReadCloser, error) {
+	if rangeGetter, ok := st.(storages.RangeGetter); ok {
+		return rangeGetter.GetRange(ctx, path, offset, length)
+	}
+	rc, err := st.Get(ctx, path)
+	if err != nil {
+		return nil, err
+	}
+	if _, err = io.CopyN(io.Discard, rc, offset); err != nil {
+		_ = rc.Close()
+		return nil, err
+	}
+	return rc, nil // the caller reads length bytes only
+}
+
+// isNotModified evaluates If-None-Match, or If-Modified-Since if If-None-Match is absent
+func isNotModified(r *http.Request, info *storages.ObjectInfo) bool {
+	if inm := r.Header.Get("If-None-Match"); inm != "" {
+		if info.ETag == "" {
+			return false
+		}
+		for _, tag := range strings.Split(inm, ",") {
+			tag = strings.TrimSpace(tag)
+			if tag == "*" || strings.TrimPrefix(tag, "W/") == strings.TrimPrefix(info.ETag, "W/") { // weak comparison
+				return true
+			}
+		}
+		return false
+	}
+	if ims := r.Header.Get("If-Modified-Since"); ims != "" && !info.ModTime.IsZero() {
+		t, err := http.ParseTime(ims)
+		return err == nil && !info.ModTime.Truncate(time.Second).After(t)
+	}
+	return false
+}
+
+// parseByteRange parses a single "bytes=" range against the object size.
+// Returns offset, length, partial (a single range requested), satisfiable.
+// Absent, malformed or multi-range headers are ignored: (0, size, false, true)
+func parseByteRange(header string, size int64) (int64, int64, bool, bool) {
+	spec, ok := strings.CutPrefix(header, "bytes=")
+	if !ok || strings.Contains(spec, ",") {
+		return 0, size, false, true
+	}
+	startStr, endStr, ok := strings.Cut(strings.TrimSpace(spec), "-")
+	if !ok {
+		return 0, size, false, true
+	}
+	if startStr == "" { // suffix range: last N bytes
+		n, err := strconv.ParseInt(endStr, 10, 64)
+		if err != nil || n < 0 {
+			return 0, size, false, true
+		}
+		if n == 0 || size == 0 {
+			return 0, 0, true, false
+		}
+		n = min(n, size)
+		return size - n, n, true, true
+	}
+	start, err := strconv.ParseInt(startStr, 10, 64)
+	if err != nil || start < 0 {
+		return 0, size, false, true
+	}
+	if start >= size {
+		return 0, 0, true, false
+	}
+	end := size - 1
+	if endStr != "" {
+		if end, err = strconv.ParseInt(endStr, 10, 64); err != nil || end < start {
+			return 0, size, false, true
+		}
+		end = min(end, size-1)
+	}
+	return start, end - start + 1, true, true
+}
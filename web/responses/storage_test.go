@@ -0,0 +1,50 @@
+package responses
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/x64c/gw/storages"
+)
+
+func TestServeStorageObject(t *testing.T) {
+	st, err := storages.OpenLocalStorage(storages.LocalStorageConf{Root: t.TempDir()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = st.Close() }()
+	_ = st.Put(context.Background(), "a.txt", strings.NewReader("0123456789"))
+
+	tests := []struct {
+		path   string
+		header map[string]string
+		status int
+		body   string
+	}{
+		{"a.txt", nil, http.StatusOK, "0123456789"},
+		{"a.txt", map[string]string{"Range": "bytes=2-4"}, http.StatusPartialContent, "234"},
+		{"a.txt", map[string]string{"Range": "bytes=20-"}, http.StatusRequestedRangeNotSatisfiable, ""},
+		{"missing.txt", nil, http.StatusNotFound, ""},
+		{"../escape", nil, http.StatusBadRequest, ""},
+	}
+	for _, tt := range tests {
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		for name, val := range tt.header {
+			r.Header.Set(name, val)
+		}
+		w := httptest.NewRecorder()
+		ServeStorageObject(w, r, st, tt.path)
+		if w.Code != tt.status {
+			t.Errorf("%s %v: status %d, want %d", tt.path, tt.header, w.Code, tt.status)
+		}
+		if tt.body != "" && w.Body.String() != tt.body {
+			t.Errorf("%s %v: body %q", tt.path, tt.header, w.Body.String())
+		}
+		if tt.status == http.StatusBadRequest && strings.Contains(w.Body.String(), "escape") {
+			t.Errorf("raw path leaked: %s", w.Body.String())
+		}
+	}
+}
@@ -0,0 +1,32 @@
+package uploads
+
+type Conf struct {
+	Dir            string   // storage path prefix of stored files. e.g. "uploads"
+	MaxFileSize    int64    // bytes per file. default 10MiB
+	MaxFiles       int      // max# of files per request. default 10
+	MaxValuesSize  int64    // bytes of all non-file fields together. default 64KiB
+	MaxRequestSize int64    // bytes of the whole body. default MaxFiles*MaxFileSize + MaxValuesSize + 1MiB for multipart framing
+	AllowedTypes   []string // sniffed MIME types. "image/*" allows a whole major type. empty = any
+}
+
+const (
+	defaultMaxFileSize   int64 = 10 << 20
+	defaultMaxFiles            = 10
+	defaultMaxValuesSize int64 = 64 << 10
+	multipartOverhead    int64 = 1 << 20
+)
+
+func (c *Conf) setDefaults() {
+	if c.MaxFileSize <= 0 {
+		c.MaxFileSize = defaultMaxFileSize
+	}
+	if c.MaxFiles <= 0 {
+		c.MaxFiles = defaultMaxFiles
+	}
+	if c.MaxValuesSize <= 0 {
+		c.MaxValuesSize = defaultMaxValuesSize
+	}
+	if c.MaxRequestSize <= 0 {
+		c.MaxRequestSize = int64(c.MaxFiles)*c.MaxFileSize + c.MaxValuesSize + multipartOverhead
+	}
+}
@@ -0,0 +1,296 @@
+// Package uploads streams multipart/form-data file parts into a storages.Storage.
+// Files are never buffered whole: each part is hashed (SHA-256) while it is written to a temp object,
+// then moved to a content-addressed path, so identical uploads share one object.
+package uploads
+
+import (
+	"bufio"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"hash"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"path"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/x64c/gw/errs"
+	"github.com/x64c/gw/storages"
+	"github.com/x64c/gw/web/responses"
+)
+
+// sniffLen is the number of leading bytes http.DetectContentType looks at
+const sniffLen = 512
+
+// File is the metadata of a stored file
+type File struct {
+	Field       string `json:"field"`        // form field name
+	Filename    string `json:"filename"`     // client-supplied name. informational only
+	Path        string `json:"path"`         // storage path. Dir/<sha256[:2]>/<sha256><ext>
+	Size        int64  `json:"size"`         // bytes
+	ContentType string `json:"content_type"` // sniffed, not client-supplied
+	SHA256      string `json:"sha256"`       // hex
+	Existed     bool   `json:"existed"`      // the same content was already stored
+}
+
+// Result is a received upload
+type Result struct {
+	Files  []File
+	Values url.Values // non-file fields
+}
+
+type Uploader struct {
+	storage storages.Storage
+	conf    Conf
+
+	mu     sync.Mutex
+	claims map[string]*claim // content-addressed path -> requests in flight holding it
+}
+
+// claim tracks the requests in flight holding a content-addressed object, which identical uploads share.
+// A failed request deletes an object it created only if no other request relies on it.
+// Claims are per Uploader: concurrent requests on other instances are not seen.
+type claim struct {
+	refs     int
+	kept     bool // a request holding it succeeded
+	orphaned bool // a request that created it failed
+}
+
+func New(storage storages.Storage, conf Conf) *Uploader {
+	conf.setDefaults()
+	return &Uploader{storage: storage, conf: conf, claims: make(map[string]*claim)}
+}
+
+// Receive reads a multipart/form-data request and stores its file parts.
+// On failure, files newly stored by this request are deleted unless a concurrent request got them too,
+// and the error is an *errs.Error.
+func (u *Uploader) Receive(r *http.Request) (*Result, error) {
+	ctx := r.Context()
+	r.Body = http.MaxBytesReader(nil, r.Body, u.conf.MaxRequestSize)
+	mr, err := r.MultipartReader()
+	if err != nil {
+		return nil, errs.UploadMalformed.Wrap(err)
+	}
+	res := &Result{Values: url.Values{}}
+	valuesSize := int64(0)
+	for {
+		part, err := mr.NextPart()
+		if errors.Is(err, io.EOF) {
+			u.release(ctx, res.Files, true)
+			return res, nil
+		}
+		if err != nil {
+			u.release(ctx, res.Files, false)
+			return nil, mapReadErr(err)
+		}
+		if part.FileName() == "" {
+			val, err := io.ReadAll(io.LimitReader(part, u.conf.MaxValuesSize-valuesSize+1))
+			_ = part.Close()
+			if err != nil {
+				u.release(ctx, res.Files, false)
+				return nil, mapReadErr(err)
+			}
+			valuesSize += int64(len(val))
+			if valuesSize > u.conf.MaxValuesSize {
+				u.release(ctx, res.Files, false)
+				return nil, errs.UploadTooLarge.WithDetail("form values")
+			}
+			res.Values.Add(part.FormName(), string(val))
+			continue
+		}
+		if len(res.Files) == u.conf.MaxFiles {
+			_ = part.Close()
+			u.release(ctx, res.Files, false)
+			return nil, errs.UploadTooManyFiles
+		}
+		file, err := u.storePart(ctx, part)
+		_ = part.Close()
+		if err != nil {
+			u.release(ctx, res.Files, false)
+			return nil, err
+		}
+		res.Files = append(res.Files, *file)
+	}
+}
+
+func (u *Uploader) storePart(ctx context.Context, part *multipart.Part) (*File, error) {
+	br := bufio.NewReaderSize(part, sniffLen)
+	head, err := br.Peek(sniffLen)
+	if err != nil && !errors.Is(err, io.EOF) {
+		return nil, mapReadErr(err)
+	}
+	contentType, _, _ := mime.ParseMediaType(http.DetectContentType(head))
+	if !u.typeAllowed(contentType) {
+		return nil, errs.UploadTypeNotAllowed.WithDetail(contentType)
+	}
+
+	hasher := sha256.New()
+	body := &limitedHashReader{r: br, h: hasher, remaining: u.conf.MaxFileSize}
+	tmpPath := path.Join(u.conf.Dir, ".tmp", rand.Text())
+	if err = u.storage.Put(ctx, tmpPath, body); err != nil {
+		_ = u.storage.Delete(context.WithoutCancel(ctx), tmpPath)
+		if body.err != nil {
+			return nil, body.err
+		}
+		return nil, storageErr(err)
+	}
+
+	sum := hex.EncodeToString(hasher.Sum(nil))
+	file := &File{
+		Field:       part.FormName(),
+		Filename:    part.FileName(),
+		Path:        path.Join(u.conf.Dir, sum[:2], sum+extension(part.FileName(), contentType)),
+		Size:        body.n,
+		ContentType: contentType,
+		SHA256:      sum,
+	}
+	// hold the path before looking at it, so a failing request holding it too cannot delete it meanwhile
+	u.claim(file.Path)
+	exists, err := u.storage.Exists(ctx, file.Path)
+	if err == nil && exists {
+		file.Existed = true
+		err = u.storage.Delete(ctx, tmpPath)
+	} else if err == nil {
+		err = u.storage.Move(ctx, tmpPath, file.Path)
+	}
+	if err != nil {
+		_ = u.storage.Delete(context.WithoutCancel(ctx), tmpPath)
+		u.release(ctx, []File{{Path: file.Path, Existed: true}}, false) // not known to be created by this request
+		return nil, storageErr(err)
+	}
+	return file, nil
+}
+
+func (u *Uploader) claim(p string) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	c, ok := u.claims[p]
+	if !ok {
+		c = &claim{}
+		u.claims[p] = c
+	}
+	c.refs++
+}
+
+// release drops the claims of a finished request.
+// A failed request marks the objects it created as orphaned, and the last request holding an orphaned object
+// deletes it, unless one of the requests holding it succeeded.
+func (u *Uploader) release(ctx context.Context, files []File, succeeded bool) {
+	ctx = context.WithoutCancel(ctx)
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	for _, f := range files {
+		c := u.claims[f.Path]
+		c.refs--
+		if succeeded {
+			c.kept = true
+		} else if !f.Existed {
+			c.orphaned = true
+		}
+		if c.refs > 0 {
+			continue
+		}
+		delete(u.claims, f.Path)
+		if c.orphaned && !c.kept {
+			// under the lock, so no request claims the object while it is being deleted
+			_ = u.storage.Delete(ctx, f.Path)
+		}
+	}
+}
+
+func (u *Uploader) typeAllowed(contentType string) bool {
+	if len(u.conf.AllowedTypes) == 0 {
+		return true
+	}
+	major, _, _ := strings.Cut(contentType, "/")
+	return slices.ContainsFunc(u.conf.AllowedTypes, func(allowed string) bool {
+		return allowed == contentType || allowed == major+"/*"
+	})
+}
+
+// extension keeps the client's extension only if it agrees with the sniffed type
+func extension(filename string, contentType string) string {
+	ext := strings.ToLower(path.Ext(filename))
+	if ext == "" {
+		return ""
+	}
+	extType, _, _ := mime.ParseMediaType(mime.TypeByExtension(ext))
+	if extType != contentType {
+		return ""
+	}
+	return ext
+}
+
+// storageErr maps storage failures. Errors already mapped by the storage (e.g. LocalStorage) are kept
+func storageErr(err error) *errs.Error {
+	if errors.Is(err, storages.ErrReadOnly) {
+		return errs.StorageReadOnly.Wrap(err)
+	}
+	return errs.AsStructured(err, errs.Storage)
+}
+
+// mapReadErr maps body read errors. http.MaxBytesReader reports an oversized request as *http.MaxBytesError
+func mapReadErr(err error) *errs.Error {
+	if _, ok := errors.AsType[*http.MaxBytesError](err); ok {
+		return errs.UploadTooLarge.WithDetail("request").WithCause(err)
+	}
+	return errs.UploadMalformed.Wrap(err)
+}
+
+// limitedHashReader hashes and counts what is read, failing once more than remaining bytes arrive
+type limitedHashReader struct {
+	r         io.Reader
+	h         hash.Hash
+	remaining int64
+	n         int64
+	err       *errs.Error // why reading failed, so the storage error can be traced back
+}
+
+func (l *limitedHashReader) Read(p []byte) (int, error) {
+	n, err := l.r.Read(p)
+	l.n += int64(n)
+	l.remaining -= int64(n)
+	if l.remaining < 0 {
+		l.err = errs.UploadTooLarge.WithDetail("file")
+		return 0, l.err
+	}
+	l.h.Write(p[:n])
+	if err != nil && !errors.Is(err, io.EOF) {
+		l.err = mapReadErr(err)
+	}
+	return n, err
+}
+
+// WriteError writes an error of Receive with its HTTP status
+func WriteError(w http.ResponseWriter, err error) {
+	resErr := errs.AsStructured(err, errs.Storage)
+	status := http.StatusInternalServerError
+	switch {
+	case resErr.IsSameCode(errs.UploadMalformed), resErr.IsSameCode(errs.UploadTooManyFiles):
+		status = http.StatusBadRequest
+	case resErr.IsSameCode(errs.UploadTooLarge):
+		status = http.StatusRequestEntityTooLarge
+	case resErr.IsSameCode(errs.UploadTypeNotAllowed):
+		status = http.StatusUnsupportedMediaType
+	}
+	responses.WriteErrorJSON(w, status, resErr)
+}
+
+// Handler receives the upload and passes the result to next. Failures are written by WriteError
+func (u *Uploader) Handler(next func(w http.ResponseWriter, r *http.Request, res *Result)) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		res, err := u.Receive(r)
+		if err != nil {
+			WriteError(w, err)
+			return
+		}
+		next(w, r, res)
+	})
+}
@@ -0,0 +1,138 @@
+package uploads
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"path"
+	"strings"
+	"testing"
+
+	"github.com/x64c/gw/errs"
+	"github.com/x64c/gw/storages/memstorage"
+)
+
+const testContent = "hello uploads"
+
+func contentPath(content string) string {
+	sum := sha256.Sum256([]byte(content))
+	hexSum := hex.EncodeToString(sum[:])
+	return path.Join("up", hexSum[:2], hexSum+".txt")
+}
+
+// uploadRequest builds a multipart request with a file part per content
+func uploadRequest(t *testing.T, contents ...string) *http.Request {
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	_ = mw.WriteField("title", "t")
+	for i, content := range contents {
+		fw, err := mw.CreateFormFile("file", strings.Repeat("f", i+1)+".txt")
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, _ = fw.Write([]byte(content))
+	}
+	_ = mw.Close()
+	r := httptest.NewRequest(http.MethodPost, "/upload", &body)
+	r.Header.Set("Content-Type", mw.FormDataContentType())
+	return r
+}
+
+func newTestUploader() (*Uploader, *memstorage.MemStorage) {
+	st := memstorage.New()
+	return New(st, Conf{Dir: "up", MaxFileSize: 64}), st
+}
+
+func exists(t *testing.T, st *memstorage.MemStorage, p string) bool {
+	ok, err := st.Exists(context.Background(), p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return ok
+}
+
+func TestReceive(t *testing.T) {
+	u, st := newTestUploader()
+	res, err := u.Receive(uploadRequest(t, testContent, testContent))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(res.Files) != 2 || res.Values.Get("title") != "t" {
+		t.Fatalf("result %+v", res)
+	}
+	f := res.Files[0]
+	if f.Path != contentPath(testContent) || f.Size != int64(len(testContent)) || f.ContentType != "text/plain" || f.Existed {
+		t.Fatalf("file %+v", f)
+	}
+	if !res.Files[1].Existed || res.Files[1].Path != f.Path {
+		t.Fatalf("same content not shared: %+v", res.Files[1])
+	}
+	if !exists(t, st, f.Path) {
+		t.Fatal("stored file missing")
+	}
+	if len(u.claims) != 0 {
+		t.Fatalf("claims left: %v", u.claims)
+	}
+}
+
+func TestReceiveFailureDiscardsCreated(t *testing.T) {
+	u, st := newTestUploader()
+	_, err := u.Receive(uploadRequest(t, testContent, strings.Repeat("x", 100)))
+	if !errors.Is(err, errs.UploadTooLarge) {
+		t.Fatalf("err = %v", err)
+	}
+	if exists(t, st, contentPath(testContent)) {
+		t.Fatal("file of the failed request kept")
+	}
+}
+
+func TestReceiveFailureKeepsExisting(t *testing.T) {
+	u, st := newTestUploader()
+	if _, err := u.Receive(uploadRequest(t, testContent)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := u.Receive(uploadRequest(t, testContent, strings.Repeat("x", 100))); err == nil {
+		t.Fatal("oversized upload accepted")
+	}
+	if !exists(t, st, contentPath(testContent)) {
+		t.Fatal("failed request deleted a file stored before it")
+	}
+}
+
+func TestReceiveFailureSparesConcurrentHolder(t *testing.T) {
+	p := contentPath(testContent)
+	for _, otherSucceeds := range []bool{true, false} {
+		u, st := newTestUploader()
+		// another request in flight got the same content
+		u.claim(p)
+		if _, err := u.Receive(uploadRequest(t, testContent, strings.Repeat("x", 100))); err == nil {
+			t.Fatal("oversized upload accepted")
+		}
+		if !exists(t, st, p) {
+			t.Fatal("failed request deleted a file another request holds")
+		}
+		u.release(context.Background(), []File{{Path: p, Existed: true}}, otherSucceeds)
+		if got := exists(t, st, p); got != otherSucceeds {
+			t.Errorf("other request succeeded %v: file exists %v", otherSucceeds, got)
+		}
+	}
+}
+
+func TestReceiveTypeNotAllowed(t *testing.T) {
+	st := memstorage.New()
+	u := New(st, Conf{Dir: "up", AllowedTypes: []string{"image/*"}})
+	_, err := u.Receive(uploadRequest(t, testContent))
+	if !errors.Is(err, errs.UploadTypeNotAllowed) {
+		t.Fatalf("err = %v", err)
+	}
+	w := httptest.NewRecorder()
+	WriteError(w, err)
+	if w.Code != http.StatusUnsupportedMediaType {
+		t.Fatalf("status %d", w.Code)
+	}
+}
@@ -0,0 +1,12 @@
+package userbearersession
+
+import "github.com/x64c/gw/kvdbs"
+
+// KeyAccessToken - access token hash (security.HashHexSHA256) -> uid
+var KeyAccessToken = &kvdbs.KeyFamily{
+	Name:      "access",
+	Pattern:   "access:{id}",
+	ValueType: kvdbs.ValueString,
+	TTLPolicy: kvdbs.TTLFixed,
+	Desc:      "bearer session uid by access token hash",
+}
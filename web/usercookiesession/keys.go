@@ -0,0 +1,41 @@
+package usercookiesession
+
+import "github.com/x64c/gw/kvdbs"
+
+// KVDB key families of cookie sessions
+var (
+	KeySession = &kvdbs.KeyFamily{
+		Name:      "ucookie_session",
+		Pattern:   "ucookie_session:{id}",
+		ValueType: kvdbs.ValueHash,
+		Fields:    []string{"uid", "csrf"},
+		TTLPolicy: kvdbs.TTLSliding,
+		Desc:      "cookie session by session ID",
+	}
+	KeySessionAccessTokens = &kvdbs.KeyFamily{
+		Name:      "ucookie_session_access_tokens",
+		Pattern:   "ucookie_session:{id}:access_tokens",
+		ValueType: kvdbs.ValueHash, // apiID -> external access token
+		TTLPolicy: kvdbs.TTLSliding,
+		Desc:      "external API access tokens of a cookie session",
+	}
+	KeySessionRefreshTokens = &kvdbs.KeyFamily{
+		Name:      "ucookie_session_refresh_tokens",
+		Pattern:   "ucookie_session:{id}:refresh_tokens",
+		ValueType: kvdbs.ValueHash, // apiID -> external refresh token
+		TTLPolicy: kvdbs.TTLSliding,
+		Desc:      "external API refresh tokens of a cookie session",
+	}
+	KeyUserSessions = &kvdbs.KeyFamily{
+		Name:      "ucookie_sessions",
+		Pattern:   "ucookie_sessions:{id}",
+		ValueType: kvdbs.ValueList, // session IDs, oldest first
+		TTLPolicy: kvdbs.TTLSliding,
+		Desc:      "cookie session IDs of a user by uid",
+	}
+)
+
+// KeyFamilies returns all KVDB key families of cookie sessions
+func KeyFamilies() []*kvdbs.KeyFamily {
+	return []*kvdbs.KeyFamily{KeySession, KeySessionAccessTokens, KeySessionRefreshTokens, KeyUserSessions}
+}
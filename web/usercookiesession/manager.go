@@ -21,7 +21,7 @@ type Manager struct {
 }
 
 func (m *Manager) SessionIDToKVDBKey(sessionID string) string {
-	return m.AppName + ":ucookie_session:" + sessionID
+	return KeySession.Key(m.AppName, sessionID)
 }
 
 func (m *Manager) SessionExistsInKVDB(ctx context.Context, sessionID string) (bool, error) {
@@ -59,7 +59,7 @@ func (m *Manager) StoreSessionInKVDB(ctx context.Context, uidStr string) (string
 	}
 
 	if m.Conf.MaxCntPerUser > 0 {
-		usrSessionListKey := fmt.Sprintf("%s:ucookie_sessions:%s", m.AppName, uidStr)
+		usrSessionListKey := KeyUserSessions.Key(m.AppName, uidStr)
 		// SessionList Lock (User Level Lock)
 		mu, _ := m.SessionLocks.LoadOrStore(usrSessionListKey, &sync.Mutex{})
 		mutex := mu.(*sync.Mutex)
@@ -67,6 +67,13 @@ func (m *Manager) StoreSessionInKVDB(ctx context.Context, uidStr string) (string
 		mutex.Lock() // waits until this gets the lock if it's locked by another goroutine
 		defer mutex.Unlock()
 
+		if pdb, ok := m.KVDB.(kvdbs.PipelineDB); ok {
+			if err := m.pushSessionPipelined(ctx, pdb, usrSessionListKey, cookieSessionID); err != nil {
+				return "", err
+			}
+			return cookieSessionID, nil
+		}
+
 		if err := m.KVDB.Push(ctx, usrSessionListKey, cookieSessionID); err != nil {
 			return "", err
 		}
@@ -126,8 +133,12 @@ func (m *Manager) LogoutRedirect(w http.ResponseWriter, r *http.Request, redirec
 	if err == nil {
 		sessionIDBytes, err := m.Cipher.DecodeDecrypt(sessionCookie.Value)
 		if err == nil {
-			baseKey := m.SessionIDToKVDBKey(string(sessionIDBytes))
-			_, _ = m.KVDB.Delete(r.Context(), baseKey, baseKey+":access_tokens", baseKey+":refresh_tokens")
+			sessionID := string(sessionIDBytes)
+			_, _ = m.KVDB.Delete(r.Context(),
+				m.SessionIDToKVDBKey(sessionID),
+				KeySessionAccessTokens.Key(m.AppName, sessionID),
+				KeySessionRefreshTokens.Key(m.AppName, sessionID),
+			)
 		}
 	}
 	m.RemoveSessionCookie(w)
@@ -146,9 +157,28 @@ func (m *Manager) RemoveSessionCookie(w http.ResponseWriter) {
 }
 
 func (m *Manager) StoreExternalTokenPairInKVDB(ctx context.Context, sessionID string, apiID string, accessToken string, refreshToken string) error {
-	baseKey := m.SessionIDToKVDBKey(sessionID)
-	accessTokenKey := baseKey + ":access_tokens"
-	refreshTokenKey := baseKey + ":refresh_tokens"
+	accessTokenKey := KeySessionAccessTokens.Key(m.AppName, sessionID)
+	refreshTokenKey := KeySessionRefreshTokens.Key(m.AppName, sessionID)
+	slidingExpiration := time.Duration(m.Conf.ExpireIn) * time.Second
+
+	if pdb, ok := m.KVDB.(kvdbs.PipelineDB); ok {
+		// 1 round trip. +1 for the first token pair
+		pipe := pdb.Pipeline()
+		existsRes := pipe.Exists(accessTokenKey) // queued before SetField -> state before this call
+		pipe.SetField(accessTokenKey, apiID, accessToken)
+		pipe.SetField(refreshTokenKey, apiID, refreshToken)
+		if err := pipe.Exec(ctx); err != nil {
+			return err
+		}
+		if found, err := existsRes.Val(); err == nil && found {
+			return nil
+		}
+		pipe = pdb.Pipeline()
+		pipe.Expire(accessTokenKey, slidingExpiration)
+		pipe.Expire(refreshTokenKey, slidingExpiration)
+		_ = pipe.Exec(ctx)
+		return nil
+	}
 
 	// If first token pair, set expiration on the containers
 	shouldSetExp := false
@@ -167,7 +197,6 @@ func (m *Manager) StoreExternalTokenPairInKVDB(ctx context.Context, sessionID st
 	}
 
 	if shouldSetExp {
-		slidingExpiration := time.Duration(m.Conf.ExpireIn) * time.Second
 		_, _ = m.KVDB.Expire(ctx, accessTokenKey, slidingExpiration)
 		_, _ = m.KVDB.Expire(ctx, refreshTokenKey, slidingExpiration)
 	}
@@ -175,8 +204,31 @@ func (m *Manager) StoreExternalTokenPairInKVDB(ctx context.Context, sessionID st
 	return nil
 }
 
+// ExtendSession resets the sliding expiration of the session and its related keys. Best effort.
+func (m *Manager) ExtendSession(ctx context.Context, sessionID string, uidStr string) {
+	slidingExpiration := time.Duration(m.Conf.ExpireIn) * time.Second
+	keys := []string{m.SessionIDToKVDBKey(sessionID)}
+	if m.Conf.WithExternalTokens {
+		keys = append(keys, KeySessionAccessTokens.Key(m.AppName, sessionID), KeySessionRefreshTokens.Key(m.AppName, sessionID))
+	}
+	if m.Conf.MaxCntPerUser > 0 {
+		keys = append(keys, KeyUserSessions.Key(m.AppName, uidStr))
+	}
+	if pdb, ok := m.KVDB.(kvdbs.PipelineDB); ok {
+		pipe := pdb.Pipeline()
+		for _, key := range keys {
+			pipe.Expire(key, slidingExpiration)
+		}
+		_ = pipe.Exec(ctx)
+		return
+	}
+	for _, key := range keys {
+		_, _ = m.KVDB.Expire(ctx, key, slidingExpiration)
+	}
+}
+
 func (m *Manager) FetchExternalAccessToken(ctx context.Context, sessionID string, apiID string) (string, error) {
-	tkn, found, err := m.KVDB.GetField(ctx, m.SessionIDToKVDBKey(sessionID)+":access_tokens", apiID)
+	tkn, found, err := m.KVDB.GetField(ctx, KeySessionAccessTokens.Key(m.AppName, sessionID), apiID)
 	if err != nil {
 		return "", err
 	}
@@ -187,7 +239,7 @@ func (m *Manager) FetchExternalAccessToken(ctx context.Context, sessionID string
 }
 
 func (m *Manager) FetchExternalRefreshToken(ctx context.Context, sessionID string, apiID string) (string, error) {
-	tkn, found, err := m.KVDB.GetField(ctx, m.SessionIDToKVDBKey(sessionID)+":refresh_tokens", apiID)
+	tkn, found, err := m.KVDB.GetField(ctx, KeySessionRefreshTokens.Key(m.AppName, sessionID), apiID)
 	if err != nil {
 		return "", err
 	}
@@ -202,24 +254,30 @@ func (m *Manager) buildKeysToDel(sessionsToDel []string) []string {
 	if m.Conf.WithExternalTokens {
 		keysToDel = make([]string, 0, len(sessionsToDel)*3)
 		for _, sid := range sessionsToDel {
-			baseKey := m.SessionIDToKVDBKey(sid)
 			keysToDel = append(keysToDel,
-				baseKey,
-				baseKey+":access_tokens",
-				baseKey+":refresh_tokens",
+				m.SessionIDToKVDBKey(sid),
+				KeySessionAccessTokens.Key(m.AppName, sid),
+				KeySessionRefreshTokens.Key(m.AppName, sid),
 			)
 		}
 		return keysToDel
 	}
 	keysToDel = make([]string, 0, len(sessionsToDel))
 	for _, sid := range sessionsToDel {
-		baseKey := m.SessionIDToKVDBKey(sid)
-		keysToDel = append(keysToDel, baseKey)
+		keysToDel = append(keysToDel, m.SessionIDToKVDBKey(sid))
 	}
 	return keysToDel
 }
 
 func (m *Manager) CleanUp(ctx context.Context, usrSessionListKey string) error {
+	if pdb, ok := m.KVDB.(kvdbs.PipelineDB); ok {
+		sessionIDs, err := m.KVDB.Range(ctx, usrSessionListKey, 0, -1) // short list. bounded by MaxCntPerUser
+		if err != nil {
+			return err
+		}
+		return m.trimSessionsPipelined(ctx, pdb, usrSessionListKey, sessionIDs)
+	}
+
 	sessionCnt, err := m.KVDB.Len(ctx, usrSessionListKey)
 	if err != nil {
 		return err
@@ -241,6 +299,36 @@ func (m *Manager) CleanUp(ctx context.Context, usrSessionListKey string) error {
 	return nil
 }
 
+// pushSessionPipelined appends the session to the user's session list, refreshes its TTL and reads it back
+// in one atomic round trip, then evicts the oldest sessions over MaxCntPerUser.
+func (m *Manager) pushSessionPipelined(ctx context.Context, pdb kvdbs.PipelineDB, usrSessionListKey string, sessionID string) error {
+	pipe := pdb.TxPipeline()
+	pipe.Push(usrSessionListKey, sessionID)
+	pipe.Expire(usrSessionListKey, time.Duration(m.Conf.ExpireIn)*time.Second)
+	sessionIDsRes := pipe.Range(usrSessionListKey, 0, -1)
+	if err := pipe.Exec(ctx); err != nil {
+		return err
+	}
+	sessionIDs, err := sessionIDsRes.Val()
+	if err != nil {
+		return err
+	}
+	return m.trimSessionsPipelined(ctx, pdb, usrSessionListKey, sessionIDs)
+}
+
+// trimSessionsPipelined deletes the oldest sessions over MaxCntPerUser and trims them off the list
+// in one atomic round trip. sessionIDs is the current list, oldest first.
+func (m *Manager) trimSessionsPipelined(ctx context.Context, pdb kvdbs.PipelineDB, usrSessionListKey string, sessionIDs []string) error {
+	diff := int64(len(sessionIDs)) - m.Conf.MaxCntPerUser
+	if diff <= 0 {
+		return nil
+	}
+	pipe := pdb.TxPipeline()
+	pipe.Delete(m.buildKeysToDel(sessionIDs[:diff])...)
+	pipe.Trim(usrSessionListKey, diff, -1)
+	return pipe.Exec(ctx)
+}
+
 func (m *Manager) SessionIDToUIDStrFromKVDB(ctx context.Context, sessionID string) (string, error) {
 	return SessionIDToUIDStrFromKVDB(ctx, m, sessionID)
 }